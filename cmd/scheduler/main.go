@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manyu/job-scheduler/internal/config"
+	"github.com/manyu/job-scheduler/internal/database"
+	"github.com/manyu/job-scheduler/internal/handlers"
+	"github.com/manyu/job-scheduler/internal/middleware"
+	"github.com/manyu/job-scheduler/internal/redis"
+	"github.com/manyu/job-scheduler/internal/services"
+	"github.com/manyu/job-scheduler/internal/storage"
+)
+
+// shutdownTimeout bounds how long in-flight HTTP requests may take to drain
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	// Load configuration
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	gin.SetMode(cfg.Server.Mode)
+
+	// Initialize database service
+	dbService, err := database.NewDatabaseService(cfg.Database.GetDSN())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbService.Close()
+
+	// Initialize Redis client with config
+	redisClient, err := redis.NewRedisClient(cfg.Redis.GetRedisAddr(), cfg.Redis.Password, cfg.Redis.DB)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redisClient.Close()
+
+	// Initialize PostgreSQL storage
+	postgresStorage := storage.NewPostgresStorage(dbService)
+
+	// Initialize job queue service
+	jobQueue, err := services.NewQueue(redisClient, services.QueueOptions{
+		Backend:       cfg.Queue.Backend,
+		RetryDefaults: cfg.Worker.RetryPolicy(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to create job queue: %v", err)
+	}
+
+	// Initialize scheduler service
+	schedulerService := services.NewSchedulerService(postgresStorage, jobQueue, redisClient)
+
+	// Replicas either split the jobs into shards or elect a leader through Redis so that only one of them polls
+	var leaderElector *services.LeaderElector
+	var leaderStatus services.LeaderElectorInterface
+	var shardCoordinator *services.ShardCoordinator
+	if cfg.Scheduler.Sharding {
+		shardCoordinator = services.NewShardCoordinator(services.NewRedisShardBackend(redisClient), schedulerService.InstanceID(), cfg.Scheduler.ShardCount, cfg.Scheduler.ShardLeaseTTL)
+	} else if cfg.Scheduler.LeaderElection {
+		leaderElector = services.NewLeaderElector(services.NewRedisLeaderLock(redisClient), schedulerService.InstanceID(), cfg.Scheduler.LeaderLockTTL, postgresStorage.MaxScheduleLeaderTerm)
+		leaderStatus = leaderElector
+	}
+
+	// Initialize the cipher of credential secrets, if an encryption key is configured
+	var credentialCipher *services.CredentialCipher
+	if key, _ := cfg.Credentials.Key(); key != nil {
+		if credentialCipher, err = services.NewCredentialCipher(key); err != nil {
+			log.Fatalf("Failed to create credential cipher: %v", err)
+		}
+	}
+
+	// Initialize HTTP handlers
+	jobHandler := handlers.NewJobHandler(postgresStorage, cfg.Scheduler.MaxJobTimeout, cfg.Worker.MaxRetries)
+	systemHandler := handlers.NewSystemHandler(dbService, redisClient, schedulerService, leaderStatus, services.NewWorkerRegistry(redisClient))
+	deadLetterHandler := handlers.NewDeadLetterHandler(services.NewDeadLetterQueue(redisClient, jobQueue))
+	credentialHandler := handlers.NewCredentialHandler(postgresStorage, credentialCipher)
+
+	server := &http.Server{
+		Addr:    cfg.Server.GetServerAddr(),
+		Handler: setupRouter(jobHandler, systemHandler, deadLetterHandler, credentialHandler),
+	}
+
+	// Start background scheduler
+	backgroundScheduler := services.NewBackgroundScheduler(schedulerService, cfg.Scheduler.BatchSize, leaderElector, shardCoordinator)
+	backgroundScheduler.Start(cfg.Scheduler.PollInterval)
+
+	// Start HTTP server
+	go func() {
+		log.Printf("API server listening on %s", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start API server: %v", err)
+		}
+	}()
+
+	// Wait for shutdown signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	<-sigChan
+	log.Println("Received shutdown signal")
+
+	// Stop scheduling new work before draining HTTP requests
+	backgroundScheduler.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("API server forced to shutdown: %v", err)
+	}
+
+	log.Println("API server shutdown complete")
+}
+
+// setupRouter registers all HTTP routes
+func setupRouter(jobHandler *handlers.JobHandler, systemHandler *handlers.SystemHandler, deadLetterHandler *handlers.DeadLetterHandler, credentialHandler *handlers.CredentialHandler) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Logger())
+	router.Use(middleware.ErrorHandlerMiddleware())
+
+	router.GET("/health", systemHandler.Health)
+	router.GET("/queue/stats", systemHandler.GetQueueStats)
+	router.GET("/workers", systemHandler.GetWorkers)
+
+	admin := router.Group("/admin")
+	{
+		admin.GET("/leader", systemHandler.GetLeader)
+		admin.GET("/queues", systemHandler.GetQueues)
+
+		deadLetters := admin.Group("/dead-letters")
+		deadLetters.GET("", deadLetterHandler.ListDeadLetters)
+		deadLetters.DELETE("", deadLetterHandler.PurgeDeadLetters)
+		deadLetters.POST("/replay", deadLetterHandler.ReplayAllDeadLetters)
+		deadLetters.GET("/:id", deadLetterHandler.GetDeadLetter)
+		deadLetters.DELETE("/:id", deadLetterHandler.DeleteDeadLetter)
+		deadLetters.POST("/:id/replay", deadLetterHandler.ReplayDeadLetter)
+	}
+
+	v1 := router.Group("/api/v1")
+	{
+		jobs := v1.Group("/jobs")
+		jobs.POST("", jobHandler.CreateJob)
+		jobs.GET("", jobHandler.ListJobs)
+		jobs.GET("/:id", jobHandler.GetJob)
+		jobs.PUT("/:id", jobHandler.UpdateJob)
+		jobs.PATCH("/:id", jobHandler.UpdateJob)
+		jobs.DELETE("/:id", jobHandler.DeleteJob)
+		jobs.POST("/:id/pause", jobHandler.PauseJob)
+		jobs.POST("/:id/resume", jobHandler.ResumeJob)
+		jobs.GET("/:id/history", jobHandler.GetJobHistory)
+		jobs.GET("/:id/schedule", jobHandler.GetJobSchedule)
+
+		credentials := v1.Group("/credentials")
+		credentials.POST("", credentialHandler.CreateCredential)
+		credentials.GET("", credentialHandler.ListCredentials)
+		credentials.GET("/:id", credentialHandler.GetCredential)
+		credentials.PUT("/:id", credentialHandler.UpdateCredential)
+		credentials.DELETE("/:id", credentialHandler.DeleteCredential)
+	}
+
+	return router
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/manyu/job-scheduler/internal/config"
 	"github.com/manyu/job-scheduler/internal/database"
+	"github.com/manyu/job-scheduler/internal/models"
 	"github.com/manyu/job-scheduler/internal/redis"
 	"github.com/manyu/job-scheduler/internal/services"
 	"github.com/manyu/job-scheduler/internal/storage"
@@ -38,13 +40,50 @@ func main() {
 	postgresStorage := storage.NewPostgresStorage(dbService)
 
 	// Initialize job queue service
-	jobQueue := services.NewJobQueueService(redisClient)
+	priorities, err := services.NewPriorityScheduler(cfg.Queue.PriorityPolicy, cfg.Queue.Weights())
+	if err != nil {
+		log.Fatalf("Failed to create priority scheduler: %v", err)
+	}
+	jobQueue, err := services.NewQueue(redisClient, services.QueueOptions{
+		Backend:       cfg.Queue.Backend,
+		RetryDefaults: cfg.Worker.RetryPolicy(),
+		Priorities:    priorities,
+		Subscription: &services.QueueSubscription{
+			Queues: cfg.Worker.Queues,
+			Tags:   models.JobTags(cfg.Worker.Tags).Normalize(),
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to create job queue: %v", err)
+	}
 
 	// Initialize scheduler service
-	schedulerService := services.NewSchedulerService(postgresStorage, redisClient)
+	schedulerService := services.NewSchedulerService(postgresStorage, jobQueue, redisClient)
+
+	// Initialize rate limiter, if any target is limited
+	var rateLimiter *services.RateLimiter
+	if cfg.Worker.RateLimited() {
+		limits := make(map[string]services.RateLimit, len(cfg.Worker.RateLimits))
+		for _, limit := range cfg.Worker.RateLimits {
+			limits[limit.Key] = rateLimit(limit)
+		}
+		rateLimiter = services.NewRateLimiter(redisClient, rateLimit(cfg.Worker.RateLimit), limits)
+	}
+
+	// Initialize the cipher of credential secrets, if an encryption key is configured
+	var credentialCipher *services.CredentialCipher
+	if key, _ := cfg.Credentials.Key(); key != nil {
+		if credentialCipher, err = services.NewCredentialCipher(key); err != nil {
+			log.Fatalf("Failed to create credential cipher: %v", err)
+		}
+	}
+
+	// Initialize the signer and the credentials of outbound calls
+	signer := services.NewRequestSigner(cfg.Worker.SigningSecrets, cfg.Worker.SigningKeySecrets())
+	credentials := services.NewCredentialProvider(postgresStorage, credentialCipher, &http.Client{Timeout: cfg.Worker.HTTPTimeout})
 
 	// Initialize worker service
-	workerService := services.NewWorkerService(jobQueue, postgresStorage, schedulerService)
+	workerService := services.NewWorkerService(jobQueue, postgresStorage, schedulerService, services.NewWorkerRegistry(redisClient), rateLimiter, signer, credentials)
 
 	// Start worker service
 	workerService.Start()
@@ -64,3 +103,12 @@ func main() {
 
 	log.Println("Worker service shutdown complete")
 }
+
+// rateLimit converts a configured rate limit for the rate limiter
+func rateLimit(limit config.RateLimitConfig) services.RateLimit {
+	return services.RateLimit{
+		Rate:        limit.Rate,
+		Burst:       limit.Burst,
+		Concurrency: limit.Concurrency,
+	}
+}
@@ -1,20 +1,25 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/manyu/job-scheduler/internal/models"
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Database  DatabaseConfig  `mapstructure:"database"`
-	Redis     RedisConfig     `mapstructure:"redis"`
-	Server    ServerConfig    `mapstructure:"server"`
-	Scheduler SchedulerConfig `mapstructure:"scheduler"`
-	Worker    WorkerConfig    `mapstructure:"worker"`
-	Logging   LoggingConfig   `mapstructure:"logging"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	Queue       QueueConfig       `mapstructure:"queue"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Scheduler   SchedulerConfig   `mapstructure:"scheduler"`
+	Worker      WorkerConfig      `mapstructure:"worker"`
+	Credentials CredentialsConfig `mapstructure:"credentials"`
+	Logging     LoggingConfig     `mapstructure:"logging"`
 }
 
 // DatabaseConfig holds database configuration
@@ -35,6 +40,22 @@ type RedisConfig struct {
 	DB       int    `mapstructure:"db"`
 }
 
+// QueueConfig holds job queue configuration
+type QueueConfig struct {
+	Backend         string         `mapstructure:"backend"`          // list or stream; schedulers and workers must agree
+	PriorityPolicy  string         `mapstructure:"priority_policy"`  // weighted or strict
+	PriorityWeights map[string]int `mapstructure:"priority_weights"` // Share of dequeues per priority under the weighted policy
+}
+
+// Weights returns the priority weights keyed by priority
+func (c *QueueConfig) Weights() map[models.JobPriority]int {
+	weights := make(map[models.JobPriority]int, len(c.PriorityWeights))
+	for priority, weight := range c.PriorityWeights {
+		weights[models.JobPriority(priority)] = weight
+	}
+	return weights
+}
+
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Host string `mapstructure:"host"`
@@ -44,17 +65,75 @@ type ServerConfig struct {
 
 // SchedulerConfig holds scheduler configuration
 type SchedulerConfig struct {
-	PollInterval time.Duration `mapstructure:"poll_interval"`
-	BatchSize    int           `mapstructure:"batch_size"`
-	HTTPTimeout  time.Duration `mapstructure:"http_timeout"`
+	PollInterval   time.Duration `mapstructure:"poll_interval"`
+	BatchSize      int           `mapstructure:"batch_size"`
+	HTTPTimeout    time.Duration `mapstructure:"http_timeout"`
+	MaxJobTimeout  time.Duration `mapstructure:"max_job_timeout"` // Upper bound for a job's timeoutSeconds
+	LeaderElection bool          `mapstructure:"leader_election"` // Only the elected replica polls for due jobs
+	LeaderLockTTL  time.Duration `mapstructure:"leader_lock_ttl"` // A standby takes over at most 4/3 of this after the leader dies
+	Sharding       bool          `mapstructure:"sharding"`        // Every replica scans its share of the jobs; replaces leader election
+	ShardCount     int           `mapstructure:"shard_count"`     // Number of shards the job IDs are split into
+	ShardLeaseTTL  time.Duration `mapstructure:"shard_lease_ttl"` // Heartbeat and shard lease TTL
 }
 
 // WorkerConfig holds worker configuration
 type WorkerConfig struct {
 	PoolSize    int           `mapstructure:"pool_size"`
 	HTTPTimeout time.Duration `mapstructure:"http_timeout"`
-	RetryDelay  time.Duration `mapstructure:"retry_delay"`
-	MaxRetries  int           `mapstructure:"max_retries"`
+	MaxRetries  int           `mapstructure:"max_retries"` // maxRetryCount of jobs that set none
+
+	// Queues the worker takes jobs from
+	Queues []string `mapstructure:"queues"` // Named queues; comma separated in the environment
+	Tags   []string `mapstructure:"tags"`   // Tags the worker has; it takes the jobs that require a subset of them
+
+	// Default retry policy, for jobs that do not set their own
+	RetryStrategy   string        `mapstructure:"retry_strategy"`   // fixed, linear, exponential
+	RetryDelay      time.Duration `mapstructure:"retry_delay"`      // Delay of the first retry
+	RetryMultiplier float64       `mapstructure:"retry_multiplier"` // Growth factor of the exponential strategy
+	RetryMaxDelay   time.Duration `mapstructure:"retry_max_delay"`  // Upper bound for any retry delay
+	RetryJitter     string        `mapstructure:"retry_jitter"`     // none, full, equal
+
+	// Limits on the calls to each target, shared by all workers
+	RateLimit  RateLimitConfig   `mapstructure:"rate_limit"`  // Applies to every target without a limit of its own
+	RateLimits []RateLimitConfig `mapstructure:"rate_limits"` // Limits of particular targets
+
+	// Secrets outbound calls are signed with; list a new secret before the old one while rotating
+	SigningSecrets []string           `mapstructure:"signing_secrets"` // Jobs without a signingKey; comma separated in the environment
+	SigningKeys    []SigningKeyConfig `mapstructure:"signing_keys"`    // Named secrets jobs select with signingKey, e.g. one per tenant
+}
+
+// SigningKeyConfig holds the active secrets of a signing key jobs select by name
+type SigningKeyConfig struct {
+	Name    string   `mapstructure:"name"`
+	Secrets []string `mapstructure:"secrets"`
+}
+
+// RateLimitConfig bounds the calls workers make to a target: an API host, or the rateLimitKey jobs declare
+type RateLimitConfig struct {
+	Key         string  `mapstructure:"key"`         // API host (with port if not the default) or rateLimitKey
+	Rate        float64 `mapstructure:"rate"`        // Calls per second; 0 is unlimited
+	Burst       int     `mapstructure:"burst"`       // Calls allowed at once after a quiet spell; 0 is the rate rounded up
+	Concurrency int     `mapstructure:"concurrency"` // Calls in flight at once; 0 is unlimited
+}
+
+// CredentialsConfig holds the configuration of the credentials jobs authenticate their calls with
+type CredentialsConfig struct {
+	EncryptionKey string `mapstructure:"encryption_key"` // Base64 of the 32-byte key secrets are encrypted with; schedulers and workers must agree
+}
+
+// Key returns the decoded encryption key, or nil if none is configured
+func (c *CredentialsConfig) Key() ([]byte, error) {
+	if c.EncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(c.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key must be base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
 }
 
 // LoggingConfig holds logging configuration
@@ -122,6 +201,11 @@ func setDefaults() {
 	viper.SetDefault("redis.password", "")
 	viper.SetDefault("redis.db", 0)
 
+	// Queue defaults
+	viper.SetDefault("queue.backend", "list")
+	viper.SetDefault("queue.priority_policy", "weighted")
+	viper.SetDefault("queue.priority_weights", map[string]int{"high": 6, "normal": 3, "low": 1})
+
 	// Server defaults
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.port", "8080")
@@ -131,12 +215,31 @@ func setDefaults() {
 	viper.SetDefault("scheduler.poll_interval", "5s")
 	viper.SetDefault("scheduler.batch_size", 100)
 	viper.SetDefault("scheduler.http_timeout", "30s")
+	viper.SetDefault("scheduler.max_job_timeout", "1h")
+	viper.SetDefault("scheduler.leader_election", true)
+	viper.SetDefault("scheduler.leader_lock_ttl", "15s")
+	viper.SetDefault("scheduler.sharding", false)
+	viper.SetDefault("scheduler.shard_count", 64)
+	viper.SetDefault("scheduler.shard_lease_ttl", "15s")
 
 	// Worker defaults
 	viper.SetDefault("worker.pool_size", 10)
 	viper.SetDefault("worker.http_timeout", "90s")
-	viper.SetDefault("worker.retry_delay", "10s")
+	viper.SetDefault("worker.retry_strategy", "exponential")
+	viper.SetDefault("worker.retry_delay", "1s")
+	viper.SetDefault("worker.retry_multiplier", 2)
+	viper.SetDefault("worker.retry_max_delay", "5m")
+	viper.SetDefault("worker.retry_jitter", "none")
 	viper.SetDefault("worker.max_retries", 3)
+	viper.SetDefault("worker.queues", []string{"default"})
+	viper.SetDefault("worker.tags", []string{})
+	viper.SetDefault("worker.rate_limit.rate", 0)
+	viper.SetDefault("worker.rate_limit.burst", 0)
+	viper.SetDefault("worker.rate_limit.concurrency", 0)
+	viper.SetDefault("worker.signing_secrets", []string{})
+
+	// Credentials defaults
+	viper.SetDefault("credentials.encryption_key", "")
 
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
@@ -163,18 +266,161 @@ func (c *Config) Validate() error {
 	if c.Redis.Port == "" {
 		return fmt.Errorf("redis port is required")
 	}
+	if c.Queue.Backend != "list" && c.Queue.Backend != "stream" {
+		return fmt.Errorf("queue backend must be list or stream")
+	}
+	if c.Queue.PriorityPolicy != "weighted" && c.Queue.PriorityPolicy != "strict" {
+		return fmt.Errorf("queue priority policy must be weighted or strict")
+	}
+	for priority, weight := range c.Queue.Weights() {
+		if !priority.IsValid() {
+			return fmt.Errorf("queue priority weights: unknown priority %q", priority)
+		}
+		if weight <= 0 {
+			return fmt.Errorf("queue priority weight of %s must be positive", priority)
+		}
+	}
 	if c.Server.Port == "" {
 		return fmt.Errorf("server port is required")
 	}
 	if c.Scheduler.PollInterval <= 0 {
 		return fmt.Errorf("scheduler poll interval must be positive")
 	}
+	if c.Scheduler.MaxJobTimeout < time.Second {
+		return fmt.Errorf("scheduler max job timeout must be at least 1s")
+	}
+	if c.Scheduler.LeaderElection && c.Scheduler.LeaderLockTTL < 3*time.Second {
+		return fmt.Errorf("scheduler leader lock ttl must be at least 3s")
+	}
+	if c.Scheduler.Sharding && c.Scheduler.ShardCount <= 0 {
+		return fmt.Errorf("scheduler shard count must be positive")
+	}
+	if c.Scheduler.Sharding && c.Scheduler.ShardLeaseTTL < 3*time.Second {
+		return fmt.Errorf("scheduler shard lease ttl must be at least 3s")
+	}
 	if c.Worker.PoolSize <= 0 {
 		return fmt.Errorf("worker pool size must be positive")
 	}
+	if c.Worker.MaxRetries < 0 {
+		return fmt.Errorf("worker max retries must not be negative")
+	}
+	if c.Worker.RetryDelay < time.Second {
+		return fmt.Errorf("worker retry delay must be at least 1s")
+	}
+	retryPolicy := c.Worker.RetryPolicy()
+	if err := retryPolicy.Validate(); err != nil {
+		return fmt.Errorf("worker retry policy: %w", err)
+	}
+	if len(c.Worker.Queues) == 0 && len(c.Worker.Tags) == 0 {
+		return fmt.Errorf("worker must subscribe to at least one queue or tag")
+	}
+	for _, queue := range c.Worker.Queues {
+		if err := models.ValidateQueueName(queue); err != nil {
+			return fmt.Errorf("worker queues: %w", err)
+		}
+	}
+	if err := models.JobTags(c.Worker.Tags).Validate(); err != nil {
+		return fmt.Errorf("worker tags: %w", err)
+	}
+	if err := c.Worker.RateLimit.Validate(); err != nil {
+		return fmt.Errorf("worker rate limit: %w", err)
+	}
+	keys := make(map[string]bool, len(c.Worker.RateLimits))
+	for _, limit := range c.Worker.RateLimits {
+		if limit.Key == "" {
+			return fmt.Errorf("worker rate limits: key is required")
+		}
+		if keys[strings.ToLower(limit.Key)] {
+			return fmt.Errorf("worker rate limits: duplicate key %q", limit.Key)
+		}
+		keys[strings.ToLower(limit.Key)] = true
+		if err := limit.Validate(); err != nil {
+			return fmt.Errorf("worker rate limit of %s: %w", limit.Key, err)
+		}
+	}
+	if _, err := c.Credentials.Key(); err != nil {
+		return fmt.Errorf("credentials: %w", err)
+	}
+	if err := validateSigningSecrets(c.Worker.SigningSecrets); err != nil {
+		return fmt.Errorf("worker signing secrets: %w", err)
+	}
+	names := make(map[string]bool, len(c.Worker.SigningKeys))
+	for _, key := range c.Worker.SigningKeys {
+		if key.Name == "" {
+			return fmt.Errorf("worker signing keys: name is required")
+		}
+		if names[key.Name] {
+			return fmt.Errorf("worker signing keys: duplicate name %q", key.Name)
+		}
+		names[key.Name] = true
+		if len(key.Secrets) == 0 {
+			return fmt.Errorf("worker signing key %s: at least one secret is required", key.Name)
+		}
+		if err := validateSigningSecrets(key.Secrets); err != nil {
+			return fmt.Errorf("worker signing key %s: %w", key.Name, err)
+		}
+	}
+	return nil
+}
+
+// minSigningSecretLength is the shortest secret accepted for signing calls
+const minSigningSecretLength = 16
+
+// validateSigningSecrets checks that signing secrets are long enough to resist guessing
+func validateSigningSecrets(secrets []string) error {
+	for _, secret := range secrets {
+		if len(secret) < minSigningSecretLength {
+			return fmt.Errorf("secrets must be at least %d characters", minSigningSecretLength)
+		}
+	}
 	return nil
 }
 
+// Validate checks that the limit's bounds are not negative
+func (c *RateLimitConfig) Validate() error {
+	if c.Rate < 0 {
+		return fmt.Errorf("rate cannot be negative")
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("burst cannot be negative")
+	}
+	if c.Concurrency < 0 {
+		return fmt.Errorf("concurrency cannot be negative")
+	}
+	return nil
+}
+
+// RateLimited reports whether any target has a rate limit or concurrency cap
+func (c *WorkerConfig) RateLimited() bool {
+	limits := append([]RateLimitConfig{c.RateLimit}, c.RateLimits...)
+	for _, limit := range limits {
+		if limit.Rate > 0 || limit.Concurrency > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SigningKeySecrets returns the secrets of each named signing key
+func (c *WorkerConfig) SigningKeySecrets() map[string][]string {
+	keys := make(map[string][]string, len(c.SigningKeys))
+	for _, key := range c.SigningKeys {
+		keys[key.Name] = key.Secrets
+	}
+	return keys
+}
+
+// RetryPolicy returns the retry policy applied to jobs that do not set their own
+func (c *WorkerConfig) RetryPolicy() models.RetryPolicy {
+	return models.RetryPolicy{
+		Strategy:         models.RetryStrategy(c.RetryStrategy),
+		BaseDelaySeconds: int(c.RetryDelay / time.Second),
+		Multiplier:       c.RetryMultiplier,
+		MaxDelaySeconds:  int(c.RetryMaxDelay / time.Second),
+		Jitter:           models.RetryJitter(c.RetryJitter),
+	}
+}
+
 // GetDSN returns the database connection string
 func (c *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
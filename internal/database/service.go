@@ -18,7 +18,8 @@ type DatabaseService struct {
 // NewDatabaseService creates a new database service
 func NewDatabaseService(dsn string) (*DatabaseService, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true, // Report unique violations as gorm.ErrDuplicatedKey
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -47,6 +48,7 @@ func (ds *DatabaseService) AutoMigrate() error {
 		&models.Job{},
 		&models.JobSchedule{},
 		&models.JobExecution{},
+		&models.Credential{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto-migrate database: %w", err)
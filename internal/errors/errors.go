@@ -47,13 +47,31 @@ func Wrap(err error, code, message string, httpStatus int) *AppError {
 // Predefined application errors
 var (
 	// Validation errors
-	ErrInvalidRequest  = NewAppError("INVALID_REQUEST", "Invalid request", http.StatusBadRequest)
-	ErrInvalidJobType  = NewAppError("INVALID_JOB_TYPE", "Invalid job type. Must be AT_LEAST_ONCE or AT_MOST_ONCE", http.StatusBadRequest)
-	ErrInvalidSchedule = NewAppError("INVALID_SCHEDULE", "Invalid schedule format", http.StatusBadRequest)
+	ErrInvalidRequest         = NewAppError("INVALID_REQUEST", "Invalid request", http.StatusBadRequest)
+	ErrInvalidJobType         = NewAppError("INVALID_JOB_TYPE", "Invalid job type. Must be AT_LEAST_ONCE or AT_MOST_ONCE", http.StatusBadRequest)
+	ErrInvalidSchedule        = NewAppError("INVALID_SCHEDULE", "Invalid schedule format", http.StatusBadRequest)
+	ErrInvalidTimezone        = NewAppError("INVALID_TIMEZONE", "Invalid time zone", http.StatusBadRequest)
+	ErrInvalidMisfirePolicy   = NewAppError("INVALID_MISFIRE_POLICY", "Invalid misfire policy", http.StatusBadRequest)
+	ErrInvalidRequestSpec     = NewAppError("INVALID_REQUEST_SPEC", "Invalid HTTP request spec", http.StatusBadRequest)
+	ErrInvalidTimeout         = NewAppError("INVALID_TIMEOUT", "Invalid job timeout", http.StatusBadRequest)
+	ErrInvalidMaxRetryCount   = NewAppError("INVALID_MAX_RETRY_COUNT", "Invalid max retry count", http.StatusBadRequest)
+	ErrInvalidSuccessCriteria = NewAppError("INVALID_SUCCESS_CRITERIA", "Invalid success criteria", http.StatusBadRequest)
+	ErrInvalidRetryPolicy     = NewAppError("INVALID_RETRY_POLICY", "Invalid retry policy", http.StatusBadRequest)
+	ErrInvalidPriority        = NewAppError("INVALID_PRIORITY", "Invalid priority. Must be high, normal or low", http.StatusBadRequest)
+	ErrInvalidQueue           = NewAppError("INVALID_QUEUE", "Invalid queue or tags", http.StatusBadRequest)
+	ErrInvalidConcurrency     = NewAppError("INVALID_CONCURRENCY_POLICY", "Invalid concurrency policy. Must be Allow, Forbid, Replace or Queue", http.StatusBadRequest)
+	ErrIdempotencyKeyReused   = NewAppError("IDEMPOTENCY_KEY_REUSED", "Idempotency key was already used for a different request", http.StatusUnprocessableEntity)
+	ErrInvalidCredential      = NewAppError("INVALID_CREDENTIAL", "Invalid credential", http.StatusBadRequest)
 
 	// Resource errors
-	ErrJobNotFound         = NewAppError("JOB_NOT_FOUND", "Job not found", http.StatusNotFound)
-	ErrJobScheduleNotFound = NewAppError("JOB_SCHEDULE_NOT_FOUND", "Job schedule not found", http.StatusNotFound)
+	ErrJobNotFound            = NewAppError("JOB_NOT_FOUND", "Job not found", http.StatusNotFound)
+	ErrJobScheduleNotFound    = NewAppError("JOB_SCHEDULE_NOT_FOUND", "Job schedule not found", http.StatusNotFound)
+	ErrLeaderElectionDisabled = NewAppError("LEADER_ELECTION_DISABLED", "Leader election is disabled", http.StatusNotFound)
+	ErrDeadLetterNotFound     = NewAppError("DEAD_LETTER_NOT_FOUND", "Dead letter not found", http.StatusNotFound)
+	ErrCredentialNotFound     = NewAppError("CREDENTIAL_NOT_FOUND", "Credential not found", http.StatusNotFound)
+	ErrCredentialExists       = NewAppError("CREDENTIAL_EXISTS", "A credential with this name already exists", http.StatusConflict)
+	ErrCredentialInUse        = NewAppError("CREDENTIAL_IN_USE", "Credential is used by jobs", http.StatusConflict)
+	ErrCredentialsDisabled    = NewAppError("CREDENTIALS_DISABLED", "No credential encryption key is configured", http.StatusServiceUnavailable)
 
 	// Server errors
 	ErrInternalServer = NewAppError("INTERNAL_SERVER_ERROR", "Internal server error", http.StatusInternalServerError)
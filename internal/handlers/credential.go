@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manyu/job-scheduler/internal/errors"
+	"github.com/manyu/job-scheduler/internal/middleware"
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/services"
+	"github.com/manyu/job-scheduler/internal/storage"
+)
+
+// CredentialHandler manages the credentials jobs authenticate their calls with. Secrets are encrypted
+// before they are stored and never returned.
+type CredentialHandler struct {
+	storage storage.Storage
+	cipher  *services.CredentialCipher // nil when no encryption key is configured
+}
+
+func NewCredentialHandler(storage storage.Storage, cipher *services.CredentialCipher) *CredentialHandler {
+	return &CredentialHandler{
+		storage: storage,
+		cipher:  cipher,
+	}
+}
+
+// CredentialRequest creates or replaces a credential. Only the secret of its type is used.
+type CredentialRequest struct {
+	Name         string                `json:"name"`
+	Type         models.CredentialType `json:"type" binding:"required"` // bearer, basic or oauth2_client_credentials
+	Description  string                `json:"description"`
+	Token        string                `json:"token"`        // bearer
+	Username     string                `json:"username"`     // basic
+	Password     string                `json:"password"`     // basic
+	ClientID     string                `json:"clientId"`     // oauth2_client_credentials
+	ClientSecret string                `json:"clientSecret"` // oauth2_client_credentials
+	TokenURL     string                `json:"tokenUrl"`     // oauth2_client_credentials
+	Scopes       string                `json:"scopes"`       // oauth2_client_credentials, space separated
+}
+
+// secret returns the secret of the request's type and the field it is sent in
+func (r *CredentialRequest) secret() (string, string) {
+	switch r.Type {
+	case models.CredentialBasic:
+		return r.Password, "password"
+	case models.CredentialOAuth2:
+		return r.ClientSecret, "clientSecret"
+	default:
+		return r.Token, "token"
+	}
+}
+
+// apply copies the request's fields, apart from the name and secret, onto credential
+func (r *CredentialRequest) apply(credential *models.Credential) {
+	credential.Type = r.Type
+	credential.Description = r.Description
+	credential.Username, credential.ClientID, credential.TokenURL, credential.Scopes = "", "", "", ""
+	switch r.Type {
+	case models.CredentialBasic:
+		credential.Username = r.Username
+	case models.CredentialOAuth2:
+		credential.ClientID = r.ClientID
+		credential.TokenURL = r.TokenURL
+		credential.Scopes = r.Scopes
+	}
+}
+
+// CreateCredential handles POST /credentials
+func (h *CredentialHandler) CreateCredential(c *gin.Context) {
+	if h.cipher == nil {
+		middleware.HandleError(c, errors.ErrCredentialsDisabled)
+		return
+	}
+
+	var req CredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	credential := &models.Credential{Name: req.Name}
+	req.apply(credential)
+	if err := credential.Validate(); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidCredential.WithDetails(err.Error()))
+		return
+	}
+
+	secret, field := req.secret()
+	if secret == "" {
+		middleware.HandleError(c, errors.ErrInvalidCredential.WithDetails(fmt.Sprintf("%s is required", field)))
+		return
+	}
+	if !h.encryptSecret(c, credential, secret) {
+		return
+	}
+
+	if err := h.storage.CreateCredential(credential); err != nil {
+		if err == storage.ErrDuplicateCredentialName {
+			middleware.HandleError(c, errors.ErrCredentialExists.WithDetails(credential.Name))
+			return
+		}
+		middleware.HandleError(c, errors.ErrDatabaseError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+// ListCredentials handles GET /credentials
+func (h *CredentialHandler) ListCredentials(c *gin.Context) {
+	credentials, err := h.storage.ListCredentials()
+	if err != nil {
+		middleware.HandleError(c, errors.ErrDatabaseError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"credentials": credentials,
+		"total":       len(credentials),
+	})
+}
+
+// GetCredential handles GET /credentials/:id
+func (h *CredentialHandler) GetCredential(c *gin.Context) {
+	credential, ok := h.loadCredential(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+// UpdateCredential handles PUT /credentials/:id. The name cannot change, since jobs reference it.
+// The secret is kept if none is sent, unless the type changes.
+func (h *CredentialHandler) UpdateCredential(c *gin.Context) {
+	if h.cipher == nil {
+		middleware.HandleError(c, errors.ErrCredentialsDisabled)
+		return
+	}
+
+	credential, ok := h.loadCredential(c)
+	if !ok {
+		return
+	}
+
+	var req CredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+	if req.Name != "" && req.Name != credential.Name {
+		middleware.HandleError(c, errors.ErrInvalidCredential.WithDetails("the name of a credential cannot change"))
+		return
+	}
+
+	typeChanged := req.Type != credential.Type
+	req.apply(credential)
+	if err := credential.Validate(); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidCredential.WithDetails(err.Error()))
+		return
+	}
+
+	secret, field := req.secret()
+	if secret == "" && typeChanged {
+		middleware.HandleError(c, errors.ErrInvalidCredential.WithDetails(fmt.Sprintf("%s is required when the type changes", field)))
+		return
+	}
+	if secret != "" && !h.encryptSecret(c, credential, secret) {
+		return
+	}
+
+	if err := h.storage.UpdateCredential(credential); err != nil {
+		middleware.HandleError(c, errors.ErrDatabaseError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+// DeleteCredential handles DELETE /credentials/:id. Credentials that jobs use cannot be deleted.
+func (h *CredentialHandler) DeleteCredential(c *gin.Context) {
+	credential, ok := h.loadCredential(c)
+	if !ok {
+		return
+	}
+
+	jobs, err := h.storage.CountJobsUsingCredential(credential.Name)
+	if err != nil {
+		middleware.HandleError(c, errors.ErrDatabaseError.WithDetails(err.Error()))
+		return
+	}
+	if jobs > 0 {
+		middleware.HandleError(c, errors.ErrCredentialInUse.WithDetails(fmt.Sprintf("%d job(s) use %s", jobs, credential.Name)))
+		return
+	}
+
+	if err := h.storage.DeleteCredential(credential.ID); err != nil {
+		if err == storage.ErrCredentialNotFound {
+			middleware.HandleError(c, errors.ErrCredentialNotFound)
+			return
+		}
+		middleware.HandleError(c, errors.ErrDatabaseError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      credential.ID,
+		"message": "Credential deleted successfully",
+	})
+}
+
+// encryptSecret sets the encrypted secret of credential, or answers the request and returns false
+func (h *CredentialHandler) encryptSecret(c *gin.Context, credential *models.Credential, secret string) bool {
+	sealed, err := h.cipher.Encrypt(secret)
+	if err != nil {
+		middleware.HandleError(c, errors.ErrInternalServer.WithDetails(err.Error()))
+		return false
+	}
+	credential.Secret = sealed
+	return true
+}
+
+// loadCredential returns the credential named by the :id path parameter, or answers the request and
+// returns false
+func (h *CredentialHandler) loadCredential(c *gin.Context) (*models.Credential, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.HandleError(c, errors.NewAppError("INVALID_CREDENTIAL_ID", "Invalid credential ID", http.StatusBadRequest))
+		return nil, false
+	}
+
+	credential, err := h.storage.GetCredential(uint(id))
+	if err != nil {
+		if err == storage.ErrCredentialNotFound {
+			middleware.HandleError(c, errors.ErrCredentialNotFound)
+			return nil, false
+		}
+		middleware.HandleError(c, errors.ErrDatabaseError.WithDetails(err.Error()))
+		return nil, false
+	}
+	return credential, true
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCredentialHandler returns a credential handler encrypting with a fixed key
+func newTestCredentialHandler(t *testing.T, mockStorage *MockStorage) (*CredentialHandler, *services.CredentialCipher) {
+	cipher, err := services.NewCredentialCipher(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+	return NewCredentialHandler(mockStorage, cipher), cipher
+}
+
+func TestCredentialHandler_CreateCredential_EncryptsSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler, cipher := newTestCredentialHandler(t, mockStorage)
+
+	var stored *models.Credential
+	mockStorage.On("CreateCredential", mock.AnythingOfType("*models.Credential")).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*models.Credential)
+		stored.ID = 3
+	}).Return(nil)
+
+	body, _ := json.Marshal(CredentialRequest{
+		Name:         "partner-oauth",
+		Type:         models.CredentialOAuth2,
+		ClientID:     "scheduler",
+		ClientSecret: "client-secret",
+		TokenURL:     "https://auth.partner.com/oauth/token",
+		Token:        "ignored",
+	})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/v1/credentials", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.CreateCredential(c)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "client-secret")
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "partner-oauth", response["name"])
+	assert.NotContains(t, response, "secret")
+	secret, err := cipher.Decrypt(stored.Secret)
+	require.NoError(t, err)
+	assert.Equal(t, "client-secret", secret)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCredentialHandler_CreateCredential_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		request  CredentialRequest
+		disabled bool
+		status   int
+	}{
+		{name: "missing secret", request: CredentialRequest{Name: "partner", Type: models.CredentialBearer}, status: http.StatusBadRequest},
+		{name: "basic without username", request: CredentialRequest{Name: "partner", Type: models.CredentialBasic, Password: "pa55"}, status: http.StatusBadRequest},
+		{name: "oauth2 without token url", request: CredentialRequest{Name: "partner", Type: models.CredentialOAuth2, ClientID: "id", ClientSecret: "secret"}, status: http.StatusBadRequest},
+		{name: "invalid name", request: CredentialRequest{Name: "Partner Token", Type: models.CredentialBearer, Token: "tok"}, status: http.StatusBadRequest},
+		{name: "unknown type", request: CredentialRequest{Name: "partner", Type: "api_key", Token: "tok"}, status: http.StatusBadRequest},
+		{name: "no encryption key", request: CredentialRequest{Name: "partner", Type: models.CredentialBearer, Token: "tok"}, disabled: true, status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			handler, _ := newTestCredentialHandler(t, mockStorage)
+			if tt.disabled {
+				handler = NewCredentialHandler(mockStorage, nil)
+			}
+
+			body, _ := json.Marshal(tt.request)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("POST", "/api/v1/credentials", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.CreateCredential(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			mockStorage.AssertNotCalled(t, "CreateCredential", mock.Anything)
+		})
+	}
+}
+
+func TestCredentialHandler_DeleteCredential_InUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler, _ := newTestCredentialHandler(t, mockStorage)
+
+	mockStorage.On("GetCredential", uint(3)).Return(&models.Credential{ID: 3, Name: "partner-token"}, nil)
+	mockStorage.On("CountJobsUsingCredential", "partner-token").Return(int64(2), nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/api/v1/credentials/3", nil)
+	c.Params = gin.Params{{Key: "id", Value: "3"}}
+
+	handler.DeleteCredential(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockStorage.AssertNotCalled(t, "DeleteCredential", mock.Anything)
+}
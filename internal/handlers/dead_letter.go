@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manyu/job-scheduler/internal/errors"
+	"github.com/manyu/job-scheduler/internal/middleware"
+	"github.com/manyu/job-scheduler/internal/services"
+)
+
+// DeadLetterHandler serves the admin endpoints of the dead-letter queue
+type DeadLetterHandler struct {
+	deadLetters services.DeadLetterQueueInterface
+}
+
+func NewDeadLetterHandler(deadLetters services.DeadLetterQueueInterface) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetters: deadLetters,
+	}
+}
+
+// ListDeadLetters handles GET /admin/dead-letters
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	letters, total, err := h.deadLetters.List(limit, offset)
+	if err != nil {
+		middleware.HandleError(c, errors.ErrRedisError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deadLetters": letters,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// GetDeadLetter handles GET /admin/dead-letters/:id
+func (h *DeadLetterHandler) GetDeadLetter(c *gin.Context) {
+	letter, err := h.deadLetters.Get(c.Param("id"))
+	if err != nil {
+		handleDeadLetterError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, letter)
+}
+
+// ReplayDeadLetter handles POST /admin/dead-letters/:id/replay
+func (h *DeadLetterHandler) ReplayDeadLetter(c *gin.Context) {
+	job, err := h.deadLetters.Replay(c.Param("id"))
+	if err != nil {
+		handleDeadLetterError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Dead letter replayed successfully",
+		"queueJobId": job.ID,
+		"jobId":      job.JobID,
+	})
+}
+
+// ReplayAllDeadLetters handles POST /admin/dead-letters/replay
+func (h *DeadLetterHandler) ReplayAllDeadLetters(c *gin.Context) {
+	replayed, err := h.deadLetters.ReplayAll()
+	if err != nil {
+		middleware.HandleError(c, errors.ErrQueueError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Dead letters replayed successfully",
+		"replayed": replayed,
+	})
+}
+
+// DeleteDeadLetter handles DELETE /admin/dead-letters/:id
+func (h *DeadLetterHandler) DeleteDeadLetter(c *gin.Context) {
+	if err := h.deadLetters.Delete(c.Param("id")); err != nil {
+		handleDeadLetterError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dead letter deleted successfully",
+	})
+}
+
+// PurgeDeadLetters handles DELETE /admin/dead-letters
+func (h *DeadLetterHandler) PurgeDeadLetters(c *gin.Context) {
+	purged, err := h.deadLetters.Purge()
+	if err != nil {
+		middleware.HandleError(c, errors.ErrRedisError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dead letters purged successfully",
+		"purged":  purged,
+	})
+}
+
+func handleDeadLetterError(c *gin.Context, err error) {
+	if err == services.ErrDeadLetterNotFound {
+		middleware.HandleError(c, errors.ErrDeadLetterNotFound.WithDetails(c.Param("id")))
+		return
+	}
+	middleware.HandleError(c, errors.ErrQueueError.WithDetails(err.Error()))
+}
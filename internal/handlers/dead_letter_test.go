@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/services"
+	mock_services "github.com/manyu/job-scheduler/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func testDeadLetter() *models.DeadLetter {
+	failedAt := time.Now()
+	return &models.DeadLetter{
+		ID:     "job_7_1700000000",
+		Job:    &models.QueueJob{ID: "job_7_1700000000", JobID: 7, API: "https://example.com/hook", Type: models.AT_LEAST_ONCE, MaxRetryCount: 1, RetryCount: 1},
+		Status: models.QueueStatusFailed,
+		Error:  "HTTP 503",
+		Attempts: []models.QueueJobAttempt{
+			{Attempt: 1, Error: "HTTP 503", FailedAt: failedAt.Add(-time.Minute)},
+			{Attempt: 2, Error: "HTTP 503", FailedAt: failedAt},
+		},
+		FailedAt: failedAt,
+	}
+}
+
+func TestDeadLetterHandler_ListDeadLetters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	deadLetters := mock_services.NewMockDeadLetterQueueInterface(ctrl)
+	handler := NewDeadLetterHandler(deadLetters)
+
+	deadLetters.EXPECT().List(5, 10).Return([]*models.DeadLetter{testDeadLetter()}, int64(11), nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/admin/dead-letters?limit=5&offset=10", nil)
+
+	handler.ListDeadLetters(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		DeadLetters []map[string]interface{} `json:"deadLetters"`
+		Total       int                      `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 11, response.Total)
+	assert.Equal(t, "job_7_1700000000", response.DeadLetters[0]["id"])
+	assert.Len(t, response.DeadLetters[0]["attempts"], 2)
+}
+
+func TestDeadLetterHandler_GetDeadLetter_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	deadLetters := mock_services.NewMockDeadLetterQueueInterface(ctrl)
+	handler := NewDeadLetterHandler(deadLetters)
+
+	deadLetters.EXPECT().Get("missing").Return(nil, services.ErrDeadLetterNotFound)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/admin/dead-letters/missing", nil)
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+
+	handler.GetDeadLetter(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeadLetterHandler_ReplayDeadLetter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	deadLetters := mock_services.NewMockDeadLetterQueueInterface(ctrl)
+	handler := NewDeadLetterHandler(deadLetters)
+
+	replayed := testDeadLetter().Job.Replay()
+	deadLetters.EXPECT().Replay("job_7_1700000000").Return(replayed, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/admin/dead-letters/job_7_1700000000/replay", nil)
+	c.Params = gin.Params{{Key: "id", Value: "job_7_1700000000"}}
+
+	handler.ReplayDeadLetter(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, replayed.ID, response["queueJobId"])
+	assert.Equal(t, float64(7), response["jobId"])
+}
+
+func TestDeadLetterHandler_PurgeDeadLetters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	deadLetters := mock_services.NewMockDeadLetterQueueInterface(ctrl)
+	handler := NewDeadLetterHandler(deadLetters)
+
+	deadLetters.EXPECT().Purge().Return(int64(3), nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/admin/dead-letters", nil)
+
+	handler.PurgeDeadLetters(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, float64(3), response["purged"])
+}
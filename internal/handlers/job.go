@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/manyu/job-scheduler/internal/errors"
@@ -12,26 +17,62 @@ import (
 	"github.com/manyu/job-scheduler/internal/utils"
 )
 
+// defaultMaxJobTimeout caps job timeouts when the server does not configure a maximum
+const defaultMaxJobTimeout = time.Hour
+
+// defaultMaxRetryCount is the maxRetryCount of jobs that set none when the server passes a negative default
+const defaultMaxRetryCount = 3
+
 type JobHandler struct {
 	storage        storage.Storage
 	scheduleParser *utils.ScheduleParser
+	maxJobTimeout  time.Duration
+	maxRetryCount  int // Default for jobs that set none
 }
 
-func NewJobHandler(storage storage.Storage) *JobHandler {
+func NewJobHandler(storage storage.Storage, maxJobTimeout time.Duration, maxRetryCount int) *JobHandler {
+	if maxJobTimeout <= 0 {
+		maxJobTimeout = defaultMaxJobTimeout
+	}
+	if maxRetryCount < 0 {
+		maxRetryCount = defaultMaxRetryCount
+	}
 	return &JobHandler{
 		storage:        storage,
 		scheduleParser: utils.NewScheduleParser(),
+		maxJobTimeout:  maxJobTimeout,
+		maxRetryCount:  maxRetryCount,
 	}
 }
 
 // CreateJobRequest represents the request payload for creating a job
 type CreateJobRequest struct {
-	Schedule      string         `json:"schedule" binding:"required"`
-	API           string         `json:"api" binding:"required"`
-	Type          models.JobType `json:"type" binding:"required"`
-	IsRecurring   bool           `json:"isRecurring"`
-	Description   string         `json:"description"`
-	MaxRetryCount int            `json:"maxRetryCount"`
+	Schedule                string                   `json:"schedule"` // Cron expression; exactly one of schedule, runAt or delay is required
+	RunAt                   *time.Time               `json:"runAt"`    // One-shot run time (RFC3339)
+	Delay                   string                   `json:"delay"`    // One-shot delay from now, e.g. "15m"
+	Timezone                string                   `json:"timezone"` // IANA zone for the schedule; defaults to UTC
+	IntervalAnchor          models.IntervalAnchor    `json:"intervalAnchor"`
+	StartAt                 *time.Time               `json:"startAt"`
+	EndAt                   *time.Time               `json:"endAt"`
+	MaxRuns                 int                      `json:"maxRuns"`
+	MisfirePolicy           models.MisfirePolicy     `json:"misfirePolicy"`           // Defaults to fire_once_now
+	MisfireThresholdSeconds int                      `json:"misfireThresholdSeconds"` // Lateness tolerated before the policy applies; defaults to 60
+	API                     string                   `json:"api" binding:"required"`
+	Request                 *models.HTTPRequestSpec  `json:"request"`
+	SuccessCriteria         *models.SuccessCriteria  `json:"successCriteria"`
+	Type                    models.JobType           `json:"type" binding:"required"`
+	Priority                models.JobPriority       `json:"priority"`          // high, normal or low; defaults to normal
+	Queue                   string                   `json:"queue"`             // Named queue; defaults to default
+	Tags                    models.JobTags           `json:"tags"`              // Tags a worker needs to run the job; excludes queue
+	ConcurrencyPolicy       models.ConcurrencyPolicy `json:"concurrencyPolicy"` // Allow, Forbid, Replace or Queue; defaults to Forbid
+	RateLimitKey            string                   `json:"rateLimitKey"`      // Rate limit bucket shared with other jobs; defaults to the API host
+	SigningKey              string                   `json:"signingKey"`        // Named secrets calls are signed with; defaults to the worker's default secrets
+	Credential              string                   `json:"credential"`        // Name of the credential calls authenticate with
+	IsRecurring             bool                     `json:"isRecurring"`
+	Description             string                   `json:"description"`
+	MaxRetryCount           *int                     `json:"maxRetryCount"`  // Defaults to the server's worker.max_retries
+	RetryPolicy             *models.RetryPolicy      `json:"retryPolicy"`    // Empty fields take the worker's defaults
+	TimeoutSeconds          int                      `json:"timeoutSeconds"` // Defaults to 90; bounded by the server maximum
 }
 
 // CreateJobResponse represents the response for creating a job
@@ -40,7 +81,8 @@ type CreateJobResponse struct {
 	Message string `json:"message"`
 }
 
-// CreateJob handles POST /jobs
+// CreateJob handles POST /jobs. A request with an Idempotency-Key header creates the job once;
+// repeating it returns the response of the first request.
 func (h *JobHandler) CreateJob(c *gin.Context) {
 	var req CreateJobRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -48,36 +90,192 @@ func (h *JobHandler) CreateJob(c *gin.Context) {
 		return
 	}
 
+	idempotencyKey := c.GetHeader(models.IdempotencyKeyHeader)
+	var requestHash string
+	if idempotencyKey != "" {
+		if len(idempotencyKey) > models.MaxIdempotencyKeyLength {
+			middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails(fmt.Sprintf("%s must be at most %d characters", models.IdempotencyKeyHeader, models.MaxIdempotencyKeyLength)))
+			return
+		}
+		requestHash = hashCreateJobRequest(&req)
+		if h.replayCreateJob(c, idempotencyKey, requestHash) {
+			return
+		}
+	}
+
 	// Validate job type
 	if req.Type != models.AT_LEAST_ONCE && req.Type != models.AT_MOST_ONCE {
 		middleware.HandleError(c, errors.ErrInvalidJobType)
 		return
 	}
 
-	// Validate CRON schedule format
-	if err := h.scheduleParser.ValidateSchedule(req.Schedule); err != nil {
+	// Validate the CRON schedule, or resolve the run time of a one-shot job
+	runAt, err := resolveRunAt(req.Schedule != "", req.RunAt, req.Delay, time.Now())
+	if err != nil {
 		middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails(err.Error()))
 		return
 	}
+	if runAt != nil && req.IsRecurring {
+		middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails("one-shot jobs cannot be recurring"))
+		return
+	}
+	if runAt == nil {
+		if err := h.scheduleParser.ValidateSchedule(req.Schedule); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails(err.Error()))
+			return
+		}
+	}
+
+	// Validate time zone
+	if err := h.scheduleParser.ValidateTimezone(req.Timezone); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidTimezone.WithDetails(err.Error()))
+		return
+	}
+
+	// Validate HTTP request spec
+	var requestSpec models.HTTPRequestSpec
+	if req.Request != nil {
+		if err := req.Request.Validate(); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidRequestSpec.WithDetails(err.Error()))
+			return
+		}
+		requestSpec = *req.Request
+	}
+	requestSpec.Normalize()
+
+	// Validate success criteria
+	var successCriteria models.SuccessCriteria
+	if req.SuccessCriteria != nil {
+		if err := req.SuccessCriteria.Validate(); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidSuccessCriteria.WithDetails(err.Error()))
+			return
+		}
+		successCriteria = *req.SuccessCriteria
+	}
+
+	// Validate priority
+	if req.Priority != "" && !req.Priority.IsValid() {
+		middleware.HandleError(c, errors.ErrInvalidPriority)
+		return
+	}
+
+	// Validate concurrency policy
+	if req.ConcurrencyPolicy != "" && !req.ConcurrencyPolicy.IsValid() {
+		middleware.HandleError(c, errors.ErrInvalidConcurrency)
+		return
+	}
+
+	// Validate queue routing
+	queue, tags, err := resolveRouting(req.Queue, req.Tags)
+	if err != nil {
+		middleware.HandleError(c, errors.ErrInvalidQueue.WithDetails(err.Error()))
+		return
+	}
+
+	// Validate rate limit and signing keys
+	if err := validateRateLimitKey(req.RateLimitKey); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+	if err := validateSigningKey(req.SigningKey); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	// Validate credential
+	if !h.checkCredential(c, req.Credential) {
+		return
+	}
+
+	// Validate retry policy
+	var retryPolicy models.RetryPolicy
+	if req.RetryPolicy != nil {
+		if err := req.RetryPolicy.Validate(); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidRetryPolicy.WithDetails(err.Error()))
+			return
+		}
+		retryPolicy = *req.RetryPolicy
+	}
+
+	// Validate timeout
+	if err := h.validateTimeout(req.TimeoutSeconds); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidTimeout.WithDetails(err.Error()))
+		return
+	}
+
+	// Validate max retry count
+	if req.MaxRetryCount != nil {
+		if err := validateMaxRetryCount(*req.MaxRetryCount); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidMaxRetryCount.WithDetails(err.Error()))
+			return
+		}
+	}
+
+	// Validate misfire policy
+	if err := validateMisfirePolicy(req.MisfirePolicy, req.MisfireThresholdSeconds); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidMisfirePolicy.WithDetails(err.Error()))
+		return
+	}
 
 	// Set default values
-	if req.MaxRetryCount == 0 {
-		req.MaxRetryCount = 3
+	maxRetryCount := h.maxRetryCount
+	if req.MaxRetryCount != nil {
+		maxRetryCount = *req.MaxRetryCount
+	}
+	if req.TimeoutSeconds == 0 {
+		req.TimeoutSeconds = models.DefaultJobTimeoutSeconds
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if req.MisfirePolicy == "" {
+		req.MisfirePolicy = models.MisfireFireOnceNow
+	}
+	if req.MisfireThresholdSeconds == 0 {
+		req.MisfireThresholdSeconds = models.DefaultMisfireThresholdSeconds
 	}
 
 	// Create job model
 	job := &models.Job{
-		Schedule:      req.Schedule,
-		API:           req.API,
-		Type:          req.Type,
-		IsRecurring:   req.IsRecurring,
-		Description:   req.Description,
-		MaxRetryCount: req.MaxRetryCount,
-		IsActive:      true,
+		Schedule:                req.Schedule,
+		Timezone:                req.Timezone,
+		RunAt:                   runAt,
+		IntervalAnchor:          req.IntervalAnchor,
+		StartAt:                 req.StartAt,
+		EndAt:                   req.EndAt,
+		MaxRuns:                 req.MaxRuns,
+		MisfirePolicy:           req.MisfirePolicy,
+		MisfireThresholdSeconds: req.MisfireThresholdSeconds,
+		API:                     req.API,
+		Request:                 requestSpec,
+		SuccessCriteria:         successCriteria,
+		Type:                    req.Type,
+		Priority:                req.Priority.OrNormal(),
+		Queue:                   queue,
+		Tags:                    tags,
+		ConcurrencyPolicy:       req.ConcurrencyPolicy.OrForbid(),
+		RateLimitKey:            req.RateLimitKey,
+		SigningKey:              req.SigningKey,
+		Credential:              req.Credential,
+		IsRecurring:             req.IsRecurring,
+		Description:             req.Description,
+		MaxRetryCount:           maxRetryCount,
+		RetryPolicy:             retryPolicy,
+		TimeoutSeconds:          req.TimeoutSeconds,
+		IsActive:                true,
+	}
+	if idempotencyKey != "" {
+		job.IdempotencyKey = &idempotencyKey
+		job.RequestHash = requestHash
+	}
+
+	if err := h.validateWindow(job, time.Now()); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails(err.Error()))
+		return
 	}
 
 	// Calculate next execution time for the schedule
-	nextExecutionTime, err := h.scheduleParser.CalculateNextExecutionFromNow(req.Schedule)
+	nextExecutionTime, err := h.nextExecutionTime(job)
 	if err != nil {
 		middleware.HandleError(c, errors.Wrap(err, "SCHEDULE_CALCULATION_ERROR", "Failed to calculate next execution time", http.StatusInternalServerError))
 		return
@@ -89,6 +287,10 @@ func (h *JobHandler) CreateJob(c *gin.Context) {
 
 	// Create job and schedule in a transaction to ensure data consistency
 	if err := h.storage.CreateJobWithSchedule(job, schedule); err != nil {
+		// A concurrent request with the same key won the race
+		if err == storage.ErrDuplicateIdempotencyKey && h.replayCreateJob(c, idempotencyKey, requestHash) {
+			return
+		}
 		middleware.HandleError(c, errors.Wrap(err, "JOB_CREATION_ERROR", "Failed to create job and schedule", http.StatusInternalServerError))
 		return
 	}
@@ -125,7 +327,7 @@ func (h *JobHandler) GetJob(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, job)
+	c.JSON(http.StatusOK, jobResponse(job))
 }
 
 // ListJobs handles GET /jobs
@@ -165,7 +367,10 @@ func (h *JobHandler) ListJobs(c *gin.Context) {
 		end = total
 	}
 
-	paginatedJobs := jobs[start:end]
+	paginatedJobs := make([]*models.Job, 0, end-start)
+	for _, job := range jobs[start:end] {
+		paginatedJobs = append(paginatedJobs, jobResponse(job))
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"jobs":   paginatedJobs,
@@ -242,3 +447,603 @@ func (h *JobHandler) GetJobSchedule(c *gin.Context) {
 
 	c.JSON(http.StatusOK, schedule)
 }
+
+// UpdateJobRequest represents the request payload for updating a job.
+// Omitted fields are left unchanged.
+type UpdateJobRequest struct {
+	Schedule                *string                   `json:"schedule"`
+	RunAt                   *time.Time                `json:"runAt"`
+	Delay                   *string                   `json:"delay"`
+	Timezone                *string                   `json:"timezone"`
+	IntervalAnchor          *models.IntervalAnchor    `json:"intervalAnchor"`
+	StartAt                 *time.Time                `json:"startAt"`
+	EndAt                   *time.Time                `json:"endAt"`
+	MaxRuns                 *int                      `json:"maxRuns"`
+	MisfirePolicy           *models.MisfirePolicy     `json:"misfirePolicy"`
+	MisfireThresholdSeconds *int                      `json:"misfireThresholdSeconds"`
+	API                     *string                   `json:"api"`
+	Request                 *models.HTTPRequestSpec   `json:"request"`
+	SuccessCriteria         *models.SuccessCriteria   `json:"successCriteria"`
+	Type                    *models.JobType           `json:"type"`
+	Priority                *models.JobPriority       `json:"priority"`
+	Queue                   *string                   `json:"queue"`
+	Tags                    *models.JobTags           `json:"tags"`
+	ConcurrencyPolicy       *models.ConcurrencyPolicy `json:"concurrencyPolicy"`
+	RateLimitKey            *string                   `json:"rateLimitKey"`
+	SigningKey              *string                   `json:"signingKey"`
+	Credential              *string                   `json:"credential"` // Empty removes the credential
+	IsRecurring             *bool                     `json:"isRecurring"`
+	Description             *string                   `json:"description"`
+	MaxRetryCount           *int                      `json:"maxRetryCount"`
+	RetryPolicy             *models.RetryPolicy       `json:"retryPolicy"`
+	TimeoutSeconds          *int                      `json:"timeoutSeconds"`
+}
+
+// UpdateJob handles PUT/PATCH /jobs/:id
+func (h *JobHandler) UpdateJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	if req.Type != nil {
+		if *req.Type != models.AT_LEAST_ONCE && *req.Type != models.AT_MOST_ONCE {
+			middleware.HandleError(c, errors.ErrInvalidJobType)
+			return
+		}
+		job.Type = *req.Type
+	}
+
+	if req.Priority != nil {
+		if !req.Priority.IsValid() {
+			middleware.HandleError(c, errors.ErrInvalidPriority)
+			return
+		}
+		job.Priority = *req.Priority
+	}
+
+	if req.ConcurrencyPolicy != nil {
+		if !req.ConcurrencyPolicy.IsValid() {
+			middleware.HandleError(c, errors.ErrInvalidConcurrency)
+			return
+		}
+		job.ConcurrencyPolicy = *req.ConcurrencyPolicy
+	}
+
+	if req.Queue != nil || req.Tags != nil {
+		queue, tags := job.Queue, job.Tags
+		if req.Queue != nil {
+			queue = *req.Queue
+		}
+		if req.Tags != nil {
+			tags = *req.Tags
+		}
+		var err error
+		job.Queue, job.Tags, err = resolveRouting(queue, tags)
+		if err != nil {
+			middleware.HandleError(c, errors.ErrInvalidQueue.WithDetails(err.Error()))
+			return
+		}
+	}
+
+	if req.RateLimitKey != nil {
+		if err := validateRateLimitKey(*req.RateLimitKey); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails(err.Error()))
+			return
+		}
+		job.RateLimitKey = *req.RateLimitKey
+	}
+
+	if req.SigningKey != nil {
+		if err := validateSigningKey(*req.SigningKey); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails(err.Error()))
+			return
+		}
+		job.SigningKey = *req.SigningKey
+	}
+
+	if req.Credential != nil {
+		if !h.checkCredential(c, *req.Credential) {
+			return
+		}
+		job.Credential = *req.Credential
+	}
+
+	if req.API != nil {
+		if *req.API == "" {
+			middleware.HandleError(c, errors.ErrInvalidRequest.WithDetails("api must not be empty"))
+			return
+		}
+		job.API = *req.API
+	}
+
+	if req.Request != nil {
+		if err := req.Request.Validate(); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidRequestSpec.WithDetails(err.Error()))
+			return
+		}
+		job.Request = *req.Request
+		job.Request.Normalize()
+	}
+
+	if req.SuccessCriteria != nil {
+		if err := req.SuccessCriteria.Validate(); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidSuccessCriteria.WithDetails(err.Error()))
+			return
+		}
+		job.SuccessCriteria = *req.SuccessCriteria
+	}
+
+	if req.IsRecurring != nil {
+		job.IsRecurring = *req.IsRecurring
+	}
+	if req.Description != nil {
+		job.Description = *req.Description
+	}
+	if req.MaxRetryCount != nil {
+		if err := validateMaxRetryCount(*req.MaxRetryCount); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidMaxRetryCount.WithDetails(err.Error()))
+			return
+		}
+		job.MaxRetryCount = *req.MaxRetryCount
+	}
+	if req.RetryPolicy != nil {
+		if err := req.RetryPolicy.Validate(); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidRetryPolicy.WithDetails(err.Error()))
+			return
+		}
+		job.RetryPolicy = *req.RetryPolicy
+	}
+	if req.TimeoutSeconds != nil {
+		if err := h.validateTimeout(*req.TimeoutSeconds); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidTimeout.WithDetails(err.Error()))
+			return
+		}
+		job.TimeoutSeconds = *req.TimeoutSeconds
+		if job.TimeoutSeconds == 0 {
+			job.TimeoutSeconds = models.DefaultJobTimeoutSeconds
+		}
+	}
+
+	if req.MisfirePolicy != nil || req.MisfireThresholdSeconds != nil {
+		policy, threshold := job.MisfirePolicy, job.MisfireThresholdSeconds
+		if req.MisfirePolicy != nil {
+			policy = *req.MisfirePolicy
+		}
+		if req.MisfireThresholdSeconds != nil {
+			threshold = *req.MisfireThresholdSeconds
+		}
+		if err := validateMisfirePolicy(policy, threshold); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidMisfirePolicy.WithDetails(err.Error()))
+			return
+		}
+		if policy == "" {
+			policy = models.MisfireFireOnceNow
+		}
+		if threshold == 0 {
+			threshold = models.DefaultMisfireThresholdSeconds
+		}
+		job.MisfirePolicy, job.MisfireThresholdSeconds = policy, threshold
+	}
+
+	// Switching between cron and one-shot timing replaces the other kind
+	var runAt *time.Time
+	if req.Schedule != nil || req.RunAt != nil || req.Delay != nil {
+		var delay string
+		if req.Delay != nil {
+			delay = *req.Delay
+		}
+		var err error
+		if runAt, err = resolveRunAt(req.Schedule != nil, req.RunAt, delay, time.Now()); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails(err.Error()))
+			return
+		}
+	}
+	willBeOneShot := runAt != nil || (req.Schedule == nil && job.IsOneShot())
+	if willBeOneShot && job.IsRecurring {
+		middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails("one-shot jobs cannot be recurring"))
+		return
+	}
+
+	scheduleChanged := req.Schedule != nil && (*req.Schedule != job.Schedule || job.IsOneShot())
+	timezoneChanged := req.Timezone != nil && *req.Timezone != job.Timezone
+	windowChanged := req.IntervalAnchor != nil || req.StartAt != nil || req.EndAt != nil
+
+	if scheduleChanged {
+		if err := h.scheduleParser.ValidateSchedule(*req.Schedule); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails(err.Error()))
+			return
+		}
+		job.Schedule = *req.Schedule
+		job.RunAt = nil
+	}
+
+	if runAt != nil {
+		job.Schedule = ""
+		job.RunAt = runAt
+	}
+
+	if timezoneChanged {
+		if err := h.scheduleParser.ValidateTimezone(*req.Timezone); err != nil {
+			middleware.HandleError(c, errors.ErrInvalidTimezone.WithDetails(err.Error()))
+			return
+		}
+		job.Timezone = *req.Timezone
+		if job.Timezone == "" {
+			job.Timezone = "UTC"
+		}
+	}
+
+	if req.IntervalAnchor != nil {
+		job.IntervalAnchor = *req.IntervalAnchor
+	}
+	if req.StartAt != nil {
+		job.StartAt = req.StartAt
+	}
+	if req.EndAt != nil {
+		job.EndAt = req.EndAt
+	}
+	if req.MaxRuns != nil {
+		job.MaxRuns = *req.MaxRuns
+	}
+	if err := h.validateWindow(job, time.Now()); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails(err.Error()))
+		return
+	}
+
+	// Without a timing change the existing next execution time stays valid
+	if !scheduleChanged && !timezoneChanged && !windowChanged && runAt == nil {
+		if err := h.storage.UpdateJob(job); err != nil {
+			middleware.HandleError(c, errors.Wrap(err, "JOB_UPDATE_ERROR", "Failed to update job", http.StatusInternalServerError))
+			return
+		}
+		c.JSON(http.StatusOK, jobResponse(job))
+		return
+	}
+
+	if !h.saveJobWithNextExecution(c, job) {
+		return
+	}
+
+	c.JSON(http.StatusOK, jobResponse(job))
+}
+
+// DeleteJob handles DELETE /jobs/:id
+func (h *JobHandler) DeleteJob(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeleteJob(id); err != nil {
+		if err == storage.ErrJobNotFound {
+			middleware.HandleError(c, errors.ErrJobNotFound)
+			return
+		}
+		middleware.HandleError(c, errors.Wrap(err, "JOB_DELETION_ERROR", "Failed to delete job", http.StatusInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"message": "Job deleted successfully",
+	})
+}
+
+// PauseJob handles POST /jobs/:id/pause
+func (h *JobHandler) PauseJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	if job.IsActive {
+		job.IsActive = false
+		if err := h.storage.UpdateJob(job); err != nil {
+			middleware.HandleError(c, errors.Wrap(err, "JOB_UPDATE_ERROR", "Failed to pause job", http.StatusInternalServerError))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, jobResponse(job))
+}
+
+// ResumeJob handles POST /jobs/:id/resume
+func (h *JobHandler) ResumeJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+
+	if job.IsActive {
+		c.JSON(http.StatusOK, jobResponse(job))
+		return
+	}
+
+	// Occurrences missed while paused are not replayed; the job resumes from now
+	if err := h.validateWindow(job, time.Now()); err != nil {
+		middleware.HandleError(c, errors.ErrInvalidSchedule.WithDetails(err.Error()))
+		return
+	}
+	job.IsActive = true
+	if !h.saveJobWithNextExecution(c, job) {
+		return
+	}
+
+	c.JSON(http.StatusOK, jobResponse(job))
+}
+
+// validateTimeout checks a requested timeout against the server maximum; 0 selects the default
+func (h *JobHandler) validateTimeout(timeoutSeconds int) error {
+	if timeoutSeconds < 0 {
+		return fmt.Errorf("timeoutSeconds must not be negative")
+	}
+	if time.Duration(timeoutSeconds)*time.Second > h.maxJobTimeout {
+		return fmt.Errorf("timeoutSeconds must not exceed %d", int(h.maxJobTimeout/time.Second))
+	}
+	return nil
+}
+
+// validateMaxRetryCount checks a requested maxRetryCount; 0 disables retries
+func validateMaxRetryCount(maxRetryCount int) error {
+	if maxRetryCount < 0 {
+		return fmt.Errorf("maxRetryCount must not be negative")
+	}
+	return nil
+}
+
+// validateMisfirePolicy checks a misfire policy and threshold; empty and 0 select the defaults
+func validateMisfirePolicy(policy models.MisfirePolicy, thresholdSeconds int) error {
+	switch policy {
+	case "", models.MisfireFireOnceNow, models.MisfireSkipToNext, models.MisfireRunAllMissed:
+	default:
+		return fmt.Errorf("misfirePolicy must be %s, %s or %s", models.MisfireFireOnceNow, models.MisfireSkipToNext, models.MisfireRunAllMissed)
+	}
+	if thresholdSeconds < 0 {
+		return fmt.Errorf("misfireThresholdSeconds must not be negative")
+	}
+	return nil
+}
+
+// resolveRouting validates the queue and tags of a job and returns them with the queue defaulted and
+// the tags normalized. A job is routed either by queue or by tags, not both.
+func resolveRouting(queue string, tags models.JobTags) (string, models.JobTags, error) {
+	if queue == "" {
+		queue = models.DefaultQueue
+	}
+	if err := models.ValidateQueueName(queue); err != nil {
+		return "", nil, err
+	}
+	if err := tags.Validate(); err != nil {
+		return "", nil, err
+	}
+	tags = tags.Normalize()
+	if len(tags) > 0 && queue != models.DefaultQueue {
+		return "", nil, fmt.Errorf("a job is routed by queue or by tags, not both")
+	}
+	return queue, tags, nil
+}
+
+// validateRateLimitKey checks that a rate limit key fits its column
+func validateRateLimitKey(key string) error {
+	if len(key) > 128 {
+		return fmt.Errorf("rateLimitKey must be at most 128 characters")
+	}
+	return nil
+}
+
+// checkCredential checks that the credential a job references exists, or answers the request and
+// returns false. An empty name references no credential.
+func (h *JobHandler) checkCredential(c *gin.Context, name string) bool {
+	if name == "" {
+		return true
+	}
+	if _, err := h.storage.GetCredentialByName(name); err != nil {
+		if err == storage.ErrCredentialNotFound {
+			middleware.HandleError(c, errors.ErrInvalidCredential.WithDetails(fmt.Sprintf("credential %q does not exist", name)))
+			return false
+		}
+		middleware.HandleError(c, errors.ErrDatabaseError.WithDetails(err.Error()))
+		return false
+	}
+	return true
+}
+
+// validateSigningKey checks that a signing key name fits its column. Whether a worker has the key is
+// only known when the job runs.
+func validateSigningKey(key string) error {
+	if len(key) > 64 {
+		return fmt.Errorf("signingKey must be at most 64 characters")
+	}
+	return nil
+}
+
+// replayCreateJob answers a create request whose idempotency key already created a job, and reports
+// whether it did. The same request gets the original response back; a different one is rejected.
+func (h *JobHandler) replayCreateJob(c *gin.Context, idempotencyKey, requestHash string) bool {
+	job, err := h.storage.GetJobByIdempotencyKey(idempotencyKey)
+	if err == storage.ErrJobNotFound {
+		return false
+	}
+	if err != nil {
+		middleware.HandleError(c, errors.Wrap(err, "JOB_CREATION_ERROR", "Failed to look up idempotency key", http.StatusInternalServerError))
+		return true
+	}
+
+	if job.RequestHash != requestHash {
+		middleware.HandleError(c, errors.ErrIdempotencyKeyReused)
+		return true
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(http.StatusCreated, CreateJobResponse{
+		ID:      job.ID,
+		Message: "Job created successfully",
+	})
+	return true
+}
+
+// hashCreateJobRequest fingerprints a create request, so a reused idempotency key can be told from a replay
+func hashCreateJobRequest(req *CreateJobRequest) string {
+	body, _ := json.Marshal(req)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// resolveRunAt checks that exactly one of a cron schedule, runAt or delay was given and returns
+// the run time of a one-shot job, or nil when the job follows its cron schedule
+func resolveRunAt(hasSchedule bool, runAt *time.Time, delay string, now time.Time) (*time.Time, error) {
+	given := 0
+	for _, set := range []bool{hasSchedule, runAt != nil, delay != ""} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, fmt.Errorf("exactly one of schedule, runAt or delay is required")
+	}
+
+	switch {
+	case runAt != nil:
+		if !runAt.After(now) {
+			return nil, fmt.Errorf("runAt must be in the future")
+		}
+		at := runAt.UTC()
+		return &at, nil
+	case delay != "":
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delay: %w", err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("delay must be positive")
+		}
+		at := now.Add(d).UTC()
+		return &at, nil
+	default:
+		return nil, nil
+	}
+}
+
+// nextExecutionTime computes when the job should next run, counting from now
+func (h *JobHandler) nextExecutionTime(job *models.Job) (time.Time, error) {
+	if job.IsOneShot() {
+		// A one-shot job that fell due while paused runs as soon as it is resumed
+		if now := time.Now().UTC(); job.RunAt.Before(now) {
+			return now, nil
+		}
+		return *job.RunAt, nil
+	}
+	return h.scheduleParser.CalculateNextExecutionInWindow(job.Schedule, job.Timezone, time.Now(), job.StartAt)
+}
+
+// validateWindow checks the interval anchor, start/end window and run limit, and that the job
+// still has a run left. An interval job without an anchor gets the default one.
+func (h *JobHandler) validateWindow(job *models.Job, now time.Time) error {
+	_, isInterval := h.scheduleParser.ParseInterval(job.Schedule)
+	switch job.IntervalAnchor {
+	case "":
+		if isInterval {
+			job.IntervalAnchor = models.IntervalFromScheduled
+		}
+	case models.IntervalFromScheduled, models.IntervalFromCompletion:
+		if !isInterval {
+			return fmt.Errorf("intervalAnchor only applies to @every schedules")
+		}
+	default:
+		return fmt.Errorf("intervalAnchor must be %s or %s", models.IntervalFromScheduled, models.IntervalFromCompletion)
+	}
+
+	if job.MaxRuns < 0 {
+		return fmt.Errorf("maxRuns must not be negative")
+	}
+	if job.MaxRuns > 0 && job.RunCount >= job.MaxRuns {
+		return fmt.Errorf("job has already completed its %d runs", job.MaxRuns)
+	}
+
+	if job.IsOneShot() {
+		if job.StartAt != nil || job.EndAt != nil || job.MaxRuns != 0 {
+			return fmt.Errorf("startAt, endAt and maxRuns do not apply to one-shot jobs")
+		}
+		return nil
+	}
+
+	if job.EndAt == nil {
+		return nil
+	}
+	if job.StartAt != nil && !job.EndAt.After(*job.StartAt) {
+		return fmt.Errorf("endAt must be after startAt")
+	}
+
+	firstRun, err := h.scheduleParser.CalculateNextExecutionInWindow(job.Schedule, job.Timezone, now, job.StartAt)
+	if err != nil {
+		return err
+	}
+	if firstRun.After(*job.EndAt) {
+		return fmt.Errorf("schedule has no runs before endAt")
+	}
+	return nil
+}
+
+// saveJobWithNextExecution recomputes the next execution time from now and saves it with the job
+func (h *JobHandler) saveJobWithNextExecution(c *gin.Context, job *models.Job) bool {
+	nextExecutionTime, err := h.nextExecutionTime(job)
+	if err != nil {
+		middleware.HandleError(c, errors.Wrap(err, "SCHEDULE_CALCULATION_ERROR", "Failed to calculate next execution time", http.StatusInternalServerError))
+		return false
+	}
+
+	schedule := &models.JobSchedule{
+		NextExecutionTime: nextExecutionTime,
+	}
+
+	if err := h.storage.UpdateJobWithSchedule(job, schedule); err != nil {
+		middleware.HandleError(c, errors.Wrap(err, "JOB_UPDATE_ERROR", "Failed to update job and schedule", http.StatusInternalServerError))
+		return false
+	}
+
+	return true
+}
+
+// loadJob parses the job ID path parameter and fetches the job, writing an error response on failure
+func (h *JobHandler) loadJob(c *gin.Context) (*models.Job, bool) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := h.storage.GetJob(id)
+	if err != nil {
+		if err == storage.ErrJobNotFound {
+			middleware.HandleError(c, errors.ErrJobNotFound)
+			return nil, false
+		}
+		middleware.HandleError(c, errors.Wrap(err, "DATABASE_ERROR", "Failed to get job", http.StatusInternalServerError))
+		return nil, false
+	}
+
+	return job, true
+}
+
+// jobResponse returns the job as it is sent to clients, without the values of credential headers
+func jobResponse(job *models.Job) *models.Job {
+	response := *job
+	response.Request = job.Request.Redacted()
+	return &response
+}
+
+// parseJobID parses the job ID path parameter, writing an error response on failure
+func parseJobID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.HandleError(c, errors.NewAppError("INVALID_JOB_ID", "Invalid job ID", http.StatusBadRequest))
+		return 0, false
+	}
+	return uint(id), true
+}
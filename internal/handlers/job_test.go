@@ -10,8 +10,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockStorage is a mock implementation of the Storage interface
@@ -45,6 +47,21 @@ func (m *MockStorage) GetAllJobs() ([]*models.Job, error) {
 	return args.Get(0).([]*models.Job), args.Error(1)
 }
 
+func (m *MockStorage) UpdateJob(job *models.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockStorage) UpdateJobWithSchedule(job *models.Job, schedule *models.JobSchedule) error {
+	args := m.Called(job, schedule)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeleteJob(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
 func (m *MockStorage) CreateJobSchedule(schedule *models.JobSchedule) error {
 	args := m.Called(schedule)
 	return args.Error(0)
@@ -86,14 +103,29 @@ func (m *MockStorage) DeleteJobSchedule(jobID uint) error {
 	return args.Error(0)
 }
 
-func (m *MockStorage) GetJobsReadyForExecution(limit int) ([]*models.Job, []*models.JobSchedule, error) {
-	args := m.Called(limit)
+func (m *MockStorage) ClaimJobsReadyForExecution(owner string, term int64, lease, dispatchExpiry time.Duration, limit int, shards *storage.ShardFilter) ([]*models.Job, []*models.JobSchedule, error) {
+	args := m.Called(owner, term, lease, dispatchExpiry, limit, shards)
 	if args.Get(0) == nil {
 		return nil, nil, args.Error(2)
 	}
 	return args.Get(0).([]*models.Job), args.Get(1).([]*models.JobSchedule), args.Error(2)
 }
 
+func (m *MockStorage) MarkScheduleDispatched(jobID uint, owner string, term int64) error {
+	args := m.Called(jobID, owner, term)
+	return args.Error(0)
+}
+
+func (m *MockStorage) MaxScheduleLeaderTerm() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStorage) ReleaseScheduleClaim(jobID uint, owner string) error {
+	args := m.Called(jobID, owner)
+	return args.Error(0)
+}
+
 func (m *MockStorage) GetJobExecutionInProgress(jobID uint) (*models.JobExecution, error) {
 	args := m.Called(jobID)
 	if args.Get(0) == nil {
@@ -102,11 +134,83 @@ func (m *MockStorage) GetJobExecutionInProgress(jobID uint) (*models.JobExecutio
 	return args.Get(0).(*models.JobExecution), args.Error(1)
 }
 
+func (m *MockStorage) GetJobByIdempotencyKey(key string) (*models.Job, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockStorage) GetJobExecutionsInProgress(jobID uint) ([]*models.JobExecution, error) {
+	args := m.Called(jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.JobExecution), args.Error(1)
+}
+
+func (m *MockStorage) ExpireJobExecutions(jobID uint, startedBefore time.Time) (int64, error) {
+	args := m.Called(jobID, startedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStorage) CancelJobExecution(id uint, reason string) (bool, error) {
+	args := m.Called(id, reason)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) CreateCredential(credential *models.Credential) error {
+	args := m.Called(credential)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetCredential(id uint) (*models.Credential, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Credential), args.Error(1)
+}
+
+func (m *MockStorage) GetCredentialByName(name string) (*models.Credential, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Credential), args.Error(1)
+}
+
+func (m *MockStorage) ListCredentials() ([]*models.Credential, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.Credential), args.Error(1)
+}
+
+func (m *MockStorage) UpdateCredential(credential *models.Credential) error {
+	args := m.Called(credential)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeleteCredential(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStorage) CountJobsUsingCredential(name string) (int64, error) {
+	args := m.Called(name)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// intPtr returns a pointer to v, for the optional fields of requests
+func intPtr(v int) *int {
+	return &v
+}
+
 func TestJobHandler_CreateJob_Success(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	mockStorage := new(MockStorage)
-	handler := NewJobHandler(mockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
 
 	// Mock expectations
 	mockStorage.On("CreateJobWithSchedule", mock.AnythingOfType("*models.Job"), mock.AnythingOfType("*models.JobSchedule")).Return(nil)
@@ -118,7 +222,7 @@ func TestJobHandler_CreateJob_Success(t *testing.T) {
 		Schedule:      "0 */5 * * * *", // Every 5 minutes
 		IsRecurring:   true,
 		Description:   "Test job description",
-		MaxRetryCount: 3,
+		MaxRetryCount: intPtr(3),
 	}
 
 	jsonBody, _ := json.Marshal(reqBody)
@@ -144,11 +248,59 @@ func TestJobHandler_CreateJob_Success(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 }
 
+func TestJobHandler_CreateJob_DefaultMaxRetryCount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name              string
+		configured        int
+		requested         *int
+		wantMaxRetryCount int
+	}{
+		{name: "configured default", configured: 5, wantMaxRetryCount: 5},
+		{name: "configured no retries", configured: 0, wantMaxRetryCount: 0},
+		{name: "unconfigured default", configured: -1, wantMaxRetryCount: 3},
+		{name: "requested", configured: 5, requested: intPtr(1), wantMaxRetryCount: 1},
+		{name: "requested no retries", configured: 5, requested: intPtr(0), wantMaxRetryCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			handler := NewJobHandler(mockStorage, time.Hour, tt.configured)
+
+			var created *models.Job
+			mockStorage.On("CreateJobWithSchedule", mock.AnythingOfType("*models.Job"), mock.AnythingOfType("*models.JobSchedule")).Run(func(args mock.Arguments) {
+				created = args.Get(0).(*models.Job)
+			}).Return(nil)
+
+			jsonBody, _ := json.Marshal(CreateJobRequest{
+				API:           "http://example.com/webhook",
+				Type:          models.AT_LEAST_ONCE,
+				Schedule:      "0 */5 * * * *",
+				MaxRetryCount: tt.requested,
+			})
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			require.NotNil(t, created)
+			assert.Equal(t, tt.wantMaxRetryCount, created.MaxRetryCount)
+		})
+	}
+}
+
 func TestJobHandler_CreateJob_InvalidJobType(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	mockStorage := new(MockStorage)
-	handler := NewJobHandler(mockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
 
 	// Test data with invalid job type
 	reqBody := CreateJobRequest{
@@ -157,7 +309,7 @@ func TestJobHandler_CreateJob_InvalidJobType(t *testing.T) {
 		Schedule:      "0 */5 * * * *",
 		IsRecurring:   true,
 		Description:   "Test job description",
-		MaxRetryCount: 3,
+		MaxRetryCount: intPtr(3),
 	}
 
 	jsonBody, _ := json.Marshal(reqBody)
@@ -185,7 +337,7 @@ func TestJobHandler_CreateJob_InvalidSchedule(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	mockStorage := new(MockStorage)
-	handler := NewJobHandler(mockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
 
 	// Test data with invalid schedule
 	reqBody := CreateJobRequest{
@@ -194,7 +346,7 @@ func TestJobHandler_CreateJob_InvalidSchedule(t *testing.T) {
 		Schedule:      "invalid cron expression",
 		IsRecurring:   true,
 		Description:   "Test job description",
-		MaxRetryCount: 3,
+		MaxRetryCount: intPtr(3),
 	}
 
 	jsonBody, _ := json.Marshal(reqBody)
@@ -221,7 +373,7 @@ func TestJobHandler_GetJob_Success(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	mockStorage := new(MockStorage)
-	handler := NewJobHandler(mockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
 
 	// Mock data
 	expectedJob := &models.Job{
@@ -257,11 +409,53 @@ func TestJobHandler_GetJob_Success(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 }
 
+func TestJobHandler_GetJob_RedactsCredentialHeaders(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	// A job stored before credential headers were rejected
+	storedJob := &models.Job{
+		ID:  1,
+		API: "http://example.com/webhook",
+		Request: models.HTTPRequestSpec{Headers: map[string]string{
+			"Authorization": "Bearer secret",
+			"X-Tenant":      "acme",
+		}},
+	}
+	mockStorage.On("GetJob", uint(1)).Return(storedJob, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/jobs/1", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.GetJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret")
+
+	var response models.Job
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RedactedHeaderValue, response.Request.Headers["Authorization"])
+	assert.Equal(t, "acme", response.Request.Headers["X-Tenant"])
+
+	// The stored job is left untouched
+	assert.Equal(t, "Bearer secret", storedJob.Request.Headers["Authorization"])
+
+	mockStorage.AssertExpectations(t)
+}
+
 func TestJobHandler_GetJob_NotFound(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	mockStorage := new(MockStorage)
-	handler := NewJobHandler(mockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
 
 	// Mock storage to return not found error
 	mockStorage.On("GetJob", uint(999)).Return(nil, assert.AnError)
@@ -280,3 +474,1195 @@ func TestJobHandler_GetJob_NotFound(t *testing.T) {
 
 	mockStorage.AssertExpectations(t)
 }
+
+func TestJobHandler_UpdateJob_ScheduleChangeRecomputesNextExecution(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	existingJob := &models.Job{
+		ID:            1,
+		Schedule:      "0 */5 * * * *",
+		API:           "http://example.com/webhook",
+		Type:          models.AT_LEAST_ONCE,
+		IsRecurring:   true,
+		MaxRetryCount: 3,
+		IsActive:      true,
+	}
+
+	mockStorage.On("GetJob", uint(1)).Return(existingJob, nil)
+	mockStorage.On("UpdateJobWithSchedule", existingJob, mock.MatchedBy(func(schedule *models.JobSchedule) bool {
+		return schedule.NextExecutionTime.After(time.Now())
+	})).Return(nil)
+
+	jsonBody := []byte(`{"schedule": "0 0 * * * *", "description": "Hourly"}`)
+	req, _ := http.NewRequest("PATCH", "/api/v1/jobs/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.UpdateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.Job
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "0 0 * * * *", response.Schedule)
+	assert.Equal(t, "Hourly", response.Description)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_UpdateJob_WithoutScheduleChange(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	existingJob := &models.Job{
+		ID:            1,
+		Schedule:      "0 */5 * * * *",
+		API:           "http://example.com/webhook",
+		Type:          models.AT_LEAST_ONCE,
+		MaxRetryCount: 3,
+		IsActive:      true,
+	}
+
+	mockStorage.On("GetJob", uint(1)).Return(existingJob, nil)
+	mockStorage.On("UpdateJob", existingJob).Return(nil)
+
+	jsonBody := []byte(`{"api": "http://example.com/other", "maxRetryCount": 5}`)
+	req, _ := http.NewRequest("PUT", "/api/v1/jobs/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.UpdateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "http://example.com/other", existingJob.API)
+	assert.Equal(t, 5, existingJob.MaxRetryCount)
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "UpdateJobWithSchedule", mock.Anything, mock.Anything)
+}
+
+func TestJobHandler_UpdateJob_InvalidSchedule(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	mockStorage.On("GetJob", uint(1)).Return(&models.Job{ID: 1, Schedule: "0 */5 * * * *", IsActive: true}, nil)
+
+	jsonBody := []byte(`{"schedule": "invalid cron expression"}`)
+	req, _ := http.NewRequest("PATCH", "/api/v1/jobs/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.UpdateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "INVALID_SCHEDULE", response["code"])
+}
+
+func TestJobHandler_UpdateJob_NegativeMaxRetryCount(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	existingJob := &models.Job{ID: 1, Schedule: "0 */5 * * * *", MaxRetryCount: 3, IsActive: true}
+	mockStorage.On("GetJob", uint(1)).Return(existingJob, nil)
+
+	jsonBody := []byte(`{"maxRetryCount": -1}`)
+	req, _ := http.NewRequest("PATCH", "/api/v1/jobs/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.UpdateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "INVALID_MAX_RETRY_COUNT", response["code"])
+	assert.Equal(t, 3, existingJob.MaxRetryCount)
+
+	mockStorage.AssertNotCalled(t, "UpdateJob", mock.Anything)
+	mockStorage.AssertNotCalled(t, "UpdateJobWithSchedule", mock.Anything, mock.Anything)
+}
+
+func TestJobHandler_DeleteJob_Success(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	mockStorage.On("DeleteJob", uint(1)).Return(nil)
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/jobs/1", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.DeleteJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_DeleteJob_NotFound(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	mockStorage.On("DeleteJob", uint(999)).Return(storage.ErrJobNotFound)
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/jobs/999", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "999"}}
+
+	// Execute
+	handler.DeleteJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_PauseJob(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	existingJob := &models.Job{ID: 1, Schedule: "0 */5 * * * *", IsActive: true}
+
+	mockStorage.On("GetJob", uint(1)).Return(existingJob, nil)
+	mockStorage.On("UpdateJob", existingJob).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/api/v1/jobs/1/pause", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.PauseJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, existingJob.IsActive)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_ResumeJob(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	existingJob := &models.Job{ID: 1, Schedule: "0 */5 * * * *", IsActive: false}
+
+	mockStorage.On("GetJob", uint(1)).Return(existingJob, nil)
+	mockStorage.On("UpdateJobWithSchedule", existingJob, mock.AnythingOfType("*models.JobSchedule")).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/api/v1/jobs/1/resume", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.ResumeJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, existingJob.IsActive)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_WithRequestSpec(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+		return job.Request.Method == http.MethodPut &&
+			job.Request.ContentType == "application/json" &&
+			job.Request.Headers["X-Tenant"] == "acme"
+	}), mock.AnythingOfType("*models.JobSchedule")).Return(nil)
+
+	reqBody := CreateJobRequest{
+		API:      "http://example.com/webhook",
+		Type:     models.AT_LEAST_ONCE,
+		Schedule: "0 */5 * * * *",
+		Request: &models.HTTPRequestSpec{
+			Method:  "put",
+			Headers: map[string]string{"X-Tenant": "acme"},
+			Body:    `{"jobId": {{.JobID}}}`,
+		},
+	}
+
+	jsonBody, _ := json.Marshal(reqBody)
+	req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	// Execute
+	handler.CreateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_InvalidRequestSpec(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	tests := []struct {
+		name    string
+		request *models.HTTPRequestSpec
+	}{
+		{name: "unsupported method", request: &models.HTTPRequestSpec{Method: "TRACE"}},
+		{name: "body on GET", request: &models.HTTPRequestSpec{Method: "GET", Body: "{}"}},
+		{name: "invalid header name", request: &models.HTTPRequestSpec{Headers: map[string]string{"Bad Header": "x"}}},
+		{name: "authorization header", request: &models.HTTPRequestSpec{Headers: map[string]string{"Authorization": "Bearer secret"}}},
+		{name: "api key header", request: &models.HTTPRequestSpec{Headers: map[string]string{"x-api-key": "secret"}}},
+		{name: "malformed template", request: &models.HTTPRequestSpec{Body: "{{.JobID"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody := CreateJobRequest{
+				API:      "http://example.com/webhook",
+				Type:     models.AT_LEAST_ONCE,
+				Schedule: "0 */5 * * * *",
+				Request:  tt.request,
+			}
+
+			jsonBody, _ := json.Marshal(reqBody)
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "INVALID_REQUEST_SPEC", response["code"])
+		})
+	}
+
+	mockStorage.AssertNotCalled(t, "CreateJobWithSchedule", mock.Anything, mock.Anything)
+}
+
+func TestJobHandler_CreateJob_InvalidSuccessCriteria(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	tests := []struct {
+		name     string
+		criteria *models.SuccessCriteria
+	}{
+		{name: "invalid status code", criteria: &models.SuccessCriteria{StatusCodes: []int{700}}},
+		{name: "overlapping retry sets", criteria: &models.SuccessCriteria{RetryableStatusCodes: []int{503}, NonRetryableStatusCodes: []int{503}}},
+		{name: "invalid regex", criteria: &models.SuccessCriteria{BodyRegex: "("}},
+		{name: "invalid JSON path", criteria: &models.SuccessCriteria{BodyJSONPath: "status"}},
+		{name: "value without path", criteria: &models.SuccessCriteria{BodyJSONValue: "ok"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody := CreateJobRequest{
+				API:             "http://example.com/webhook",
+				Type:            models.AT_LEAST_ONCE,
+				Schedule:        "0 */5 * * * *",
+				SuccessCriteria: tt.criteria,
+			}
+
+			jsonBody, _ := json.Marshal(reqBody)
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "INVALID_SUCCESS_CRITERIA", response["code"])
+		})
+	}
+
+	mockStorage.AssertNotCalled(t, "CreateJobWithSchedule", mock.Anything, mock.Anything)
+}
+
+func TestJobHandler_CreateJob_Priority(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	tests := []struct {
+		name     string
+		priority models.JobPriority
+		expected models.JobPriority
+		status   int
+	}{
+		{name: "defaults to normal", priority: "", expected: models.PriorityNormal, status: http.StatusCreated},
+		{name: "high", priority: models.PriorityHigh, expected: models.PriorityHigh, status: http.StatusCreated},
+		{name: "unknown", priority: "urgent", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.status == http.StatusCreated {
+				mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+					return job.Priority == tt.expected
+				}), mock.AnythingOfType("*models.JobSchedule")).Return(nil).Once()
+			}
+
+			reqBody := CreateJobRequest{
+				API:      "http://example.com/webhook",
+				Type:     models.AT_LEAST_ONCE,
+				Schedule: "0 */5 * * * *",
+				Priority: tt.priority,
+			}
+
+			jsonBody, _ := json.Marshal(reqBody)
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_ConcurrencyPolicy(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	tests := []struct {
+		name     string
+		policy   models.ConcurrencyPolicy
+		expected models.ConcurrencyPolicy
+		status   int
+	}{
+		{name: "defaults to forbid", policy: "", expected: models.ConcurrencyForbid, status: http.StatusCreated},
+		{name: "replace", policy: models.ConcurrencyReplace, expected: models.ConcurrencyReplace, status: http.StatusCreated},
+		{name: "unknown", policy: "Skip", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.status == http.StatusCreated {
+				mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+					return job.ConcurrencyPolicy == tt.expected
+				}), mock.AnythingOfType("*models.JobSchedule")).Return(nil).Once()
+			}
+
+			reqBody := CreateJobRequest{
+				API:               "http://example.com/webhook",
+				Type:              models.AT_LEAST_ONCE,
+				Schedule:          "0 */5 * * * *",
+				ConcurrencyPolicy: tt.policy,
+			}
+
+			jsonBody, _ := json.Marshal(reqBody)
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_IdempotencyKey(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	reqBody := CreateJobRequest{
+		API:      "http://example.com/webhook",
+		Type:     models.AT_LEAST_ONCE,
+		Schedule: "0 */5 * * * *",
+	}
+	createJob := func(body CreateJobRequest) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "create-report-job")
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		handler.CreateJob(c)
+		return w
+	}
+
+	// The first request creates the job with its key
+	var created *models.Job
+	mockStorage.On("GetJobByIdempotencyKey", "create-report-job").Return(nil, storage.ErrJobNotFound).Once()
+	mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+		return job.IdempotencyKey != nil && *job.IdempotencyKey == "create-report-job" && job.RequestHash != ""
+	}), mock.AnythingOfType("*models.JobSchedule")).Run(func(args mock.Arguments) {
+		created = args.Get(0).(*models.Job)
+		created.ID = 12
+	}).Return(nil).Once()
+
+	w := createJob(reqBody)
+	require.Equal(t, http.StatusCreated, w.Code)
+	original := w.Body.String()
+
+	// A retry gets the original response without creating another job
+	mockStorage.On("GetJobByIdempotencyKey", "create-report-job").Return(created, nil).Twice()
+	w = createJob(reqBody)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, original, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+
+	// The key cannot be reused for a different job
+	reqBody.Schedule = "0 0 * * * *"
+	w = createJob(reqBody)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_Credential(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		credential string
+		wantStatus int
+	}{
+		{name: "existing credential", credential: "partner-token", wantStatus: http.StatusCreated},
+		{name: "missing credential", credential: "missing", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+			mockStorage.On("GetCredentialByName", "partner-token").Return(&models.Credential{Name: "partner-token"}, nil).Maybe()
+			mockStorage.On("GetCredentialByName", "missing").Return(nil, storage.ErrCredentialNotFound).Maybe()
+			var created *models.Job
+			mockStorage.On("CreateJobWithSchedule", mock.AnythingOfType("*models.Job"), mock.AnythingOfType("*models.JobSchedule")).Run(func(args mock.Arguments) {
+				created = args.Get(0).(*models.Job)
+			}).Return(nil).Maybe()
+
+			jsonBody, _ := json.Marshal(CreateJobRequest{
+				API:        "http://example.com/webhook",
+				Type:       models.AT_LEAST_ONCE,
+				Schedule:   "0 */5 * * * *",
+				Credential: tt.credential,
+			})
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusCreated {
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "INVALID_CREDENTIAL", response["code"])
+				mockStorage.AssertNotCalled(t, "CreateJobWithSchedule", mock.Anything, mock.Anything)
+				return
+			}
+			require.NotNil(t, created)
+			assert.Equal(t, tt.credential, created.Credential)
+		})
+	}
+}
+
+func TestJobHandler_UpdateJob_Credential(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		wantStatus     int
+		wantCredential string
+	}{
+		{name: "existing credential", body: `{"credential": "partner-token"}`, wantStatus: http.StatusOK, wantCredential: "partner-token"},
+		{name: "missing credential", body: `{"credential": "missing"}`, wantStatus: http.StatusBadRequest, wantCredential: "old-token"},
+		{name: "empty credential removes it", body: `{"credential": ""}`, wantStatus: http.StatusOK, wantCredential: ""},
+		{name: "omitted credential keeps it", body: `{"description": "Renamed"}`, wantStatus: http.StatusOK, wantCredential: "old-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+			existingJob := &models.Job{
+				ID:          1,
+				Schedule:    "0 */5 * * * *",
+				API:         "http://example.com/webhook",
+				Type:        models.AT_LEAST_ONCE,
+				IsRecurring: true,
+				IsActive:    true,
+				Credential:  "old-token",
+			}
+			mockStorage.On("GetJob", uint(1)).Return(existingJob, nil)
+			mockStorage.On("GetCredentialByName", "partner-token").Return(&models.Credential{Name: "partner-token"}, nil).Maybe()
+			mockStorage.On("GetCredentialByName", "missing").Return(nil, storage.ErrCredentialNotFound).Maybe()
+			mockStorage.On("UpdateJob", existingJob).Return(nil).Maybe()
+
+			req, _ := http.NewRequest("PATCH", "/api/v1/jobs/1", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+			handler.UpdateJob(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantCredential, existingJob.Credential)
+			if tt.wantStatus != http.StatusOK {
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "INVALID_CREDENTIAL", response["code"])
+				mockStorage.AssertNotCalled(t, "UpdateJob", mock.Anything)
+				return
+			}
+			mockStorage.AssertCalled(t, "UpdateJob", existingJob)
+			if tt.wantCredential == "" {
+				mockStorage.AssertNotCalled(t, "GetCredentialByName", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestJobHandler_CreateJob_InvalidRetryPolicy(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	tests := []struct {
+		name   string
+		policy *models.RetryPolicy
+	}{
+		{name: "unknown strategy", policy: &models.RetryPolicy{Strategy: "random"}},
+		{name: "unknown jitter", policy: &models.RetryPolicy{Jitter: "half"}},
+		{name: "negative base delay", policy: &models.RetryPolicy{BaseDelaySeconds: -1}},
+		{name: "max below base", policy: &models.RetryPolicy{BaseDelaySeconds: 60, MaxDelaySeconds: 30}},
+		{name: "shrinking multiplier", policy: &models.RetryPolicy{Strategy: models.RetryExponential, Multiplier: 0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody := CreateJobRequest{
+				API:         "http://example.com/webhook",
+				Type:        models.AT_LEAST_ONCE,
+				Schedule:    "0 */5 * * * *",
+				RetryPolicy: tt.policy,
+			}
+
+			jsonBody, _ := json.Marshal(reqBody)
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "INVALID_RETRY_POLICY", response["code"])
+		})
+	}
+
+	mockStorage.AssertNotCalled(t, "CreateJobWithSchedule", mock.Anything, mock.Anything)
+}
+
+func TestJobHandler_CreateJob_Routing(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	tests := []struct {
+		name          string
+		queue         string
+		tags          models.JobTags
+		expectedQueue string
+		expectedTags  models.JobTags
+		code          string
+	}{
+		{name: "defaults to the default queue", expectedQueue: models.DefaultQueue},
+		{name: "named queue", queue: "reports", expectedQueue: "reports"},
+		{name: "tags are normalized", tags: models.JobTags{"linux", "gpu", "linux"}, expectedQueue: models.DefaultQueue, expectedTags: models.JobTags{"gpu", "linux"}},
+		{name: "invalid queue name", queue: "Reports!", code: "INVALID_QUEUE"},
+		{name: "reserved queue name", queue: "high", code: "INVALID_QUEUE"},
+		{name: "invalid tag", tags: models.JobTags{"GPU"}, code: "INVALID_QUEUE"},
+		{name: "queue and tags", queue: "reports", tags: models.JobTags{"gpu"}, code: "INVALID_QUEUE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.code == "" {
+				mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+					return job.Queue == tt.expectedQueue && assert.ObjectsAreEqual(tt.expectedTags, job.Tags)
+				}), mock.AnythingOfType("*models.JobSchedule")).Return(nil).Once()
+			}
+
+			reqBody := CreateJobRequest{
+				API:      "http://example.com/webhook",
+				Type:     models.AT_LEAST_ONCE,
+				Schedule: "0 */5 * * * *",
+				Queue:    tt.queue,
+				Tags:     tt.tags,
+			}
+
+			jsonBody, _ := json.Marshal(reqBody)
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			if tt.code == "" {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				return
+			}
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.code, response["code"])
+		})
+	}
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_TimeoutBounds(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, 10*time.Minute, 0)
+
+	mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+		return job.TimeoutSeconds == models.DefaultJobTimeoutSeconds
+	}), mock.AnythingOfType("*models.JobSchedule")).Return(nil).Once()
+	mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+		return job.TimeoutSeconds == 600
+	}), mock.AnythingOfType("*models.JobSchedule")).Return(nil).Once()
+
+	tests := []struct {
+		name           string
+		timeoutSeconds int
+		expectedStatus int
+	}{
+		{name: "default", timeoutSeconds: 0, expectedStatus: http.StatusCreated},
+		{name: "at maximum", timeoutSeconds: 600, expectedStatus: http.StatusCreated},
+		{name: "above maximum", timeoutSeconds: 601, expectedStatus: http.StatusBadRequest},
+		{name: "negative", timeoutSeconds: -1, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody := CreateJobRequest{
+				API:            "http://example.com/webhook",
+				Type:           models.AT_LEAST_ONCE,
+				Schedule:       "0 */5 * * * *",
+				TimeoutSeconds: tt.timeoutSeconds,
+			}
+
+			jsonBody, _ := json.Marshal(reqBody)
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusBadRequest {
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "INVALID_TIMEOUT", response["code"])
+			}
+		})
+	}
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_NegativeMaxRetryCount(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	reqBody := CreateJobRequest{
+		API:           "http://example.com/webhook",
+		Type:          models.AT_LEAST_ONCE,
+		Schedule:      "0 */5 * * * *",
+		MaxRetryCount: intPtr(-1),
+	}
+
+	jsonBody, _ := json.Marshal(reqBody)
+	req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	// Execute
+	handler.CreateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "INVALID_MAX_RETRY_COUNT", response["code"])
+
+	mockStorage.AssertNotCalled(t, "CreateJobWithSchedule", mock.Anything, mock.Anything)
+}
+
+func TestJobHandler_CreateJob_WithTimezone(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+		return job.Timezone == "Europe/Berlin"
+	}), mock.MatchedBy(func(schedule *models.JobSchedule) bool {
+		local := schedule.NextExecutionTime.In(berlin)
+		return local.Hour() == 9 && local.Minute() == 0
+	})).Return(nil)
+
+	reqBody := CreateJobRequest{
+		API:      "http://example.com/webhook",
+		Type:     models.AT_LEAST_ONCE,
+		Schedule: "0 0 9 * * *",
+		Timezone: "Europe/Berlin",
+	}
+
+	jsonBody, _ := json.Marshal(reqBody)
+	req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	// Execute
+	handler.CreateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_InvalidTimezone(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	reqBody := CreateJobRequest{
+		API:      "http://example.com/webhook",
+		Type:     models.AT_LEAST_ONCE,
+		Schedule: "0 0 9 * * *",
+		Timezone: "Europe/Atlantis",
+	}
+
+	jsonBody, _ := json.Marshal(reqBody)
+	req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	// Execute
+	handler.CreateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "INVALID_TIMEZONE", response["code"])
+
+	mockStorage.AssertNotCalled(t, "CreateJobWithSchedule", mock.Anything, mock.Anything)
+}
+
+func TestJobHandler_UpdateJob_TimezoneChangeRecomputesNextExecution(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	existingJob := &models.Job{
+		ID:            1,
+		Schedule:      "0 0 9 * * *",
+		Timezone:      "UTC",
+		API:           "http://example.com/webhook",
+		Type:          models.AT_LEAST_ONCE,
+		IsRecurring:   true,
+		MaxRetryCount: 3,
+		IsActive:      true,
+	}
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	mockStorage.On("GetJob", uint(1)).Return(existingJob, nil)
+	mockStorage.On("UpdateJobWithSchedule", existingJob, mock.MatchedBy(func(schedule *models.JobSchedule) bool {
+		return schedule.NextExecutionTime.In(tokyo).Hour() == 9
+	})).Return(nil)
+
+	jsonBody := []byte(`{"timezone": "Asia/Tokyo"}`)
+	req, _ := http.NewRequest("PATCH", "/api/v1/jobs/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.UpdateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Asia/Tokyo", existingJob.Timezone)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestResolveRunAt(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	future := now.Add(2 * time.Hour)
+	past := now.Add(-time.Minute)
+	afterDelay := now.Add(15 * time.Minute)
+
+	tests := []struct {
+		name        string
+		hasSchedule bool
+		runAt       *time.Time
+		delay       string
+		expected    *time.Time
+		expectError bool
+	}{
+		{name: "cron schedule", hasSchedule: true},
+		{name: "runAt", runAt: &future, expected: &future},
+		{name: "delay", delay: "15m", expected: &afterDelay},
+		{name: "nothing given", expectError: true},
+		{name: "schedule and runAt", hasSchedule: true, runAt: &future, expectError: true},
+		{name: "runAt and delay", runAt: &future, delay: "1h", expectError: true},
+		{name: "runAt in the past", runAt: &past, expectError: true},
+		{name: "malformed delay", delay: "soon", expectError: true},
+		{name: "negative delay", delay: "-5m", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runAt, err := resolveRunAt(tt.hasSchedule, tt.runAt, tt.delay, now)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, runAt)
+			} else {
+				assert.True(t, tt.expected.Equal(*runAt), "got %v, want %v", runAt, tt.expected)
+			}
+		})
+	}
+}
+
+func TestJobHandler_CreateJob_WithDelay(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	before := time.Now()
+	mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+		return job.IsOneShot() && job.Schedule == "" && !job.IsRecurring
+	}), mock.MatchedBy(func(schedule *models.JobSchedule) bool {
+		return !schedule.NextExecutionTime.Before(before.Add(2*time.Hour)) &&
+			schedule.NextExecutionTime.Before(time.Now().Add(2*time.Hour+time.Second))
+	})).Return(nil)
+
+	jsonBody := []byte(`{"api": "http://example.com/remind", "type": "AT_LEAST_ONCE", "delay": "2h"}`)
+	req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	// Execute
+	handler.CreateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_CreateJob_OneShotValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	runAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "schedule and runAt", body: `{"api": "http://example.com", "type": "AT_LEAST_ONCE", "schedule": "0 * * * * *", "runAt": "` + runAt + `"}`},
+		{name: "recurring one-shot", body: `{"api": "http://example.com", "type": "AT_LEAST_ONCE", "delay": "1h", "isRecurring": true}`},
+		{name: "no timing", body: `{"api": "http://example.com", "type": "AT_LEAST_ONCE"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+			req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.CreateJob(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "INVALID_SCHEDULE", response["code"])
+			mockStorage.AssertNotCalled(t, "CreateJobWithSchedule", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestJobHandler_UpdateJob_SwitchToRunAt(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	existingJob := &models.Job{
+		ID:            1,
+		Schedule:      "0 */5 * * * *",
+		Timezone:      "UTC",
+		API:           "http://example.com/webhook",
+		Type:          models.AT_LEAST_ONCE,
+		MaxRetryCount: 3,
+		IsActive:      true,
+	}
+	runAt := time.Now().Add(3 * time.Hour).UTC().Truncate(time.Second)
+
+	mockStorage.On("GetJob", uint(1)).Return(existingJob, nil)
+	mockStorage.On("UpdateJobWithSchedule", existingJob, mock.MatchedBy(func(schedule *models.JobSchedule) bool {
+		return schedule.NextExecutionTime.Equal(runAt)
+	})).Return(nil)
+
+	jsonBody := []byte(`{"runAt": "` + runAt.Format(time.RFC3339) + `"}`)
+	req, _ := http.NewRequest("PATCH", "/api/v1/jobs/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	// Execute
+	handler.UpdateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, existingJob.Schedule)
+	assert.True(t, existingJob.IsOneShot())
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJobHandler_ValidateWindow(t *testing.T) {
+	handler := NewJobHandler(new(MockStorage), time.Hour, 0)
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	startAt := now.Add(time.Hour)
+	endAt := now.Add(2 * time.Hour)
+	soon := now.Add(time.Minute)
+
+	tests := []struct {
+		name        string
+		job         models.Job
+		expectError bool
+	}{
+		{name: "interval within window", job: models.Job{Schedule: "@every 10m", StartAt: &startAt, EndAt: &endAt, MaxRuns: 5}},
+		{name: "completion anchor", job: models.Job{Schedule: "@every 10m", IntervalAnchor: models.IntervalFromCompletion}},
+		{name: "anchor on cron schedule", job: models.Job{Schedule: "0 0 * * * *", IntervalAnchor: models.IntervalFromCompletion}, expectError: true},
+		{name: "unknown anchor", job: models.Job{Schedule: "@every 10m", IntervalAnchor: "LATER"}, expectError: true},
+		{name: "endAt before startAt", job: models.Job{Schedule: "@every 10m", StartAt: &endAt, EndAt: &startAt}, expectError: true},
+		{name: "no run before endAt", job: models.Job{Schedule: "0 0 12 * * *", EndAt: &soon}, expectError: true},
+		{name: "negative maxRuns", job: models.Job{Schedule: "@every 10m", MaxRuns: -1}, expectError: true},
+		{name: "maxRuns used up", job: models.Job{Schedule: "@every 10m", MaxRuns: 3, RunCount: 3}, expectError: true},
+		{name: "window on one-shot job", job: models.Job{RunAt: &startAt, EndAt: &endAt}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := handler.validateWindow(&tt.job, now)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// Interval jobs default to the scheduled-time anchor
+	job := models.Job{Schedule: "@every 10m"}
+	assert.NoError(t, handler.validateWindow(&job, now))
+	assert.Equal(t, models.IntervalFromScheduled, job.IntervalAnchor)
+}
+
+func TestValidateMisfirePolicy(t *testing.T) {
+	assert.NoError(t, validateMisfirePolicy("", 0))
+	assert.NoError(t, validateMisfirePolicy(models.MisfireFireOnceNow, 60))
+	assert.NoError(t, validateMisfirePolicy(models.MisfireSkipToNext, 300))
+	assert.NoError(t, validateMisfirePolicy(models.MisfireRunAllMissed, 30))
+	assert.Error(t, validateMisfirePolicy("run_some", 60))
+	assert.Error(t, validateMisfirePolicy(models.MisfireSkipToNext, -1))
+}
+
+func TestJobHandler_CreateJob_MisfireDefaults(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	mockStorage := new(MockStorage)
+	handler := NewJobHandler(mockStorage, time.Hour, 0)
+
+	mockStorage.On("CreateJobWithSchedule", mock.MatchedBy(func(job *models.Job) bool {
+		return job.MisfirePolicy == models.MisfireFireOnceNow &&
+			job.MisfireThresholdSeconds == models.DefaultMisfireThresholdSeconds
+	}), mock.AnythingOfType("*models.JobSchedule")).Return(nil)
+
+	jsonBody := []byte(`{"api": "http://example.com", "type": "AT_LEAST_ONCE", "schedule": "0 * * * * *"}`)
+	req, _ := http.NewRequest("POST", "/api/v1/jobs", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	// Execute
+	handler.CreateJob(c)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manyu/job-scheduler/internal/errors"
+	"github.com/manyu/job-scheduler/internal/middleware"
+	"github.com/manyu/job-scheduler/internal/services"
+)
+
+// HealthChecker is implemented by dependencies that can report their health
+type HealthChecker interface {
+	Health() error
+}
+
+// SystemHandler serves operational endpoints such as health and queue statistics
+type SystemHandler struct {
+	database  HealthChecker
+	redis     HealthChecker
+	scheduler services.SchedulerServiceInterface
+	leader    services.LeaderElectorInterface // nil when leader election is disabled
+	workers   services.WorkerRegistryInterface
+}
+
+func NewSystemHandler(database, redis HealthChecker, scheduler services.SchedulerServiceInterface, leader services.LeaderElectorInterface, workers services.WorkerRegistryInterface) *SystemHandler {
+	return &SystemHandler{
+		database:  database,
+		redis:     redis,
+		scheduler: scheduler,
+		leader:    leader,
+		workers:   workers,
+	}
+}
+
+// Health handles GET /health
+func (h *SystemHandler) Health(c *gin.Context) {
+	checks := gin.H{
+		"database": "healthy",
+		"redis":    "healthy",
+	}
+	healthy := true
+
+	if err := h.database.Health(); err != nil {
+		checks["database"] = err.Error()
+		healthy = false
+	}
+	if err := h.redis.Health(); err != nil {
+		checks["redis"] = err.Error()
+		healthy = false
+	}
+
+	if !healthy {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "unhealthy",
+			"checks": checks,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "healthy",
+		"checks": checks,
+	})
+}
+
+// GetQueueStats handles GET /queue/stats
+func (h *SystemHandler) GetQueueStats(c *gin.Context) {
+	stats, err := h.scheduler.GetQueueStats()
+	if err != nil {
+		middleware.HandleError(c, errors.Wrap(err, "QUEUE_STATS_ERROR", "Failed to get queue stats", http.StatusInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetLeader handles GET /admin/leader
+func (h *SystemHandler) GetLeader(c *gin.Context) {
+	if h.leader == nil {
+		middleware.HandleError(c, errors.ErrLeaderElectionDisabled)
+		return
+	}
+
+	status, err := h.leader.Status(c.Request.Context())
+	if err != nil {
+		middleware.HandleError(c, errors.ErrRedisError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetWorkers handles GET /workers
+func (h *SystemHandler) GetWorkers(c *gin.Context) {
+	workers, err := h.workers.List(c.Request.Context())
+	if err != nil {
+		middleware.HandleError(c, errors.ErrRedisError.WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workers": workers,
+		"total":   len(workers),
+	})
+}
+
+// QueueResponse is a queue with the number of live workers taking jobs from it
+type QueueResponse struct {
+	*services.QueueInfo
+	Workers int `json:"workers"`
+}
+
+// GetQueues handles GET /admin/queues
+func (h *SystemHandler) GetQueues(c *gin.Context) {
+	queues, err := h.scheduler.ListQueues()
+	if err != nil {
+		middleware.HandleError(c, errors.ErrRedisError.WithDetails(err.Error()))
+		return
+	}
+	workers, err := h.workers.List(c.Request.Context())
+	if err != nil {
+		middleware.HandleError(c, errors.ErrRedisError.WithDetails(err.Error()))
+		return
+	}
+
+	response := make([]QueueResponse, len(queues))
+	for i, queue := range queues {
+		response[i] = QueueResponse{QueueInfo: queue}
+		for _, worker := range workers {
+			subscription := services.QueueSubscription{Queues: worker.Queues, Tags: worker.Tags}
+			if !worker.IsShutdown && subscription.Matches(queue.Name) {
+				response[i].Workers++
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queues": response,
+		"total":  len(response),
+	})
+}
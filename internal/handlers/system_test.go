@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/services"
+	mock_services "github.com/manyu/job-scheduler/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// fakeHealthChecker returns a fixed health result
+type fakeHealthChecker struct {
+	err error
+}
+
+func (f *fakeHealthChecker) Health() error {
+	return f.err
+}
+
+func TestSystemHandler_Health_Healthy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewSystemHandler(&fakeHealthChecker{}, &fakeHealthChecker{}, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/health", nil)
+
+	handler.Health(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "healthy", response["status"])
+}
+
+func TestSystemHandler_Health_Unhealthy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewSystemHandler(&fakeHealthChecker{}, &fakeHealthChecker{err: assert.AnError}, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/health", nil)
+
+	handler.Health(c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "unhealthy", response["status"])
+}
+
+func TestSystemHandler_GetQueueStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	scheduler := mock_services.NewMockSchedulerServiceInterface(ctrl)
+	handler := NewSystemHandler(&fakeHealthChecker{}, &fakeHealthChecker{}, scheduler, nil, nil)
+
+	scheduler.EXPECT().GetQueueStats().Return(map[string]int64{
+		"ready":      5,
+		"processing": 3,
+		"completed":  150,
+		"retrying":   2,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/queue/stats", nil)
+
+	handler.GetQueueStats(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]int64
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(5), response["ready"])
+	assert.Equal(t, int64(2), response["retrying"])
+}
+
+func TestSystemHandler_GetLeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	leader := mock_services.NewMockLeaderElectorInterface(ctrl)
+	handler := NewSystemHandler(&fakeHealthChecker{}, &fakeHealthChecker{}, nil, leader, nil)
+
+	expiresAt := time.Now().Add(10 * time.Second)
+	leader.EXPECT().Status(gomock.Any()).Return(&services.LeaderStatus{
+		Leader:    "scheduler-a",
+		Term:      7,
+		ExpiresAt: &expiresAt,
+		Self:      "scheduler-b",
+		IsLeader:  false,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/admin/leader", nil)
+
+	handler.GetLeader(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "scheduler-a", response["leader"])
+	assert.Equal(t, float64(7), response["term"])
+	assert.Equal(t, "scheduler-b", response["self"])
+	assert.Equal(t, false, response["isLeader"])
+}
+
+func TestSystemHandler_GetLeader_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewSystemHandler(&fakeHealthChecker{}, &fakeHealthChecker{}, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/admin/leader", nil)
+
+	handler.GetLeader(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSystemHandler_GetWorkers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	workers := mock_services.NewMockWorkerRegistryInterface(ctrl)
+	handler := NewSystemHandler(&fakeHealthChecker{}, &fakeHealthChecker{}, nil, nil, workers)
+
+	workers.EXPECT().List(gomock.Any()).Return([]*services.WorkerStats{
+		{
+			ID:            "worker-3f1a-1-5c02e7d9",
+			Host:          "worker-3f1a",
+			Version:       "dev",
+			StartedAt:     time.Now().Add(-time.Hour),
+			HeartbeatAt:   time.Now(),
+			PoolSize:      10,
+			ActiveWorkers: 2,
+			ActiveJobIDs:  []string{"job_4_1700000000", "job_9_1700000005"},
+		},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/workers", nil)
+
+	handler.GetWorkers(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Workers []map[string]interface{} `json:"workers"`
+		Total   int                      `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Total)
+	assert.Equal(t, "worker-3f1a-1-5c02e7d9", response.Workers[0]["id"])
+	assert.Equal(t, float64(10), response.Workers[0]["poolSize"])
+	assert.Equal(t, []interface{}{"job_4_1700000000", "job_9_1700000005"}, response.Workers[0]["activeJobIds"])
+}
+
+func TestSystemHandler_GetWorkers_RedisError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	workers := mock_services.NewMockWorkerRegistryInterface(ctrl)
+	handler := NewSystemHandler(&fakeHealthChecker{}, &fakeHealthChecker{}, nil, nil, workers)
+
+	workers.EXPECT().List(gomock.Any()).Return(nil, assert.AnError)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/workers", nil)
+
+	handler.GetWorkers(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestSystemHandler_GetQueues(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	scheduler := mock_services.NewMockSchedulerServiceInterface(ctrl)
+	workers := mock_services.NewMockWorkerRegistryInterface(ctrl)
+	handler := NewSystemHandler(&fakeHealthChecker{}, &fakeHealthChecker{}, scheduler, nil, workers)
+
+	gpuQueue := models.JobTags{"gpu"}.QueueName()
+	scheduler.EXPECT().ListQueues().Return([]*services.QueueInfo{
+		{Name: models.DefaultQueue, Ready: 4, ReadyByPriority: map[models.JobPriority]int64{models.PriorityNormal: 3, models.PriorityHigh: 1}},
+		{Name: gpuQueue, Tags: models.JobTags{"gpu"}, Ready: 2, ReadyByPriority: map[models.JobPriority]int64{models.PriorityNormal: 2}},
+	}, nil)
+	workers.EXPECT().List(gomock.Any()).Return([]*services.WorkerStats{
+		{ID: "worker-a", Queues: []string{models.DefaultQueue}},
+		{ID: "worker-b", Queues: []string{models.DefaultQueue}, Tags: models.JobTags{"gpu", "linux"}},
+		{ID: "worker-c", Queues: []string{models.DefaultQueue}, Tags: models.JobTags{"gpu"}, IsShutdown: true},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/admin/queues", nil)
+
+	handler.GetQueues(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Queues []map[string]interface{} `json:"queues"`
+		Total  int                      `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Total)
+	assert.Equal(t, "default", response.Queues[0]["name"])
+	assert.Equal(t, float64(4), response.Queues[0]["ready"])
+	assert.Equal(t, float64(2), response.Queues[0]["workers"])
+	assert.Equal(t, gpuQueue, response.Queues[1]["name"])
+	assert.Equal(t, []interface{}{"gpu"}, response.Queues[1]["tags"])
+	assert.Equal(t, float64(1), response.Queues[1]["workers"])
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// CredentialType selects how a credential authenticates the calls of the jobs using it
+type CredentialType string
+
+const (
+	CredentialBearer CredentialType = "bearer"                    // Static token sent as "Authorization: Bearer <token>"
+	CredentialBasic  CredentialType = "basic"                     // Username and password sent as HTTP basic auth
+	CredentialOAuth2 CredentialType = "oauth2_client_credentials" // Access token fetched from a token endpoint with a client ID and secret
+)
+
+var credentialNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// Credential authenticates the calls of the jobs that reference it by name. Its secret, the bearer
+// token, password or client secret, is stored encrypted and never returned by the API.
+type Credential struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"size:64;not null;uniqueIndex"`
+	Type        CredentialType `json:"type" gorm:"size:30;not null"`
+	Description string         `json:"description,omitempty" gorm:"type:text"`
+	Username    string         `json:"username,omitempty" gorm:"size:255"`  // Basic auth
+	ClientID    string         `json:"clientId,omitempty" gorm:"size:255"`  // OAuth2
+	TokenURL    string         `json:"tokenUrl,omitempty" gorm:"type:text"` // OAuth2
+	Scopes      string         `json:"scopes,omitempty" gorm:"type:text"`   // OAuth2, space separated
+	Secret      []byte         `json:"-" gorm:"type:bytea;not null"`        // Encrypted token, password or client secret
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+// ValidateCredentialName checks that name can name a credential
+func ValidateCredentialName(name string) error {
+	if !credentialNamePattern.MatchString(name) {
+		return fmt.Errorf("credential name %q must be up to 64 lowercase letters, digits, '-', '_' or '.'", name)
+	}
+	return nil
+}
+
+// Validate checks that the credential has the fields its type needs, apart from the secret
+func (c *Credential) Validate() error {
+	if err := ValidateCredentialName(c.Name); err != nil {
+		return err
+	}
+
+	switch c.Type {
+	case CredentialBearer:
+		return nil
+	case CredentialBasic:
+		if c.Username == "" {
+			return fmt.Errorf("basic credentials need a username")
+		}
+		return nil
+	case CredentialOAuth2:
+		if c.ClientID == "" {
+			return fmt.Errorf("oauth2 credentials need a clientId")
+		}
+		u, err := url.Parse(c.TokenURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("oauth2 credentials need an http(s) tokenUrl")
+		}
+		return nil
+	default:
+		return fmt.Errorf("credential type must be bearer, basic or oauth2_client_credentials")
+	}
+}
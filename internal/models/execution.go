@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql/driver"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,17 +14,67 @@ const (
 	StatusRunning   ExecutionStatus = "RUNNING"
 	StatusSuccess   ExecutionStatus = "SUCCESS"
 	StatusFailed    ExecutionStatus = "FAILED"
+	StatusTimeout   ExecutionStatus = "TIMEOUT"
+	StatusSkipped   ExecutionStatus = "SKIPPED"   // A run that the misfire or concurrency policy did not execute
+	StatusCancelled ExecutionStatus = "CANCELLED" // A run stopped for a newer one by the Replace concurrency policy
+	StatusExpired   ExecutionStatus = "EXPIRED"   // A run that never finished and was given up past its deadline
 )
 
+// ExecutionErrorClass categorises why an execution failed
+type ExecutionErrorClass string
+
+const (
+	ErrorClassTimeout           ExecutionErrorClass = "TIMEOUT"
+	ErrorClassConnectionRefused ExecutionErrorClass = "CONNECTION_REFUSED"
+	ErrorClassDNS               ExecutionErrorClass = "DNS"
+	ErrorClassTLS               ExecutionErrorClass = "TLS"
+	ErrorClassNetwork           ExecutionErrorClass = "NETWORK"
+	ErrorClassHTTPStatus        ExecutionErrorClass = "HTTP_STATUS"   // Response received but status was not a success
+	ErrorClassBodyMismatch      ExecutionErrorClass = "BODY_MISMATCH" // Status accepted but body failed the success criteria
+	ErrorClassRequest           ExecutionErrorClass = "REQUEST"       // Request could not be built
+)
+
+// ExecutionLatency breaks down where time was spent during the outbound call.
+// Phases skipped on a reused connection are zero.
+type ExecutionLatency struct {
+	DNSLookup       time.Duration `json:"dnsLookup"`
+	Connect         time.Duration `json:"connect"`
+	TLSHandshake    time.Duration `json:"tlsHandshake"`
+	TimeToFirstByte time.Duration `json:"timeToFirstByte"`
+}
+
+// ResponseHeaders holds response headers flattened to a single value per name
+type ResponseHeaders map[string]string
+
 type JobExecution struct {
-	ID                uint            `json:"id" gorm:"primaryKey"`
-	JobID             uint            `json:"jobId" gorm:"not null;index"`
-	Status            ExecutionStatus `json:"status" gorm:"size:20;not null;index"`
-	Error             string          `json:"error,omitempty" gorm:"type:text"`
-	ExecutionTime     time.Time       `json:"executionTime" gorm:"not null;index"`
-	ExecutionDuration *time.Duration  `json:"executionDuration,omitempty"`
-	RetryCount        int             `json:"retryCount" gorm:"default:0"`
-	CreatedAt         time.Time       `json:"createdAt"`
-	UpdatedAt         time.Time       `json:"updatedAt"`
-	DeletedAt         gorm.DeletedAt  `json:"-" gorm:"index"`
+	ID                 uint                `json:"id" gorm:"primaryKey"`
+	JobID              uint                `json:"jobId" gorm:"not null;index"`
+	OccurrenceID       string              `json:"occurrenceId,omitempty" gorm:"size:64;index"` // Scheduled run this execution belongs to
+	Status             ExecutionStatus     `json:"status" gorm:"size:20;not null;index"`
+	Error              string              `json:"error,omitempty" gorm:"type:text"`
+	ErrorClass         ExecutionErrorClass `json:"errorClass,omitempty" gorm:"size:30"`
+	ExecutionTime      time.Time           `json:"executionTime" gorm:"not null;index"`
+	ExecutionDuration  *time.Duration      `json:"executionDuration,omitempty"`
+	Latency            ExecutionLatency    `json:"latency" gorm:"embedded;embeddedPrefix:latency_"`
+	ResponseStatusCode int                 `json:"responseStatusCode,omitempty"`
+	ResponseHeaders    ResponseHeaders     `json:"responseHeaders,omitempty" gorm:"type:jsonb"`
+	ResponseBody       string              `json:"responseBody,omitempty" gorm:"type:text"` // Truncated excerpt
+	RetryCount         int                 `json:"retryCount" gorm:"default:0"`
+	CreatedAt          time.Time           `json:"createdAt"`
+	UpdatedAt          time.Time           `json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt      `json:"-" gorm:"index"`
+}
+
+// Value implements driver.Valuer so the headers are stored as JSON
+func (h ResponseHeaders) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	return jsonValue(h)
+}
+
+// Scan implements sql.Scanner so the headers can be read back from JSON
+func (h *ResponseHeaders) Scan(value interface{}) error {
+	*h = nil
+	return scanJSON(value, h)
 }
@@ -13,16 +13,146 @@ const (
 	AT_MOST_ONCE  JobType = "AT_MOST_ONCE"
 )
 
+// IntervalAnchor selects what an "@every" interval is measured from
+type IntervalAnchor string
+
+const (
+	IntervalFromScheduled  IntervalAnchor = "SCHEDULED"  // From the previous scheduled time, keeping a fixed grid
+	IntervalFromCompletion IntervalAnchor = "COMPLETION" // From when the previous run finished
+)
+
+// MisfirePolicy selects what happens to runs that were missed, e.g. while the scheduler was down
+type MisfirePolicy string
+
+const (
+	MisfireFireOnceNow  MisfirePolicy = "fire_once_now"  // Run the latest missed occurrence once and skip the rest
+	MisfireSkipToNext   MisfirePolicy = "skip_to_next"   // Skip every missed occurrence and wait for the next one
+	MisfireRunAllMissed MisfirePolicy = "run_all_missed" // Run missed occurrences, up to MaxMisfireCatchUpRuns
+)
+
+// DefaultMisfireThresholdSeconds is how late a run may start before it counts as missed
+const DefaultMisfireThresholdSeconds = 60
+
+// MaxMisfireCatchUpRuns bounds how many missed occurrences run_all_missed executes
+const MaxMisfireCatchUpRuns = 10
+
+// JobPriority selects which ready queue a job waits in. Workers favour the higher priorities.
+type JobPriority string
+
+const (
+	PriorityHigh   JobPriority = "high"
+	PriorityNormal JobPriority = "normal"
+	PriorityLow    JobPriority = "low"
+)
+
+// Priorities lists every priority, highest first
+var Priorities = []JobPriority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// IsValid reports whether p is a known priority
+func (p JobPriority) IsValid() bool {
+	for _, priority := range Priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// OrNormal returns p, or PriorityNormal if p is empty, as for jobs queued before priorities existed
+func (p JobPriority) OrNormal() JobPriority {
+	if p == "" {
+		return PriorityNormal
+	}
+	return p
+}
+
+// ConcurrencyPolicy selects what a run does when earlier runs of its job are still in progress
+type ConcurrencyPolicy string
+
+const (
+	ConcurrencyAllow   ConcurrencyPolicy = "Allow"   // Run alongside the earlier runs
+	ConcurrencyForbid  ConcurrencyPolicy = "Forbid"  // Skip the run and record it as skipped
+	ConcurrencyReplace ConcurrencyPolicy = "Replace" // Cancel the earlier runs and run
+	ConcurrencyQueue   ConcurrencyPolicy = "Queue"   // Wait for the earlier runs to finish, then run
+)
+
+// IsValid reports whether p is a known concurrency policy
+func (p ConcurrencyPolicy) IsValid() bool {
+	switch p {
+	case ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace, ConcurrencyQueue:
+		return true
+	}
+	return false
+}
+
+// OrForbid returns p, or ConcurrencyForbid if p is empty, as for jobs created before concurrency policies existed
+func (p ConcurrencyPolicy) OrForbid() ConcurrencyPolicy {
+	if p == "" {
+		return ConcurrencyForbid
+	}
+	return p
+}
+
+// DefaultJobTimeoutSeconds is applied to jobs that do not declare a timeout
+const DefaultJobTimeoutSeconds = 90
+
 type Job struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	Schedule      string         `json:"schedule" gorm:"size:100;not null"`
-	API           string         `json:"api" gorm:"type:text;not null"`
-	Type          JobType        `json:"type" gorm:"size:20;not null"`
-	IsRecurring   bool           `json:"isRecurring" gorm:"default:false"`
-	IsActive      bool           `json:"isActive" gorm:"default:true;index"`
-	Description   string         `json:"description" gorm:"type:text"`
-	MaxRetryCount int            `json:"maxRetryCount" gorm:"default:3"`
-	CreatedAt     time.Time      `json:"createdAt"`
-	UpdatedAt     time.Time      `json:"updatedAt"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                      uint              `json:"id" gorm:"primaryKey"`
+	Schedule                string            `json:"schedule" gorm:"size:100;not null"`
+	Timezone                string            `json:"timezone" gorm:"size:64;not null;default:UTC"` // IANA zone the schedule is evaluated in
+	RunAt                   *time.Time        `json:"runAt,omitempty"`                              // Set for one-shot jobs, which have no schedule
+	IntervalAnchor          IntervalAnchor    `json:"intervalAnchor,omitempty" gorm:"size:20"`      // For "@every" schedules; defaults to SCHEDULED
+	StartAt                 *time.Time        `json:"startAt,omitempty"`                            // No runs before this time
+	EndAt                   *time.Time        `json:"endAt,omitempty"`                              // No runs after this time
+	MaxRuns                 int               `json:"maxRuns,omitempty"`                            // Deactivate after this many runs; 0 is unlimited
+	RunCount                int               `json:"runCount" gorm:"default:0"`                    // Runs so far
+	MisfirePolicy           MisfirePolicy     `json:"misfirePolicy" gorm:"size:20;default:fire_once_now"`
+	MisfireThresholdSeconds int               `json:"misfireThresholdSeconds" gorm:"default:60"` // Runs later than this are missed
+	API                     string            `json:"api" gorm:"type:text;not null"`
+	Request                 HTTPRequestSpec   `json:"request" gorm:"type:jsonb"`
+	SuccessCriteria         SuccessCriteria   `json:"successCriteria" gorm:"type:jsonb"`
+	Type                    JobType           `json:"type" gorm:"size:20;not null"`
+	Priority                JobPriority       `json:"priority" gorm:"size:10;not null;default:normal"`
+	Queue                   string            `json:"queue" gorm:"size:64;not null;default:default"`            // Named queue the job runs from, unless it has tags
+	Tags                    JobTags           `json:"tags,omitempty" gorm:"type:jsonb"`                         // Tags a worker needs to run the job
+	ConcurrencyPolicy       ConcurrencyPolicy `json:"concurrencyPolicy" gorm:"size:10;not null;default:Forbid"` // What a run does while earlier runs are in progress
+	RateLimitKey            string            `json:"rateLimitKey,omitempty" gorm:"size:128"`                   // Rate limit bucket shared by jobs; defaults to the API host
+	SigningKey              string            `json:"signingKey,omitempty" gorm:"size:64"`                      // Named secrets calls are signed with; defaults to the worker's default secrets
+	Credential              string            `json:"credential,omitempty" gorm:"size:64;index"`                // Name of the credential calls authenticate with
+	IsRecurring             bool              `json:"isRecurring" gorm:"default:false"`
+	IsActive                bool              `json:"isActive" gorm:"default:true;index"`
+	Description             string            `json:"description" gorm:"type:text"`
+	MaxRetryCount           int               `json:"maxRetryCount"`                 // 0 means no retries, so the column has no default
+	RetryPolicy             RetryPolicy       `json:"retryPolicy" gorm:"type:jsonb"` // Empty fields take the worker's defaults
+	TimeoutSeconds          int               `json:"timeoutSeconds" gorm:"default:90"`
+	IdempotencyKey          *string           `json:"-" gorm:"size:255;uniqueIndex"` // Idempotency-Key the job was created with
+	RequestHash             string            `json:"-" gorm:"size:64"`              // Hash of the creation request, to tell a replay from a reused key
+	CreatedAt               time.Time         `json:"createdAt"`
+	UpdatedAt               time.Time         `json:"updatedAt"`
+	DeletedAt               gorm.DeletedAt    `json:"-" gorm:"index"`
+}
+
+// IsOneShot reports whether the job runs once at RunAt rather than on a cron schedule
+func (j *Job) IsOneShot() bool {
+	return j.RunAt != nil
+}
+
+// QueueName returns the queue the job's runs are routed to: the queue of its tags if it has any,
+// otherwise its named queue
+func (j *Job) QueueName() string {
+	if len(j.Tags) > 0 {
+		return j.Tags.QueueName()
+	}
+	if j.Queue == "" {
+		return DefaultQueue
+	}
+	return j.Queue
+}
+
+// MisfireThreshold returns how late a run may start before the misfire policy applies
+func (j *Job) MisfireThreshold() time.Duration {
+	if j.MisfireThresholdSeconds <= 0 {
+		return DefaultMisfireThresholdSeconds * time.Second
+	}
+	return time.Duration(j.MisfireThresholdSeconds) * time.Second
 }
@@ -8,17 +8,36 @@ import (
 
 // QueueJob represents a job in the Redis queue
 type QueueJob struct {
-	ID            string    `json:"id"`              // Unique queue job ID
-	JobID         uint      `json:"job_id"`          // Original job ID from database
-	API           string    `json:"api"`             // API endpoint to call
-	MaxRetryCount int       `json:"max_retry_count"` // Maximum number of retries
-	RetryCount    int       `json:"retry_count"`     // Current retry count
-	CreatedAt     time.Time `json:"created_at"`      // When the job was created
-	ScheduledAt   time.Time `json:"scheduled_at"`    // When the job should be executed
-	Timeout       int       `json:"timeout"`         // Timeout in seconds (default 90)
-	Type          JobType   `json:"type"`            // Job type (AT_MOST_ONCE, AT_LEAST_ONCE)
-	IsRecurring   bool      `json:"is_recurring"`    // Whether this is a recurring job
-	Schedule      string    `json:"schedule"`        // Cron schedule for recurring jobs
+	ID              string            `json:"id"`                         // Unique queue job ID
+	JobID           uint              `json:"job_id"`                     // Original job ID from database
+	OccurrenceID    string            `json:"occurrence_id"`              // Stable ID of the scheduled run, shared by its retries
+	API             string            `json:"api"`                        // API endpoint to call
+	Request         HTTPRequestSpec   `json:"request"`                    // HTTP request definition
+	SuccessCriteria SuccessCriteria   `json:"success_criteria"`           // Rules for success and retryability
+	LastStatusCode  int               `json:"last_status_code,omitempty"` // Status code of the last failed attempt, 0 if none
+	MaxRetryCount   int               `json:"max_retry_count"`            // Maximum number of retries
+	RetryCount      int               `json:"retry_count"`                // Current retry count
+	RetryPolicy     RetryPolicy       `json:"retry_policy"`               // Delays between retries; empty fields take the worker's defaults
+	CreatedAt       time.Time         `json:"created_at"`                 // When the job was created
+	ScheduledAt     time.Time         `json:"scheduled_at"`               // When the job should be executed
+	Timeout         int               `json:"timeout"`                    // Timeout in seconds (default 90)
+	Type            JobType           `json:"type"`                       // Job type (AT_MOST_ONCE, AT_LEAST_ONCE)
+	Priority        JobPriority       `json:"priority,omitempty"`         // Ready queue the job waits in; empty is normal
+	Queue           string            `json:"queue,omitempty"`            // Named or tag queue the job is routed to; empty is the default queue
+	RateLimitKey    string            `json:"rate_limit_key,omitempty"`   // Rate limit bucket the call counts against; empty is the API host
+	SigningKey      string            `json:"signing_key,omitempty"`      // Named secrets the call is signed with; empty is the worker's default secrets
+	Credential      string            `json:"credential,omitempty"`       // Name of the credential the call authenticates with
+	IsRecurring     bool              `json:"is_recurring"`               // Whether this is a recurring job
+	Schedule        string            `json:"schedule"`                   // Cron schedule for recurring jobs
+	Attempts        []QueueJobAttempt `json:"attempts,omitempty"`         // Failed attempts so far, oldest first
+}
+
+// QueueJobAttempt records a failed attempt of a queue job
+type QueueJobAttempt struct {
+	Attempt    int       `json:"attempt"`              // 1 for the first attempt
+	Error      string    `json:"error"`                // Why the attempt failed
+	StatusCode int       `json:"statusCode,omitempty"` // Response status code, 0 if there was no response
+	FailedAt   time.Time `json:"failedAt"`
 }
 
 // QueueJobStatus represents the status of a job in the queue
@@ -44,6 +63,31 @@ type QueueJobResult struct {
 	NextExecution     *time.Time     `json:"next_execution,omitempty"` // For recurring jobs
 }
 
+// DeadLetter is a job that failed permanently, kept with its full payload so it can be inspected and replayed
+type DeadLetter struct {
+	ID       string            `json:"id"`       // Queue job ID of the last attempt
+	Job      *QueueJob         `json:"job"`      // Payload as last attempted
+	Status   QueueJobStatus    `json:"status"`   // Always QueueStatusFailed
+	Error    string            `json:"error"`    // Error of the last attempt
+	Attempts []QueueJobAttempt `json:"attempts"` // Every failed attempt, oldest first
+	FailedAt time.Time         `json:"failedAt"`
+}
+
+// NewDeadLetter creates the dead letter of a job whose last attempt failed with errorMsg.
+// The attempt history moves from the job to the dead letter.
+func NewDeadLetter(job *QueueJob, errorMsg string) *DeadLetter {
+	payload := *job
+	payload.Attempts = nil
+	return &DeadLetter{
+		ID:       job.ID,
+		Job:      &payload,
+		Status:   QueueStatusFailed,
+		Error:    errorMsg,
+		Attempts: job.Attempts,
+		FailedAt: time.Now(),
+	}
+}
+
 // Serialize converts a QueueJob to JSON bytes
 func (qj *QueueJob) Serialize() ([]byte, error) {
 	return json.Marshal(qj)
@@ -70,18 +114,32 @@ func DeserializeQueueJobResult(data []byte) (*QueueJobResult, error) {
 
 // NewQueueJob creates a new QueueJob from a database Job and JobSchedule
 func NewQueueJob(job *Job, schedule *JobSchedule) *QueueJob {
+	timeout := job.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = DefaultJobTimeoutSeconds
+	}
+
 	return &QueueJob{
-		ID:            generateQueueJobID(job.ID),
-		JobID:         job.ID,
-		API:           job.API,
-		MaxRetryCount: job.MaxRetryCount,
-		RetryCount:    0,
-		CreatedAt:     time.Now(),
-		ScheduledAt:   schedule.NextExecutionTime,
-		Timeout:       90, // Default 90 seconds for long-running tasks
-		Type:          job.Type,
-		IsRecurring:   job.IsRecurring,
-		Schedule:      job.Schedule,
+		ID:              generateQueueJobID(job.ID),
+		JobID:           job.ID,
+		OccurrenceID:    OccurrenceID(job.ID, schedule.NextExecutionTime),
+		API:             job.API,
+		Request:         job.Request,
+		SuccessCriteria: job.SuccessCriteria,
+		MaxRetryCount:   job.MaxRetryCount,
+		RetryCount:      0,
+		RetryPolicy:     job.RetryPolicy,
+		CreatedAt:       time.Now(),
+		ScheduledAt:     schedule.NextExecutionTime,
+		Timeout:         timeout,
+		Type:            job.Type,
+		Priority:        job.Priority.OrNormal(),
+		Queue:           job.QueueName(),
+		RateLimitKey:    job.RateLimitKey,
+		SigningKey:      job.SigningKey,
+		Credential:      job.Credential,
+		IsRecurring:     job.IsRecurring,
+		Schedule:        job.Schedule,
 	}
 }
 
@@ -90,6 +148,29 @@ func generateQueueJobID(jobID uint) string {
 	return fmt.Sprintf("job_%d_%d", jobID, time.Now().UnixNano())
 }
 
+// OccurrenceID identifies the run of a job scheduled at scheduledAt. Unlike the queue job ID it is the same
+// whichever scheduler dispatches the run and however often it is retried, so consumers can deduplicate on it.
+func OccurrenceID(jobID uint, scheduledAt time.Time) string {
+	return fmt.Sprintf("occ_%d_%d", jobID, scheduledAt.UnixMicro())
+}
+
+// IdempotencyKey returns the key the job's calls carry, which is the same for every retry of an
+// occurrence. Entries enqueued before occurrence IDs existed derive it from their scheduled time.
+func (qj *QueueJob) IdempotencyKey() string {
+	if qj.OccurrenceID != "" {
+		return qj.OccurrenceID
+	}
+	return OccurrenceID(qj.JobID, qj.ScheduledAt)
+}
+
+// QueueName returns the queue the job is routed to
+func (qj *QueueJob) QueueName() string {
+	if qj.Queue == "" {
+		return DefaultQueue
+	}
+	return qj.Queue
+}
+
 // ShouldRetry determines if the job should be retried based on its type and retry count
 func (qj *QueueJob) ShouldRetry() bool {
 	// Don't retry if we've exceeded max retry count
@@ -102,6 +183,11 @@ func (qj *QueueJob) ShouldRetry() bool {
 		return false
 	}
 
+	// Some failed responses are final, e.g. a 400 for a malformed request
+	if !qj.SuccessCriteria.IsRetryableStatus(qj.LastStatusCode) {
+		return false
+	}
+
 	// AT_LEAST_ONCE jobs should be retried
 	return qj.Type == AT_LEAST_ONCE
 }
@@ -114,16 +200,28 @@ func (qj *QueueJob) IncrementRetry() *QueueJob {
 	return &newJob
 }
 
-// CalculateRetryDelay calculates the delay for the next retry using exponential backoff
-func (qj *QueueJob) CalculateRetryDelay() time.Duration {
-	// Exponential backoff: 2^retryCount seconds, max 5 minutes
-	delaySeconds := 1 << qj.RetryCount // 1, 2, 4, 8, 16, 32, 64, 128, 256, 512 seconds
-	maxDelay := 5 * time.Minute
+// RecordFailure appends a failed attempt with errorMsg to the job's attempt history
+func (qj *QueueJob) RecordFailure(errorMsg string) {
+	qj.Attempts = append(qj.Attempts, QueueJobAttempt{
+		Attempt:    qj.RetryCount + 1,
+		Error:      errorMsg,
+		StatusCode: qj.LastStatusCode,
+		FailedAt:   time.Now(),
+	})
+}
 
-	delay := time.Duration(delaySeconds) * time.Second
-	if delay > maxDelay {
-		delay = maxDelay
-	}
+// Replay returns a copy of the job under a new ID with its retries and attempt history reset
+func (qj *QueueJob) Replay() *QueueJob {
+	newJob := *qj
+	newJob.ID = generateQueueJobID(qj.JobID)
+	newJob.RetryCount = 0
+	newJob.LastStatusCode = 0
+	newJob.Attempts = nil
+	return &newJob
+}
 
-	return delay
+// RetryDelay returns how long to wait before the job's current retry under its retry policy,
+// with fields the job leaves empty taken from defaults
+func (qj *QueueJob) RetryDelay(defaults RetryPolicy) time.Duration {
+	return qj.RetryPolicy.WithDefaults(defaults).Delay(qj.RetryCount)
 }
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultRequestMethod is used when a job does not declare a method
+const DefaultRequestMethod = http.MethodPost
+
+// DefaultBodyContentType is sent with a request body when no content type is declared
+const DefaultBodyContentType = "application/json"
+
+// IdempotencyKeyHeader carries the key that makes a request safe to retry: on job creation, from the
+// client, and on the calls of AT_LEAST_ONCE jobs, to the target
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// MaxIdempotencyKeyLength bounds the idempotency keys clients send
+const MaxIdempotencyKeyLength = 255
+
+// RedactedHeaderValue replaces the values of credential headers in API responses
+const RedactedHeaderValue = "[REDACTED]"
+
+// credentialHeaders carry secrets, which belong in an encrypted credential rather than in the plaintext
+// request spec. Keys are canonical header names.
+var credentialHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Api-Key":             true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+	"X-Access-Token":      true,
+}
+
+var allowedRequestMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// HTTPRequestSpec describes the HTTP request a worker sends when executing a job
+type HTTPRequestSpec struct {
+	Method      string            `json:"method,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Query       map[string]string `json:"query,omitempty"`
+	Body        string            `json:"body,omitempty"`        // text/template rendered with RequestTemplateData
+	ContentType string            `json:"contentType,omitempty"` // Content-Type of the rendered body
+}
+
+// RequestTemplateData is the data available to a request body template
+type RequestTemplateData struct {
+	JobID       uint
+	ScheduledAt time.Time
+	Attempt     int
+}
+
+// Normalize fills in defaults so the spec can be stored and executed as-is
+func (r *HTTPRequestSpec) Normalize() {
+	r.Method = strings.ToUpper(strings.TrimSpace(r.Method))
+	if r.Method == "" {
+		r.Method = DefaultRequestMethod
+	}
+	if r.Body != "" && r.ContentType == "" {
+		r.ContentType = DefaultBodyContentType
+	}
+}
+
+// Validate checks that the spec describes a request the worker can send
+func (r *HTTPRequestSpec) Validate() error {
+	method := strings.ToUpper(strings.TrimSpace(r.Method))
+	if method != "" && !allowedRequestMethods[method] {
+		return fmt.Errorf("unsupported method %q", r.Method)
+	}
+
+	if r.Body != "" && (method == http.MethodGet || method == http.MethodHead) {
+		return fmt.Errorf("%s requests cannot have a body", method)
+	}
+
+	for name := range r.Headers {
+		if !isValidHeaderName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if IsCredentialHeader(name) {
+			return fmt.Errorf("header %q carries credentials and would be stored in plaintext; reference a credential with \"credential\" instead", name)
+		}
+	}
+
+	for name := range r.Query {
+		if name == "" {
+			return fmt.Errorf("query parameter names must not be empty")
+		}
+	}
+
+	if _, err := r.parseBodyTemplate(); err != nil {
+		return fmt.Errorf("invalid body template: %w", err)
+	}
+
+	return nil
+}
+
+// Redacted returns a copy of the spec with the values of credential headers replaced, for specs stored
+// before such headers were rejected
+func (r HTTPRequestSpec) Redacted() HTTPRequestSpec {
+	for name := range r.Headers {
+		if IsCredentialHeader(name) {
+			headers := make(map[string]string, len(r.Headers))
+			for name, value := range r.Headers {
+				if IsCredentialHeader(name) {
+					value = RedactedHeaderValue
+				}
+				headers[name] = value
+			}
+			r.Headers = headers
+			break
+		}
+	}
+	return r
+}
+
+// IsCredentialHeader reports whether the header named name carries credentials
+func IsCredentialHeader(name string) bool {
+	return credentialHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// RenderBody executes the body template against the given data
+func (r *HTTPRequestSpec) RenderBody(data RequestTemplateData) ([]byte, error) {
+	if r.Body == "" {
+		return nil, nil
+	}
+
+	tmpl, err := r.parseBodyTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render body template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r *HTTPRequestSpec) parseBodyTemplate() (*template.Template, error) {
+	return template.New("body").Option("missingkey=error").Parse(r.Body)
+}
+
+// Value implements driver.Valuer so the spec is stored as JSON
+func (r HTTPRequestSpec) Value() (driver.Value, error) {
+	return jsonValue(r)
+}
+
+// Scan implements sql.Scanner so the spec can be read back from JSON
+func (r *HTTPRequestSpec) Scan(value interface{}) error {
+	*r = HTTPRequestSpec{}
+	return scanJSON(value, r)
+}
+
+// isValidHeaderName reports whether name is a valid HTTP header token
+func isValidHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryStrategy selects how the delay between retries grows
+type RetryStrategy string
+
+const (
+	RetryFixed       RetryStrategy = "fixed"       // Every retry waits the base delay
+	RetryLinear      RetryStrategy = "linear"      // The nth retry waits n times the base delay
+	RetryExponential RetryStrategy = "exponential" // The nth retry waits the base delay times multiplier^(n-1)
+)
+
+// RetryJitter selects how much of a retry delay is randomized, so failed jobs do not retry in lockstep
+type RetryJitter string
+
+const (
+	JitterNone  RetryJitter = "none"  // Wait the computed delay exactly
+	JitterFull  RetryJitter = "full"  // Wait a random time between zero and the computed delay
+	JitterEqual RetryJitter = "equal" // Wait half the computed delay plus a random time up to the other half
+)
+
+// DefaultRetryPolicy is the exponential backoff applied when neither the job nor the server configures one:
+// 1s, 2s, 4s, 8s... up to 5 minutes.
+var DefaultRetryPolicy = RetryPolicy{
+	Strategy:         RetryExponential,
+	BaseDelaySeconds: 1,
+	Multiplier:       2,
+	MaxDelaySeconds:  300,
+	Jitter:           JitterNone,
+}
+
+// RetryPolicy decides how long a failed job waits before each retry. Fields left empty take the
+// server's defaults.
+type RetryPolicy struct {
+	Strategy         RetryStrategy `json:"strategy,omitempty"`
+	BaseDelaySeconds int           `json:"baseDelaySeconds,omitempty"` // Delay of the first retry
+	Multiplier       float64       `json:"multiplier,omitempty"`       // Growth factor of the exponential strategy
+	MaxDelaySeconds  int           `json:"maxDelaySeconds,omitempty"`  // Upper bound for any delay, applied before jitter
+	Jitter           RetryJitter   `json:"jitter,omitempty"`
+}
+
+// Validate checks that the policy is well formed
+func (rp *RetryPolicy) Validate() error {
+	switch rp.Strategy {
+	case "", RetryFixed, RetryLinear, RetryExponential:
+	default:
+		return fmt.Errorf("unknown retry strategy %q", rp.Strategy)
+	}
+
+	switch rp.Jitter {
+	case "", JitterNone, JitterFull, JitterEqual:
+	default:
+		return fmt.Errorf("unknown retry jitter %q", rp.Jitter)
+	}
+
+	if rp.BaseDelaySeconds < 0 {
+		return fmt.Errorf("baseDelaySeconds cannot be negative")
+	}
+	if rp.MaxDelaySeconds < 0 {
+		return fmt.Errorf("maxDelaySeconds cannot be negative")
+	}
+	if rp.MaxDelaySeconds > 0 && rp.MaxDelaySeconds < rp.BaseDelaySeconds {
+		return fmt.Errorf("maxDelaySeconds cannot be less than baseDelaySeconds")
+	}
+	if rp.Multiplier != 0 && rp.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1")
+	}
+
+	return nil
+}
+
+// WithDefaults returns the policy with its empty fields taken from defaults
+func (rp RetryPolicy) WithDefaults(defaults RetryPolicy) RetryPolicy {
+	if rp.Strategy == "" {
+		rp.Strategy = defaults.Strategy
+	}
+	if rp.BaseDelaySeconds == 0 {
+		rp.BaseDelaySeconds = defaults.BaseDelaySeconds
+	}
+	if rp.Multiplier == 0 {
+		rp.Multiplier = defaults.Multiplier
+	}
+	if rp.MaxDelaySeconds == 0 {
+		rp.MaxDelaySeconds = defaults.MaxDelaySeconds
+	}
+	if rp.Jitter == "" {
+		rp.Jitter = defaults.Jitter
+	}
+	return rp
+}
+
+// Delay returns how long to wait before the given retry, counting from 1
+func (rp RetryPolicy) Delay(retry int) time.Duration {
+	if retry < 1 {
+		retry = 1
+	}
+
+	base := float64(rp.BaseDelaySeconds) * float64(time.Second)
+	var delay float64
+	switch rp.Strategy {
+	case RetryFixed:
+		delay = base
+	case RetryLinear:
+		delay = base * float64(retry)
+	default:
+		multiplier := rp.Multiplier
+		if multiplier < 1 {
+			multiplier = DefaultRetryPolicy.Multiplier
+		}
+		delay = base * math.Pow(multiplier, float64(retry-1))
+	}
+
+	// Bound the delay before converting it, the exponential strategy overflows a Duration quickly
+	if maxDelay := float64(rp.MaxDelaySeconds) * float64(time.Second); rp.MaxDelaySeconds > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	capped := time.Duration(math.MaxInt64)
+	if delay < float64(math.MaxInt64) {
+		capped = time.Duration(delay)
+	}
+	if capped <= 0 {
+		return 0
+	}
+
+	switch rp.Jitter {
+	case JitterFull:
+		return rand.N(capped)
+	case JitterEqual:
+		half := capped / 2
+		return half + rand.N(capped-half)
+	default:
+		return capped
+	}
+}
+
+// Value implements driver.Valuer so the policy is stored as JSON
+func (rp RetryPolicy) Value() (driver.Value, error) {
+	return jsonValue(rp)
+}
+
+// Scan implements sql.Scanner so the policy can be read back from JSON
+func (rp *RetryPolicy) Scan(value interface{}) error {
+	*rp = RetryPolicy{}
+	return scanJSON(value, rp)
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultQueue is the queue of jobs that name neither a queue nor tags
+const DefaultQueue = "default"
+
+// tagQueuePrefix starts the names of the queues jobs are routed to by their tags
+const tagQueuePrefix = "tags:"
+
+var (
+	queueNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	tagPattern       = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+)
+
+// JobTags are the tags a worker must have to run a job
+type JobTags []string
+
+// ValidateQueueName checks that name can name a queue. Priority names are reserved, since they
+// extend queue keys.
+func ValidateQueueName(name string) error {
+	if !queueNamePattern.MatchString(name) {
+		return fmt.Errorf("queue name %q must be up to 64 lowercase letters, digits, '-' or '_'", name)
+	}
+	if JobPriority(name).IsValid() {
+		return fmt.Errorf("queue name %q is reserved", name)
+	}
+	return nil
+}
+
+// Validate checks that every tag is well formed
+func (t JobTags) Validate() error {
+	for _, tag := range t {
+		if !tagPattern.MatchString(tag) {
+			return fmt.Errorf("tag %q must be up to 64 lowercase letters, digits, '-', '_' or '.'", tag)
+		}
+	}
+	return nil
+}
+
+// Normalize returns the tags sorted and without duplicates
+func (t JobTags) Normalize() JobTags {
+	if len(t) == 0 {
+		return nil
+	}
+	tags := slices.Clone(t)
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// QueueName returns the queue of the jobs that require these tags. Jobs with the same tags share a queue.
+func (t JobTags) QueueName() string {
+	return tagQueuePrefix + strings.Join(t.Normalize(), ",")
+}
+
+// TagQueueTags returns the tags a queue named by JobTags.QueueName routes on, or false for other queues
+func TagQueueTags(queue string) (JobTags, bool) {
+	tags, ok := strings.CutPrefix(queue, tagQueuePrefix)
+	if !ok {
+		return nil, false
+	}
+	return JobTags(strings.Split(tags, ",")), true
+}
+
+// Value implements driver.Valuer so the tags are stored as JSON
+func (t JobTags) Value() (driver.Value, error) {
+	if t == nil {
+		return jsonValue([]string{})
+	}
+	return jsonValue([]string(t))
+}
+
+// Scan implements sql.Scanner so the tags can be read back from JSON
+func (t *JobTags) Scan(value interface{}) error {
+	*t = nil
+	return scanJSON(value, (*[]string)(t))
+}
@@ -10,6 +10,10 @@ type JobSchedule struct {
 	ID                uint           `json:"id" gorm:"primaryKey"`
 	JobID             uint           `json:"jobId" gorm:"not null;uniqueIndex;index"`
 	NextExecutionTime time.Time      `json:"nextExecutionTime" gorm:"not null;index"`
+	DispatchedAt      *time.Time     `json:"dispatchedAt,omitempty"`                               // Set once the occurrence at NextExecutionTime is enqueued
+	LeaseOwner        string         `json:"leaseOwner,omitempty" gorm:"size:100"`                 // Scheduler instance holding the claim on the occurrence
+	LeaseExpiresAt    *time.Time     `json:"leaseExpiresAt,omitempty" gorm:"index"`                // The claim lapses after this, e.g. if its owner crashed
+	LeaderTerm        int64          `json:"leaderTerm,omitempty" gorm:"not null;default:0;index"` // Highest leader term that claimed the schedule; earlier leaders cannot claim it
 	CreatedAt         time.Time      `json:"createdAt"`
 	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 }
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SuccessCriteria decides whether a response counts as a successful execution
+// and whether a failed one is worth retrying.
+type SuccessCriteria struct {
+	StatusCodes             []int  `json:"statusCodes,omitempty"`             // Allowed status codes; any 2xx when empty
+	BodyRegex               string `json:"bodyRegex,omitempty"`               // Response body must match
+	BodyJSONPath            string `json:"bodyJsonPath,omitempty"`            // e.g. "$.status" or "$.items[0].state"
+	BodyJSONValue           string `json:"bodyJsonValue,omitempty"`           // Expected value at BodyJSONPath; path must only exist when empty
+	RetryableStatusCodes    []int  `json:"retryableStatusCodes,omitempty"`    // Only these failed status codes are retried when set
+	NonRetryableStatusCodes []int  `json:"nonRetryableStatusCodes,omitempty"` // Failed status codes that are never retried
+}
+
+// HasBodyRules reports whether evaluating the criteria needs the response body
+func (sc *SuccessCriteria) HasBodyRules() bool {
+	return sc.BodyRegex != "" || sc.BodyJSONPath != ""
+}
+
+// Validate checks that the criteria are well formed
+func (sc *SuccessCriteria) Validate() error {
+	for _, codes := range [][]int{sc.StatusCodes, sc.RetryableStatusCodes, sc.NonRetryableStatusCodes} {
+		for _, code := range codes {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("invalid status code %d", code)
+			}
+		}
+	}
+
+	for _, code := range sc.RetryableStatusCodes {
+		if containsStatusCode(sc.NonRetryableStatusCodes, code) {
+			return fmt.Errorf("status code %d cannot be both retryable and non-retryable", code)
+		}
+	}
+
+	if sc.BodyRegex != "" {
+		if _, err := regexp.Compile(sc.BodyRegex); err != nil {
+			return fmt.Errorf("invalid body regex: %w", err)
+		}
+	}
+
+	if sc.BodyJSONPath != "" {
+		if _, err := parseJSONPath(sc.BodyJSONPath); err != nil {
+			return fmt.Errorf("invalid body JSON path: %w", err)
+		}
+	} else if sc.BodyJSONValue != "" {
+		return fmt.Errorf("bodyJsonValue requires bodyJsonPath")
+	}
+
+	return nil
+}
+
+// IsSuccessStatus reports whether the status code is accepted
+func (sc *SuccessCriteria) IsSuccessStatus(statusCode int) bool {
+	if len(sc.StatusCodes) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+	return containsStatusCode(sc.StatusCodes, statusCode)
+}
+
+// MatchBody checks the body rules, returning a reason when the body does not match
+func (sc *SuccessCriteria) MatchBody(body []byte) (bool, string) {
+	if sc.BodyRegex != "" {
+		re, err := regexp.Compile(sc.BodyRegex)
+		if err != nil {
+			return false, fmt.Sprintf("invalid body regex: %v", err)
+		}
+		if !re.Match(body) {
+			return false, fmt.Sprintf("response body does not match %q", sc.BodyRegex)
+		}
+	}
+
+	if sc.BodyJSONPath != "" {
+		path, err := parseJSONPath(sc.BodyJSONPath)
+		if err != nil {
+			return false, fmt.Sprintf("invalid body JSON path: %v", err)
+		}
+
+		var document interface{}
+		if err := json.Unmarshal(body, &document); err != nil {
+			return false, "response body is not valid JSON"
+		}
+
+		value, found := path.lookup(document)
+		if !found {
+			return false, fmt.Sprintf("response body has no value at %s", sc.BodyJSONPath)
+		}
+
+		if sc.BodyJSONValue != "" {
+			actual := jsonScalarString(value)
+			if actual != sc.BodyJSONValue {
+				return false, fmt.Sprintf("response body has %s=%s, expected %s", sc.BodyJSONPath, actual, sc.BodyJSONValue)
+			}
+		}
+	}
+
+	return true, ""
+}
+
+// IsRetryableStatus reports whether a failed response with this status may be retried.
+// A status code of 0 means no response was received and is always retryable.
+func (sc *SuccessCriteria) IsRetryableStatus(statusCode int) bool {
+	if statusCode == 0 {
+		return true
+	}
+	if containsStatusCode(sc.NonRetryableStatusCodes, statusCode) {
+		return false
+	}
+	if len(sc.RetryableStatusCodes) > 0 {
+		return containsStatusCode(sc.RetryableStatusCodes, statusCode)
+	}
+	return true
+}
+
+// Value implements driver.Valuer so the criteria are stored as JSON
+func (sc SuccessCriteria) Value() (driver.Value, error) {
+	return jsonValue(sc)
+}
+
+// Scan implements sql.Scanner so the criteria can be read back from JSON
+func (sc *SuccessCriteria) Scan(value interface{}) error {
+	*sc = SuccessCriteria{}
+	return scanJSON(value, sc)
+}
+
+func containsStatusCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonScalarString formats a decoded JSON value for comparison; strings are compared unquoted
+func jsonScalarString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// jsonPath is a parsed subset of JSONPath: $, .key, ["key"] and [index]
+type jsonPath []interface{}
+
+func parseJSONPath(expr string) (jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("path must start with $")
+	}
+
+	var path jsonPath
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in %q", expr)
+			}
+			path = append(path, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed bracket in %q", expr)
+			}
+			segment := rest[1:end]
+			rest = rest[end+1:]
+			if len(segment) >= 2 && (segment[0] == '"' || segment[0] == '\'') && segment[len(segment)-1] == segment[0] {
+				path = append(path, segment[1:len(segment)-1])
+				continue
+			}
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index %q in %q", segment, expr)
+			}
+			path = append(path, index)
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", rest[0], expr)
+		}
+	}
+
+	return path, nil
+}
+
+// lookup walks the decoded JSON document along the path
+func (p jsonPath) lookup(document interface{}) (interface{}, bool) {
+	current := document
+	for _, segment := range p {
+		switch key := segment.(type) {
+		case string:
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = object[key]; !ok {
+				return nil, false
+			}
+		case int:
+			array, ok := current.([]interface{})
+			if !ok || key >= len(array) {
+				return nil, false
+			}
+			current = array[key]
+		}
+	}
+	return current, true
+}
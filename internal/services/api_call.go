@@ -0,0 +1,312 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/pkg/signature"
+)
+
+// maxResponseBodySnippet caps how much of a response body is stored on the execution
+const maxResponseBodySnippet = 4 * 1024
+
+// maxResponseBodyEvaluate caps how much of a response body is read to evaluate body success rules
+const maxResponseBodyEvaluate = 1024 * 1024
+
+// maxResponseBodyDrain caps how much of the remaining body is read so the connection can be reused
+const maxResponseBodyDrain = 64 * 1024
+
+// redactedResponseHeaders are never stored on the execution
+var redactedResponseHeaders = map[string]bool{
+	"Set-Cookie": true,
+}
+
+// APICallResult describes the outcome of a single outbound call
+type APICallResult struct {
+	Success     bool
+	StatusCode  int
+	Headers     models.ResponseHeaders
+	BodySnippet string
+	Latency     models.ExecutionLatency
+	ErrorClass  models.ExecutionErrorClass
+	Err         error
+}
+
+// applyTo copies the call outcome onto an execution record
+func (r *APICallResult) applyTo(execution *models.JobExecution) {
+	execution.ResponseStatusCode = r.StatusCode
+	execution.ResponseHeaders = r.Headers
+	execution.ResponseBody = r.BodySnippet
+	execution.Latency = r.Latency
+	execution.ErrorClass = r.ErrorClass
+	if r.Err != nil {
+		execution.Error = r.Err.Error()
+	}
+}
+
+// callJobAPI makes HTTP call to the job's API endpoint for an execution; ctx bounds the whole call
+// including the body read
+func (ws *WorkerService) callJobAPI(ctx context.Context, job *models.QueueJob, executionID uint) *APICallResult {
+	result := &APICallResult{}
+
+	req, err := ws.buildJobRequest(ctx, job, executionID)
+	if err != nil {
+		log.Printf("Failed to create request for %s: %v", job.API, err)
+		result.ErrorClass = models.ErrorClassRequest
+		result.Err = fmt.Errorf("failed to build request: %w", err)
+		return result
+	}
+
+	trace := &latencyTrace{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+
+	trace.start = time.Now()
+	resp, err := ws.httpClient.Do(req)
+	result.Latency = trace.latency()
+	if err != nil {
+		log.Printf("Failed to call API %s: %v", job.API, err)
+		result.ErrorClass = classifyCallError(err)
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.Headers = flattenResponseHeaders(resp.Header)
+
+	// A rejected access token may have been revoked; the retry fetches a new one
+	if resp.StatusCode == http.StatusUnauthorized && job.Credential != "" && ws.credentials != nil {
+		ws.credentials.Invalidate(job.Credential)
+	}
+
+	criteria := &job.SuccessCriteria
+	readLimit := int64(maxResponseBodySnippet)
+	if criteria.HasBodyRules() {
+		readLimit = maxResponseBodyEvaluate
+	}
+
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, readLimit))
+	if readErr == nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBodyDrain))
+	}
+
+	snippet := body
+	if len(snippet) > maxResponseBodySnippet {
+		snippet = snippet[:maxResponseBodySnippet]
+	}
+	result.BodySnippet = string(snippet)
+
+	// A truncated body cannot be judged, and a deadline expiring mid-read is a timeout
+	if readErr != nil {
+		log.Printf("Failed to read response body from %s: %v", job.API, readErr)
+		result.ErrorClass = classifyCallError(readErr)
+		result.Err = fmt.Errorf("failed to read response body: %w", readErr)
+		return result
+	}
+
+	if !criteria.IsSuccessStatus(resp.StatusCode) {
+		result.ErrorClass = models.ErrorClassHTTPStatus
+		result.Err = fmt.Errorf("unexpected response status %s", resp.Status)
+		return result
+	}
+
+	if matched, reason := criteria.MatchBody(body); !matched {
+		result.ErrorClass = models.ErrorClassBodyMismatch
+		result.Err = errors.New(reason)
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// jobTimeout returns how long a single attempt of the job may take
+func (ws *WorkerService) jobTimeout(job *models.QueueJob) time.Duration {
+	if job.Timeout > 0 {
+		return time.Duration(job.Timeout) * time.Second
+	}
+	return ws.defaultTimeout
+}
+
+// buildJobRequest builds the outbound HTTP request of an execution from the job's request spec, and
+// signs it
+func (ws *WorkerService) buildJobRequest(ctx context.Context, job *models.QueueJob, executionID uint) (*http.Request, error) {
+	spec := job.Request
+	spec.Normalize()
+
+	body, err := spec.RenderBody(models.RequestTemplateData{
+		JobID:       job.JobID,
+		ScheduledAt: job.ScheduledAt,
+		Attempt:     job.RetryCount + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, spec.Method, job.API, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(spec.Query) > 0 {
+		query := req.URL.Query()
+		for name, value := range spec.Query {
+			query.Set(name, value)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+
+	for name, value := range spec.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if job.Credential != "" {
+		if ws.credentials == nil {
+			return nil, fmt.Errorf("credential %q cannot be used: credentials are not configured", job.Credential)
+		}
+		if err := ws.credentials.Authorize(ctx, req, job.Credential); err != nil {
+			return nil, err
+		}
+	}
+
+	// The target can deduplicate the retries of an occurrence, unless the job sends a key of its own
+	if job.Type == models.AT_LEAST_ONCE && req.Header.Get(models.IdempotencyKeyHeader) == "" {
+		req.Header.Set(models.IdempotencyKeyHeader, job.IdempotencyKey())
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", spec.ContentType)
+	}
+
+	req.Header.Set(signature.HeaderJobID, strconv.FormatUint(uint64(job.JobID), 10))
+	req.Header.Set(signature.HeaderExecutionID, strconv.FormatUint(uint64(executionID), 10))
+	if ws.signer != nil {
+		if err := ws.signer.Sign(req, body, job); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+// classifyCallError maps a transport error to an execution error class
+func classifyCallError(err error) models.ExecutionErrorClass {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return models.ErrorClassTimeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return models.ErrorClassDNS
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return models.ErrorClassConnectionRefused
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &certInvalidErr) {
+		return models.ErrorClassTLS
+	}
+
+	return models.ErrorClassNetwork
+}
+
+// flattenResponseHeaders joins multi-value headers and drops redacted ones
+func flattenResponseHeaders(header http.Header) models.ResponseHeaders {
+	flattened := make(models.ResponseHeaders, len(header))
+	for name, values := range header {
+		if redactedResponseHeaders[name] {
+			continue
+		}
+		flattened[name] = strings.Join(values, ", ")
+	}
+	return flattened
+}
+
+// latencyTrace records connection phase timings via httptrace
+type latencyTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+}
+
+// markStart records the start of a phase, keeping the first one when the dialer races several addresses
+func (lt *latencyTrace) markStart(field *time.Time) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	if field.IsZero() {
+		*field = time.Now()
+	}
+}
+
+// markDone records the end of a phase
+func (lt *latencyTrace) markDone(field *time.Time) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	*field = time.Now()
+}
+
+func (lt *latencyTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { lt.markStart(&lt.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { lt.markDone(&lt.dnsDone) },
+		ConnectStart:         func(string, string) { lt.markStart(&lt.connectStart) },
+		ConnectDone:          func(string, string, error) { lt.markDone(&lt.connectDone) },
+		TLSHandshakeStart:    func() { lt.markStart(&lt.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { lt.markDone(&lt.tlsDone) },
+		GotFirstResponseByte: func() { lt.markDone(&lt.firstByte) },
+	}
+}
+
+func (lt *latencyTrace) latency() models.ExecutionLatency {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	return models.ExecutionLatency{
+		DNSLookup:       phaseDuration(lt.dnsStart, lt.dnsDone),
+		Connect:         phaseDuration(lt.connectStart, lt.connectDone),
+		TLSHandshake:    phaseDuration(lt.tlsStart, lt.tlsDone),
+		TimeToFirstByte: phaseDuration(lt.start, lt.firstByte),
+	}
+}
+
+// phaseDuration returns the elapsed time of a phase, or zero if it did not complete
+func phaseDuration(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
@@ -4,25 +4,37 @@ import (
 	"context"
 	"log"
 	"time"
+
+	"github.com/manyu/job-scheduler/internal/storage"
 )
 
-// BackgroundScheduler runs continuously to process scheduled jobs
+// BackgroundScheduler runs continuously to process scheduled jobs.
+// With a leader elector, only the replica that is leader polls; the others stand by.
+// With a shard coordinator, every replica polls the shards it owns.
 type BackgroundScheduler struct {
 	schedulerService *SchedulerService
+	elector          *LeaderElector    // nil when this is the only scheduler
+	shards           *ShardCoordinator // nil when scanning is not sharded
 	ticker           *time.Ticker
 	ctx              context.Context
 	cancel           context.CancelFunc
 	batchSize        int
 }
 
-// NewBackgroundScheduler creates a new background scheduler
-func NewBackgroundScheduler(schedulerService *SchedulerService) *BackgroundScheduler {
+// NewBackgroundScheduler creates a new background scheduler. elector and shards may be nil
+// to poll every job without coordinating with other replicas.
+func NewBackgroundScheduler(schedulerService *SchedulerService, batchSize int, elector *LeaderElector, shards *ShardCoordinator) *BackgroundScheduler {
 	ctx, cancel := context.WithCancel(context.Background())
+	if batchSize <= 0 {
+		batchSize = 100 // Default batch size
+	}
 	return &BackgroundScheduler{
 		schedulerService: schedulerService,
+		elector:          elector,
+		shards:           shards,
 		ctx:              ctx,
 		cancel:           cancel,
-		batchSize:        100, // Default batch size
+		batchSize:        batchSize,
 	}
 }
 
@@ -31,6 +43,12 @@ func (bs *BackgroundScheduler) Start(interval time.Duration) {
 	bs.ticker = time.NewTicker(interval)
 	log.Printf("Background scheduler started with interval: %v, batch size: %d", interval, bs.batchSize)
 
+	if bs.elector != nil {
+		bs.elector.Start()
+	}
+	if bs.shards != nil {
+		bs.shards.Start()
+	}
 	go bs.pollingLoop()
 }
 
@@ -42,8 +60,29 @@ func (bs *BackgroundScheduler) pollingLoop() {
 			log.Println("Background scheduler stopped")
 			return
 		case <-bs.ticker.C:
+			// Standby replicas wait until they are elected, and the leader's claims carry its term
+			if bs.elector != nil {
+				term := bs.elector.Term()
+				if term == 0 {
+					continue
+				}
+				if err := bs.schedulerService.ProcessReadyJobsAsLeader(bs.ctx, bs.batchSize, term); err != nil {
+					log.Printf("Error processing ready jobs: %v", err)
+				}
+				continue
+			}
+
+			// Sharded replicas only scan the shards they currently own
+			var filter *storage.ShardFilter
+			if bs.shards != nil {
+				filter = bs.shards.Filter()
+				if len(filter.Shards) == 0 {
+					continue
+				}
+			}
+
 			// Process jobs with current batch size
-			err := bs.schedulerService.ProcessReadyJobs(bs.ctx, bs.batchSize)
+			err := bs.schedulerService.ProcessReadyJobsInShards(bs.ctx, bs.batchSize, filter)
 			if err != nil {
 				log.Printf("Error processing ready jobs: %v", err)
 			}
@@ -57,9 +96,20 @@ func (bs *BackgroundScheduler) Stop() {
 		bs.ticker.Stop()
 	}
 	bs.cancel()
+	if bs.elector != nil {
+		bs.elector.Stop()
+	}
+	if bs.shards != nil {
+		bs.shards.Stop()
+	}
 	log.Println("Background scheduler stopped")
 }
 
+// IsLeader reports whether this scheduler is the one polling for due jobs
+func (bs *BackgroundScheduler) IsLeader() bool {
+	return bs.elector == nil || bs.elector.IsLeader()
+}
+
 // IsRunning checks if the scheduler is running
 func (bs *BackgroundScheduler) IsRunning() bool {
 	select {
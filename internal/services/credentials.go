@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+)
+
+// tokenExpiryMargin is how long before its expiry a cached access token is refreshed, so it does not
+// expire in flight
+const tokenExpiryMargin = 30 * time.Second
+
+// defaultTokenLifetime is how long an access token is cached when the token endpoint does not say
+const defaultTokenLifetime = 5 * time.Minute
+
+// maxTokenResponse caps how much of a token endpoint response is read
+const maxTokenResponse = 64 * 1024
+
+// ErrCredentialDecrypt is returned for a secret that was not encrypted with the configured key
+var ErrCredentialDecrypt = errors.New("credential secret cannot be decrypted with the configured key")
+
+// CredentialCipher encrypts the secrets of credentials with AES-256-GCM before they are stored
+type CredentialCipher struct {
+	aead cipher.AEAD
+}
+
+// NewCredentialCipher creates a cipher from a 32-byte key
+func NewCredentialCipher(key []byte) (*CredentialCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("credential encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &CredentialCipher{aead: aead}, nil
+}
+
+// Encrypt returns the secret sealed under a random nonce, which prefixes it
+func (c *CredentialCipher) Encrypt(secret string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, []byte(secret), nil), nil
+}
+
+// Decrypt opens a secret sealed by Encrypt
+func (c *CredentialCipher) Decrypt(sealed []byte) (string, error) {
+	size := c.aead.NonceSize()
+	if len(sealed) < size {
+		return "", ErrCredentialDecrypt
+	}
+	secret, err := c.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return "", ErrCredentialDecrypt
+	}
+	return string(secret), nil
+}
+
+// credentialStore loads the credentials jobs reference
+type credentialStore interface {
+	GetCredentialByName(name string) (*models.Credential, error)
+}
+
+// accessToken is an OAuth2 access token cached for a credential
+type accessToken struct {
+	mu        sync.Mutex // Held while the token is fetched, so workers fetch it once
+	value     string
+	expiresAt time.Time
+	version   time.Time // UpdatedAt of the credential it was fetched with
+}
+
+// CredentialProvider authenticates the calls of jobs with their credentials. OAuth2 access tokens are
+// cached per credential until shortly before they expire, or until the credential changes or a call is
+// rejected with 401. Secrets are only held in memory and never logged.
+type CredentialProvider struct {
+	store      credentialStore
+	cipher     *CredentialCipher // nil when no encryption key is configured
+	httpClient *http.Client
+	tokensMu   sync.Mutex
+	tokens     map[string]*accessToken // By credential name
+}
+
+// NewCredentialProvider creates a provider loading credentials from store and decrypting them with cipher
+func NewCredentialProvider(store credentialStore, cipher *CredentialCipher, httpClient *http.Client) *CredentialProvider {
+	return &CredentialProvider{
+		store:      store,
+		cipher:     cipher,
+		httpClient: httpClient,
+		tokens:     make(map[string]*accessToken),
+	}
+}
+
+// Authorize adds the Authorization header of the named credential to req
+func (p *CredentialProvider) Authorize(ctx context.Context, req *http.Request, name string) error {
+	if p.cipher == nil {
+		return fmt.Errorf("credential %q cannot be used: no credential encryption key is configured", name)
+	}
+	credential, err := p.store.GetCredentialByName(name)
+	if err != nil {
+		return fmt.Errorf("failed to load credential %q: %w", name, err)
+	}
+	secret, err := p.cipher.Decrypt(credential.Secret)
+	if err != nil {
+		return fmt.Errorf("credential %q: %w", name, err)
+	}
+
+	switch credential.Type {
+	case models.CredentialBearer:
+		req.Header.Set("Authorization", "Bearer "+secret)
+	case models.CredentialBasic:
+		req.SetBasicAuth(credential.Username, secret)
+	case models.CredentialOAuth2:
+		token, err := p.accessToken(ctx, credential, secret)
+		if err != nil {
+			return fmt.Errorf("credential %q: %w", name, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		return fmt.Errorf("credential %q has unknown type %q", name, credential.Type)
+	}
+	return nil
+}
+
+// Invalidate drops the cached access token of the named credential, so the next call fetches a new one
+func (p *CredentialProvider) Invalidate(name string) {
+	p.tokensMu.Lock()
+	defer p.tokensMu.Unlock()
+	delete(p.tokens, name)
+}
+
+// accessToken returns the cached access token of an OAuth2 credential, fetching a new one if it is
+// missing, about to expire or was fetched with an older version of the credential
+func (p *CredentialProvider) accessToken(ctx context.Context, credential *models.Credential, clientSecret string) (string, error) {
+	p.tokensMu.Lock()
+	token, ok := p.tokens[credential.Name]
+	if !ok {
+		token = &accessToken{}
+		p.tokens[credential.Name] = token
+	}
+	p.tokensMu.Unlock()
+
+	token.mu.Lock()
+	defer token.mu.Unlock()
+	if token.value != "" && token.version.Equal(credential.UpdatedAt) && time.Now().Add(tokenExpiryMargin).Before(token.expiresAt) {
+		return token.value, nil
+	}
+
+	value, lifetime, err := p.fetchToken(ctx, credential, clientSecret)
+	if err != nil {
+		return "", err
+	}
+	token.value, token.expiresAt, token.version = value, time.Now().Add(lifetime), credential.UpdatedAt
+	return value, nil
+}
+
+// fetchToken requests an access token from the credential's token endpoint with the client
+// credentials grant, authenticating the client with HTTP basic auth
+func (p *CredentialProvider) fetchToken(ctx context.Context, credential *models.Credential, clientSecret string) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if credential.Scopes != "" {
+		form.Set("scope", credential.Scopes)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, credential.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(credential.ClientID), url.QueryEscape(clientSecret))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The response holds the token, so only the standard error code is ever reported
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		Error       string `json:"error"`
+	}
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxTokenResponse)).Decode(&body)
+	if resp.StatusCode != http.StatusOK {
+		if body.Error != "" {
+			return "", 0, fmt.Errorf("token endpoint returned %s: %s", resp.Status, body.Error)
+		}
+		return "", 0, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	if decodeErr != nil || body.AccessToken == "" {
+		return "", 0, fmt.Errorf("token endpoint returned no access token")
+	}
+
+	lifetime := defaultTokenLifetime
+	if body.ExpiresIn > 0 {
+		lifetime = time.Duration(body.ExpiresIn) * time.Second
+	}
+	return body.AccessToken, lifetime, nil
+}
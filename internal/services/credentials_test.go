@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCredentialStore holds credentials by name
+type testCredentialStore map[string]*models.Credential
+
+func (s testCredentialStore) GetCredentialByName(name string) (*models.Credential, error) {
+	if credential, ok := s[name]; ok {
+		return credential, nil
+	}
+	return nil, storage.ErrCredentialNotFound
+}
+
+// newTestCredentialCipher returns a cipher under a fixed key
+func newTestCredentialCipher(t *testing.T) *CredentialCipher {
+	cipher, err := NewCredentialCipher(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+	return cipher
+}
+
+// sealedSecret encrypts secret under cipher
+func sealedSecret(t *testing.T, cipher *CredentialCipher, secret string) []byte {
+	sealed, err := cipher.Encrypt(secret)
+	require.NoError(t, err)
+	return sealed
+}
+
+func TestCredentialCipher(t *testing.T) {
+	cipher := newTestCredentialCipher(t)
+
+	sealed := sealedSecret(t, cipher, "s3cr3t-token")
+	assert.NotContains(t, string(sealed), "s3cr3t-token")
+	assert.NotEqual(t, sealed, sealedSecret(t, cipher, "s3cr3t-token"), "every secret gets its own nonce")
+
+	secret, err := cipher.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-token", secret)
+
+	other, err := NewCredentialCipher(bytes.Repeat([]byte{8}, 32))
+	require.NoError(t, err)
+	_, err = other.Decrypt(sealed)
+	assert.ErrorIs(t, err, ErrCredentialDecrypt)
+
+	_, err = NewCredentialCipher([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestCredentialProvider_StaticCredentials(t *testing.T) {
+	cipher := newTestCredentialCipher(t)
+	provider := NewCredentialProvider(testCredentialStore{
+		"partner-token": {Name: "partner-token", Type: models.CredentialBearer, Secret: sealedSecret(t, cipher, "tok_123")},
+		"legacy-api":    {Name: "legacy-api", Type: models.CredentialBasic, Username: "scheduler", Secret: sealedSecret(t, cipher, "pa55")},
+	}, cipher, http.DefaultClient)
+
+	req := httptest.NewRequest(http.MethodPost, "http://api.example.com/hook", nil)
+	require.NoError(t, provider.Authorize(context.Background(), req, "partner-token"))
+	assert.Equal(t, "Bearer tok_123", req.Header.Get("Authorization"))
+
+	require.NoError(t, provider.Authorize(context.Background(), req, "legacy-api"))
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "scheduler", username)
+	assert.Equal(t, "pa55", password)
+
+	assert.ErrorIs(t, provider.Authorize(context.Background(), req, "missing"), storage.ErrCredentialNotFound)
+
+	// Without the encryption key no credential can be used
+	disabled := NewCredentialProvider(testCredentialStore{}, nil, http.DefaultClient)
+	assert.Error(t, disabled.Authorize(context.Background(), req, "partner-token"))
+}
+
+func TestCredentialProvider_OAuth2CachesAndRefreshesTokens(t *testing.T) {
+	var fetches atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		if clientID != "scheduler" || clientSecret != "client-secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid_client", "error_description": "client-secret is wrong"}`))
+			return
+		}
+		assert.Equal(t, "jobs:write", r.FormValue("scope"))
+		n := fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "access-%d", "token_type": "Bearer", "expires_in": 3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	cipher := newTestCredentialCipher(t)
+	credential := &models.Credential{
+		Name:      "partner-oauth",
+		Type:      models.CredentialOAuth2,
+		ClientID:  "scheduler",
+		TokenURL:  tokenServer.URL,
+		Scopes:    "jobs:write",
+		Secret:    sealedSecret(t, cipher, "client-secret"),
+		UpdatedAt: time.Now(),
+	}
+	provider := NewCredentialProvider(testCredentialStore{credential.Name: credential}, cipher, tokenServer.Client())
+	authorize := func() string {
+		req := httptest.NewRequest(http.MethodPost, "http://api.example.com/hook", nil)
+		require.NoError(t, provider.Authorize(context.Background(), req, credential.Name))
+		return req.Header.Get("Authorization")
+	}
+
+	// The token is fetched once and reused until it is rejected
+	assert.Equal(t, "Bearer access-1", authorize())
+	assert.Equal(t, "Bearer access-1", authorize())
+	provider.Invalidate(credential.Name)
+	assert.Equal(t, "Bearer access-2", authorize())
+
+	// A changed credential fetches a new token
+	credential.UpdatedAt = credential.UpdatedAt.Add(time.Second)
+	assert.Equal(t, "Bearer access-3", authorize())
+
+	// Errors of the token endpoint carry its error code but not the secret
+	credential.Secret = sealedSecret(t, cipher, "wrong-secret")
+	credential.UpdatedAt = credential.UpdatedAt.Add(time.Second)
+	err := provider.Authorize(context.Background(), httptest.NewRequest(http.MethodPost, "http://api.example.com/hook", nil), credential.Name)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_client")
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestWorkerService_CallJobAPI_AuthenticatesWithCredential(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cipher := newTestCredentialCipher(t)
+	ws := newTestWorkerService()
+	ws.credentials = NewCredentialProvider(testCredentialStore{
+		"partner-token": {Name: "partner-token", Type: models.CredentialBearer, Secret: sealedSecret(t, cipher, "tok_123")},
+	}, cipher, http.DefaultClient)
+
+	// The credential replaces an Authorization header of the request spec
+	job := &models.QueueJob{ID: "job_9", JobID: 9, API: server.URL, Credential: "partner-token",
+		Request: models.HTTPRequestSpec{Headers: map[string]string{"Authorization": "Bearer stale"}}}
+	require.True(t, ws.callJobAPI(context.Background(), job, 1).Success)
+	assert.Equal(t, "Bearer tok_123", authorization)
+
+	job.Credential = "missing"
+	result := ws.callJobAPI(context.Background(), job, 2)
+	assert.False(t, result.Success)
+	assert.Equal(t, models.ErrorClassRequest, result.ErrorClass)
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	redisclient "github.com/manyu/job-scheduler/internal/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+// QueueFailedIndex orders the dead letters in QueueFailed by when they failed, in ms
+const QueueFailedIndex = "job_queue:failed:index"
+
+// ErrDeadLetterNotFound is returned for a dead letter that does not exist
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// deadLetter records a job that failed permanently in the dead-letter queue
+func deadLetter(ctx context.Context, client *redis.Client, job *models.QueueJob, errorMsg string) error {
+	letter := models.NewDeadLetter(job, errorMsg)
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to serialize dead letter: %w", err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, QueueFailed, letter.ID, data)
+		pipe.ZAdd(ctx, QueueFailedIndex, redis.Z{Score: float64(letter.FailedAt.UnixMilli()), Member: letter.ID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add job to dead-letter queue: %w", err)
+	}
+
+	log.Printf("Job %s (JobID: %d) moved to dead-letter queue after %d attempts: %s",
+		job.ID, job.JobID, len(letter.Attempts), errorMsg)
+	return nil
+}
+
+// takeDeadLetterScript removes the dead letter ARGV[1] and returns it, or false if it does not exist
+// KEYS: dead letters, dead letter index
+var takeDeadLetterScript = redis.NewScript(`
+local data = redis.call('HGET', KEYS[1], ARGV[1])
+if not data then
+	return false
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+return data
+`)
+
+// DeadLetterQueue gives access to jobs that failed permanently. Replayed jobs are put back on queue.
+type DeadLetterQueue struct {
+	client *redis.Client
+	ctx    context.Context
+	queue  JobQueueServiceInterface
+}
+
+// NewDeadLetterQueue creates a dead-letter queue that replays jobs onto queue
+func NewDeadLetterQueue(redisClient redisclient.RedisClientInterface, queue JobQueueServiceInterface) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		client: redisClient.GetClient(),
+		ctx:    redisClient.GetContext(),
+		queue:  queue,
+	}
+}
+
+// List returns dead letters, most recent first, and how many there are in total
+func (dlq *DeadLetterQueue) List(limit, offset int) ([]*models.DeadLetter, int64, error) {
+	total, err := dlq.client.ZCard(dlq.ctx, QueueFailedIndex).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+
+	ids, err := dlq.client.ZRevRange(dlq.ctx, QueueFailedIndex, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]*models.DeadLetter, 0, len(ids))
+	if len(ids) == 0 {
+		return letters, total, nil
+	}
+
+	values, err := dlq.client.HMGet(dlq.ctx, QueueFailed, ids...).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get dead letters: %w", err)
+	}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // Replayed or deleted between the two reads
+		}
+		letter, err := parseDeadLetter(data)
+		if err != nil {
+			log.Printf("Warning: failed to deserialize dead letter %s: %v", ids[i], err)
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	return letters, total, nil
+}
+
+// Get returns one dead letter
+func (dlq *DeadLetterQueue) Get(id string) (*models.DeadLetter, error) {
+	data, err := dlq.client.HGet(dlq.ctx, QueueFailed, id).Result()
+	if err == redis.Nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter %s: %w", id, err)
+	}
+	return parseDeadLetter(data)
+}
+
+// Replay takes a dead letter off the queue and enqueues its job again under a new ID with its
+// retries reset. It returns the new queue job.
+func (dlq *DeadLetterQueue) Replay(id string) (*models.QueueJob, error) {
+	data, err := takeDeadLetterScript.Run(dlq.ctx, dlq.client, []string{QueueFailed, QueueFailedIndex}, id).Text()
+	if err == redis.Nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take dead letter %s: %w", id, err)
+	}
+
+	letter, err := parseDeadLetter(data)
+	if err != nil {
+		return nil, err
+	}
+
+	job := letter.Job.Replay()
+	if err := dlq.queue.EnqueueJob(job); err != nil {
+		// Put the dead letter back so the replay can be attempted again
+		_, restoreErr := dlq.client.TxPipelined(dlq.ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(dlq.ctx, QueueFailed, id, data)
+			pipe.ZAdd(dlq.ctx, QueueFailedIndex, redis.Z{Score: float64(letter.FailedAt.UnixMilli()), Member: id})
+			return nil
+		})
+		if restoreErr != nil {
+			log.Printf("Failed to restore dead letter %s after a failed replay: %v", id, restoreErr)
+		}
+		return nil, fmt.Errorf("failed to replay dead letter %s: %w", id, err)
+	}
+
+	log.Printf("Replayed dead letter %s (JobID: %d) as %s", id, job.JobID, job.ID)
+	return job, nil
+}
+
+// ReplayAll replays every dead letter and returns how many were replayed
+func (dlq *DeadLetterQueue) ReplayAll() (int, error) {
+	ids, err := dlq.client.ZRange(dlq.ctx, QueueFailedIndex, 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	replayed := 0
+	for _, id := range ids {
+		_, err := dlq.Replay(id)
+		if err == ErrDeadLetterNotFound {
+			continue // Replayed or deleted concurrently
+		}
+		if err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// Delete removes one dead letter without replaying it
+func (dlq *DeadLetterQueue) Delete(id string) error {
+	err := takeDeadLetterScript.Run(dlq.ctx, dlq.client, []string{QueueFailed, QueueFailedIndex}, id).Err()
+	if err == redis.Nil {
+		return ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter %s: %w", id, err)
+	}
+	return nil
+}
+
+// Purge removes every dead letter and returns how many there were
+func (dlq *DeadLetterQueue) Purge() (int64, error) {
+	var count *redis.IntCmd
+	_, err := dlq.client.TxPipelined(dlq.ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.HLen(dlq.ctx, QueueFailed)
+		pipe.Del(dlq.ctx, QueueFailed, QueueFailedIndex)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+	}
+	log.Printf("Purged %d dead letters", count.Val())
+	return count.Val(), nil
+}
+
+// countDeadLetters returns how many dead letters there are
+func countDeadLetters(ctx context.Context, client *redis.Client) (int64, error) {
+	count, err := client.HLen(ctx, QueueFailed).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get dead-letter queue length: %w", err)
+	}
+	return count, nil
+}
+
+func parseDeadLetter(data string) (*models.DeadLetter, error) {
+	var letter models.DeadLetter
+	if err := json.Unmarshal([]byte(data), &letter); err != nil {
+		return nil, fmt.Errorf("failed to deserialize dead letter: %w", err)
+	}
+	if letter.Job == nil {
+		return nil, fmt.Errorf("dead letter %s has no job", letter.ID)
+	}
+	return &letter, nil
+}
@@ -11,32 +11,207 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-// JobQueueService handles job queuing operations using Redis
+// JobQueueService handles job queuing operations using Redis.
+// Dequeued jobs stay in flight, in a processing list of the worker that took them, until the
+// worker acknowledges them. Each in-flight job has a visibility deadline that the worker keeps
+// extending while it is alive; jobs whose deadline passes are returned to the ready queue or failed by ReapJob.
 type JobQueueService struct {
 	redisClient redisclient.RedisClientInterface
 	client      *redis.Client
 	ctx         context.Context
+	workerID    string        // Names this consumer's processing list
+	lease       time.Duration // How far each extension moves the visibility deadline of a job
+
+	retryDefaults models.RetryPolicy // Fills in the retry policies of failed jobs
+	priorities    *PriorityScheduler // Order the ready queue of each priority is dequeued in
+	subscription  QueueSubscription  // Queues this worker takes jobs from
+	directory     *queueDirectory
 }
 
 // Queue names
 const (
-	QueueReady      = "job_queue:ready"
-	QueueProcessing = "job_queue:processing"
+	QueueReady      = "job_queue:ready"      // Default queue, normal priority; followed by ":<queue>" for other queues and ":<priority>" for other priorities
+	QueueProcessing = "job_queue:processing" // Followed by ":<worker ID>", one list per worker
 	QueueCompleted  = "job_queue:completed"
-	QueueFailed     = "job_queue:failed"
+	QueueFailed     = "job_queue:failed" // Hash of dead-lettered job ID to its DeadLetter
 	QueueRetrying   = "job_queue:retrying"
+
+	QueueInFlight        = "job_queue:inflight"         // Sorted set of in-flight job IDs scored by visibility deadline in ms
+	QueueInFlightData    = "job_queue:inflight:data"    // Hash of in-flight job ID to job data
+	QueueInFlightWorkers = "job_queue:inflight:workers" // Hash of in-flight job ID to the worker processing it
 )
 
+// inFlightLease is how long a dequeued job stays reserved for its worker unless the worker extends it.
+// Workers extend the leases of their jobs every third of this, so a job outlives its worker by at most this long.
+const inFlightLease = 30 * time.Second
+
+// dequeuePollInterval is how often DequeueJob checks an empty ready queue
+const dequeuePollInterval = 100 * time.Millisecond
+
+// reapBatchSize bounds how many expired jobs one ExpiredJobs call returns
+const reapBatchSize = 100
+
 // NewJobQueueService creates a new job queue service
 func NewJobQueueService(redisClient redisclient.RedisClientInterface) *JobQueueService {
 	return &JobQueueService{
 		redisClient: redisClient,
 		client:      redisClient.GetClient(),
 		ctx:         redisClient.GetContext(),
+		workerID:    newInstanceID(),
+		lease:       inFlightLease,
+
+		retryDefaults: models.DefaultRetryPolicy,
+		priorities:    defaultPriorityScheduler(),
+		subscription:  defaultSubscription(),
+		directory:     newQueueDirectory(redisClient.GetClient(), redisClient.GetContext()),
+	}
+}
+
+// Queue backends
+const (
+	QueueBackendList   = "list"   // JobQueueService
+	QueueBackendStream = "stream" // StreamJobQueueService
+)
+
+// QueueOptions configures the job queue created by NewQueue
+type QueueOptions struct {
+	Backend       string             // QueueBackendList or QueueBackendStream; empty selects the list backend
+	RetryDefaults models.RetryPolicy // Fills in the retry policies of failed jobs
+	Priorities    *PriorityScheduler // Order the ready queue of each priority is dequeued in; nil is weighted by default weights
+	Subscription  *QueueSubscription // Queues the worker takes jobs from; nil is the default queue
+}
+
+// NewQueue creates the job queue of the backend in options
+func NewQueue(redisClient redisclient.RedisClientInterface, options QueueOptions) (WorkerQueueInterface, error) {
+	priorities := options.Priorities
+	if priorities == nil {
+		priorities = defaultPriorityScheduler()
+	}
+	subscription := defaultSubscription()
+	if options.Subscription != nil {
+		subscription = *options.Subscription
+	}
+
+	switch options.Backend {
+	case QueueBackendList, "":
+		queue := NewJobQueueService(redisClient)
+		queue.retryDefaults = options.RetryDefaults
+		queue.priorities = priorities
+		queue.subscription = subscription
+		return queue, nil
+	case QueueBackendStream:
+		queue, err := NewStreamJobQueueService(redisClient, inFlightLease)
+		if err != nil {
+			return nil, err
+		}
+		queue.retryDefaults = options.RetryDefaults
+		queue.priorities = priorities
+		queue.subscription = subscription
+		return queue, nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", options.Backend)
 	}
 }
 
-// EnqueueJob adds a job to the ready queue
+// WorkerID returns the ID this service consumes jobs as
+func (jqs *JobQueueService) WorkerID() string {
+	return jqs.workerID
+}
+
+// Subscription returns the queues this worker takes jobs from
+func (jqs *JobQueueService) Subscription() QueueSubscription {
+	return jqs.subscription
+}
+
+// readyList returns the ready list of a queue and priority
+func readyList(queue string, priority models.JobPriority) string {
+	return queueKey(QueueReady, queue, priority)
+}
+
+// processingList returns the processing list of a worker
+func processingList(workerID string) string {
+	return QueueProcessing + ":" + workerID
+}
+
+// dequeueScript moves the oldest job of the first non-empty ready queue onto the worker's processing list
+// and records its visibility deadline
+// KEYS: processing list, in-flight set, in-flight data, in-flight workers, ready queues in the order to try them
+// ARGV: worker ID, deadline in ms
+var dequeueScript = redis.NewScript(`
+local data
+for i = 5, #KEYS do
+	data = redis.call('LMOVE', KEYS[i], KEYS[1], 'RIGHT', 'LEFT')
+	if data then
+		break
+	end
+end
+if not data then
+	return false
+end
+local id = cjson.decode(data)['id']
+redis.call('ZADD', KEYS[2], ARGV[2], id)
+redis.call('HSET', KEYS[3], id, data)
+redis.call('HSET', KEYS[4], id, ARGV[1])
+return data
+`)
+
+// extendScript moves the visibility deadline of every job on the worker's processing list that the
+// worker still holds to ARGV[2] and returns how many it extended. Jobs reaped in the meantime are left alone.
+// KEYS: processing list, in-flight set, in-flight workers
+// ARGV: worker ID, deadline in ms
+var extendScript = redis.NewScript(`
+local extended = 0
+for _, data in ipairs(redis.call('LRANGE', KEYS[1], 0, -1)) do
+	local id = cjson.decode(data)['id']
+	if redis.call('HGET', KEYS[3], id) == ARGV[1] then
+		redis.call('ZADD', KEYS[2], 'XX', ARGV[2], id)
+		extended = extended + 1
+	end
+end
+return extended
+`)
+
+// releaseScript takes a job out of flight if ARGV[2] is still processing it. When ARGV[3] is "requeue"
+// it puts the job back at the head of its ready queue, KEYS[5], and when it is "defer" it adds the job to
+// the retry queue, KEYS[5], due at ARGV[5]. With ARGV[4] set, the job is only released if its visibility
+// deadline is before ARGV[4] in ms. Returns the job data, or false if nothing was released.
+// KEYS: in-flight set, in-flight data, in-flight workers, processing list, ready or retry queue
+var releaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then
+	return false
+end
+if ARGV[4] ~= '' then
+	local deadline = redis.call('ZSCORE', KEYS[1], ARGV[1])
+	if not deadline or tonumber(deadline) >= tonumber(ARGV[4]) then
+		return false
+	end
+end
+local data = redis.call('HGET', KEYS[2], ARGV[1])
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+if not data then
+	return false
+end
+redis.call('LREM', KEYS[4], 1, data)
+if ARGV[3] == 'requeue' then
+	redis.call('RPUSH', KEYS[5], data)
+elseif ARGV[3] == 'defer' then
+	redis.call('ZADD', KEYS[5], ARGV[5], data)
+end
+return data
+`)
+
+// promoteScript moves a job held in the retry queue (KEYS[1]) to the head of its ready list (KEYS[2]),
+// unless the retry queue already released it
+var promoteScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+	redis.call('RPUSH', KEYS[2], ARGV[1])
+end
+return 0
+`)
+
+// EnqueueJob adds a job to the ready list of its queue and priority
 func (jqs *JobQueueService) EnqueueJob(job *models.QueueJob) error {
 	// Serialize the job
 	jobData, err := job.Serialize()
@@ -45,156 +220,312 @@ func (jqs *JobQueueService) EnqueueJob(job *models.QueueJob) error {
 	}
 
 	// Add to ready queue
-	if err := jqs.client.LPush(jqs.ctx, QueueReady, jobData).Err(); err != nil {
+	_, err = jqs.client.TxPipelined(jqs.ctx, func(pipe redis.Pipeliner) error {
+		jqs.directory.register(pipe, job.QueueName())
+		pipe.LPush(jqs.ctx, readyList(job.QueueName(), job.Priority), jobData)
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to enqueue job: %w", err)
 	}
 
-	log.Printf("Enqueued job %s (JobID: %d) to ready queue", job.ID, job.JobID)
+	log.Printf("Enqueued job %s (JobID: %d) to queue %s with %s priority", job.ID, job.JobID, job.QueueName(), job.Priority.OrNormal())
 	return nil
 }
 
-// DequeueJob removes and returns a job from the ready queue
+// DequeueJob atomically moves the oldest job of a ready list onto this worker's processing list,
+// waiting up to timeout for one. Only the queues the worker subscribes to are read; their ready lists
+// are tried by priority in the order the priority scheduler picks.
+// The job stays in flight until it is completed, discarded, failed or released, or until its
+// visibility deadline passes without the worker extending it.
 func (jqs *JobQueueService) DequeueJob(timeout time.Duration) (*models.QueueJob, error) {
-	// Block until a job is available or timeout
-	result, err := jqs.client.BRPop(jqs.ctx, timeout, QueueReady).Result()
-	if err != nil {
-		if err == redis.Nil {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		queues, err := jqs.directory.subscribed(jqs.subscription)
+		if err != nil {
+			return nil, err
+		}
+		keys := append([]string{processingList(jqs.workerID), QueueInFlight, QueueInFlightData, QueueInFlightWorkers},
+			dequeueOrder(jqs.priorities.Order(), queues, readyList)...)
+		data, err := dequeueScript.Run(jqs.ctx, jqs.client, keys,
+			jqs.workerID, time.Now().Add(jqs.lease).UnixMilli()).Text()
+		if err == nil {
+			job, err := models.DeserializeQueueJob([]byte(data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to deserialize job: %w", err)
+			}
+			return job, nil
+		}
+		if err != redis.Nil {
+			return nil, fmt.Errorf("failed to dequeue job: %w", err)
+		}
+
+		// Lua cannot block, so an empty queue is polled until the timeout
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
 			return nil, nil // No job available
 		}
-		return nil, fmt.Errorf("failed to dequeue job: %w", err)
+		time.Sleep(min(remaining, dequeuePollInterval))
+	}
+}
+
+// release runs releaseScript for a job processed by workerID. A requeued job goes back to readyKey.
+func (jqs *JobQueueService) release(jobID, workerID, readyKey string, requeue bool, expiredBefore *time.Time) (string, error) {
+	mode, before := "ack", ""
+	if requeue {
+		mode = "requeue"
+	}
+	if expiredBefore != nil {
+		before = fmt.Sprintf("%d", expiredBefore.UnixMilli())
 	}
 
-	if len(result) < 2 {
-		return nil, fmt.Errorf("invalid result from Redis BRPop")
+	keys := []string{QueueInFlight, QueueInFlightData, QueueInFlightWorkers, processingList(workerID), readyKey}
+	data, err := releaseScript.Run(jqs.ctx, jqs.client, keys, jobID, workerID, mode, before, "").Text()
+	if err == redis.Nil {
+		return "", nil
 	}
+	return data, err
+}
 
-	// Deserialize the job
-	job, err := models.DeserializeQueueJob([]byte(result[1]))
-	if err != nil {
-		return nil, fmt.Errorf("failed to deserialize job: %w", err)
+// acknowledge takes a job this worker is done with out of flight
+func (jqs *JobQueueService) acknowledge(jobID string) {
+	if _, err := jqs.release(jobID, jqs.workerID, QueueReady, false, nil); err != nil {
+		log.Printf("Warning: failed to remove job %s from processing queue: %v", jobID, err)
 	}
+}
+
+// RequeueJob puts a job this worker dequeued but did not start back at the head of its ready queue
+func (jqs *JobQueueService) RequeueJob(job *models.QueueJob) error {
+	if _, err := jqs.release(job.ID, jqs.workerID, readyList(job.QueueName(), job.Priority), true, nil); err != nil {
+		return fmt.Errorf("failed to requeue job %s: %w", job.ID, err)
+	}
+	return nil
+}
 
-	// Move to processing queue
-	if err := jqs.MoveToProcessing(job); err != nil {
-		log.Printf("Warning: failed to move job %s to processing queue: %v", job.ID, err)
+// DeferJob takes a job this worker dequeued but did not start out of flight and adds it to the
+// retry queue, due after delay, without counting an attempt
+func (jqs *JobQueueService) DeferJob(job *models.QueueJob, delay time.Duration) error {
+	keys := []string{QueueInFlight, QueueInFlightData, QueueInFlightWorkers, processingList(jqs.workerID), QueueRetrying}
+	err := releaseScript.Run(jqs.ctx, jqs.client, keys, job.ID, jqs.workerID, "defer", "", deferredScore(delay)).Err()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to defer job %s: %w", job.ID, err)
 	}
+	return nil
+}
 
-	return job, nil
+// ExtendLeases moves the visibility deadline of every job this worker holds to a lease from now
+func (jqs *JobQueueService) ExtendLeases() error {
+	keys := []string{processingList(jqs.workerID), QueueInFlight, QueueInFlightWorkers}
+	if err := extendScript.Run(jqs.ctx, jqs.client, keys, jqs.workerID, time.Now().Add(jqs.lease).UnixMilli()).Err(); err != nil {
+		return fmt.Errorf("failed to extend job leases: %w", err)
+	}
+	return nil
 }
 
-// MoveToProcessing moves a job from ready to processing queue
-func (jqs *JobQueueService) MoveToProcessing(job *models.QueueJob) error {
-	jobData, err := job.Serialize()
+// ExpiredJobs returns in-flight jobs whose visibility deadline has passed, i.e. whose worker died or hung
+func (jqs *JobQueueService) ExpiredJobs() ([]*models.QueueJob, error) {
+	jobIDs, err := jqs.client.ZRangeByScore(jqs.ctx, QueueInFlight, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprintf("(%d", time.Now().UnixMilli()),
+		Count: reapBatchSize,
+	}).Result()
 	if err != nil {
-		return fmt.Errorf("failed to serialize job: %w", err)
+		return nil, fmt.Errorf("failed to get expired jobs: %w", err)
 	}
-
-	// Add to processing set with job ID as member
-	if err := jqs.client.SAdd(jqs.ctx, QueueProcessing, job.ID).Err(); err != nil {
-		return fmt.Errorf("failed to add job to processing queue: %w", err)
+	if len(jobIDs) == 0 {
+		return nil, nil
 	}
 
-	// Store job data with TTL (e.g., 6 hours for processing long-running jobs)
-	if err := jqs.client.Set(jqs.ctx, fmt.Sprintf("job_data:%s", job.ID), jobData, 6*time.Hour).Err(); err != nil {
-		return fmt.Errorf("failed to store job data: %w", err)
+	payloads, err := jqs.client.HMGet(jqs.ctx, QueueInFlightData, jobIDs...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired job data: %w", err)
 	}
 
-	return nil
+	jobs := make([]*models.QueueJob, 0, len(payloads))
+	for i, payload := range payloads {
+		data, ok := payload.(string)
+		if !ok {
+			continue
+		}
+		job, err := models.DeserializeQueueJob([]byte(data))
+		if err != nil {
+			log.Printf("Warning: failed to deserialize expired job %s: %v", jobIDs[i], err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
 }
 
-// CompleteJob marks a job as completed and removes it from processing
-func (jqs *JobQueueService) CompleteJob(jobID string, result *models.QueueJobResult) error {
-	// Remove from processing queue
-	if err := jqs.client.SRem(jqs.ctx, QueueProcessing, jobID).Err(); err != nil {
-		log.Printf("Warning: failed to remove job %s from processing queue: %v", jobID, err)
+// ReapJob takes an expired job out of flight. AT_LEAST_ONCE jobs go back to the head of their ready
+// queue to be delivered again; AT_MOST_ONCE jobs may already have run, so they are dead-lettered
+// instead. It reports whether the job was reaped and whether it was requeued; a job whose worker
+// acknowledged it or whose deadline was extended in the meantime is left alone.
+func (jqs *JobQueueService) ReapJob(job *models.QueueJob, onReaped func()) (bool, bool, error) {
+	workerID, err := jqs.client.HGet(jqs.ctx, QueueInFlightWorkers, job.ID).Result()
+	if err == redis.Nil {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get worker of job %s: %w", job.ID, err)
 	}
 
-	// Remove job data
-	if err := jqs.client.Del(jqs.ctx, fmt.Sprintf("job_data:%s", jobID)).Err(); err != nil {
-		log.Printf("Warning: failed to remove job data for %s: %v", jobID, err)
+	if job.Type == models.AT_LEAST_ONCE {
+		return jqs.reapAndRequeue(job, workerID, onReaped)
 	}
 
-	// Add to completed queue
-	resultData, err := result.Serialize()
+	now := time.Now()
+	data, err := jqs.release(job.ID, workerID, QueueReady, false, &now)
 	if err != nil {
-		return fmt.Errorf("failed to serialize job result: %w", err)
+		return false, false, fmt.Errorf("failed to reap job %s: %w", job.ID, err)
+	}
+	if data == "" {
+		return false, false, nil
 	}
+	onReaped()
 
-	if err := jqs.client.LPush(jqs.ctx, QueueCompleted, resultData).Err(); err != nil {
-		return fmt.Errorf("failed to add job result to completed queue: %w", err)
+	errorMsg := fmt.Sprintf("worker %s did not finish the job before its visibility deadline", workerID)
+	job.RecordFailure(errorMsg)
+	if err := deadLetter(jqs.ctx, jqs.client, job, errorMsg); err != nil {
+		return true, false, err
 	}
+	log.Printf("AT_MOST_ONCE job %s (JobID: %d) passed its visibility deadline on worker %s, not requeued", job.ID, job.JobID, workerID)
+	return true, false, nil
+}
 
-	// Keep only last 1000 completed jobs
-	if err := jqs.client.LTrim(jqs.ctx, QueueCompleted, 0, 999).Err(); err != nil {
-		log.Printf("Warning: failed to trim completed queue: %v", err)
+// reapAndRequeue takes an expired job from workerID and puts it back at the head of its ready list.
+// The job is held in the retry queue while onReaped runs, so it is not delivered again before that, and
+// is still delivered after inFlightLease should this worker die in between.
+func (jqs *JobQueueService) reapAndRequeue(job *models.QueueJob, workerID string, onReaped func()) (bool, bool, error) {
+	keys := []string{QueueInFlight, QueueInFlightData, QueueInFlightWorkers, processingList(workerID), QueueRetrying}
+	before := fmt.Sprintf("%d", time.Now().UnixMilli())
+	data, err := releaseScript.Run(jqs.ctx, jqs.client, keys, job.ID, workerID, "defer", before, deferredScore(inFlightLease)).Text()
+	if err == redis.Nil {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to reap job %s: %w", job.ID, err)
+	}
+	onReaped()
+
+	if err := promoteScript.Run(jqs.ctx, jqs.client, []string{QueueRetrying, readyList(job.QueueName(), job.Priority)}, data).Err(); err != nil {
+		log.Printf("Warning: failed to requeue reaped job %s, it is retried after %v: %v", job.ID, inFlightLease, err)
+	}
+	log.Printf("Job %s (JobID: %d) passed its visibility deadline on worker %s, requeued", job.ID, job.JobID, workerID)
+	return true, true, nil
+}
+
+// CompleteJob marks a job as completed and removes it from processing
+func (jqs *JobQueueService) CompleteJob(jobID string, result *models.QueueJobResult) error {
+	// Remove from processing queue
+	jqs.acknowledge(jobID)
+
+	if err := recordResult(jqs.ctx, jqs.client, result); err != nil {
+		return err
 	}
 
 	log.Printf("Completed job %s with status %s", jobID, result.Status)
 	return nil
 }
 
-// FailJob marks a job as failed and handles retry logic
+// DiscardJob removes a job from processing without recording a result
+func (jqs *JobQueueService) DiscardJob(jobID string) {
+	jqs.acknowledge(jobID)
+}
+
+// FailJob records a failed attempt and schedules a retry or dead-letters the job
 func (jqs *JobQueueService) FailJob(job *models.QueueJob, errorMsg string) error {
 	// Remove from processing queue
-	if err := jqs.client.SRem(jqs.ctx, QueueProcessing, job.ID).Err(); err != nil {
-		log.Printf("Warning: failed to remove job %s from processing queue: %v", job.ID, err)
-	}
+	jqs.acknowledge(job.ID)
 
-	// Remove job data
-	if err := jqs.client.Del(jqs.ctx, fmt.Sprintf("job_data:%s", job.ID)).Err(); err != nil {
-		log.Printf("Warning: failed to remove job data for %s: %v", job.ID, err)
+	// Record the attempt and check if job should be retried
+	job.RecordFailure(errorMsg)
+	if job.ShouldRetry() {
+		return scheduleRetry(jqs.ctx, jqs.client, job, jqs.retryDefaults)
 	}
 
-	// Check if job should be retried
-	if job.ShouldRetry() {
-		// Increment retry count and schedule retry
-		retryJob := job.IncrementRetry()
-		retryDelay := retryJob.CalculateRetryDelay()
+	// Max retries exceeded, keep the job in the dead-letter queue
+	return deadLetter(jqs.ctx, jqs.client, job, errorMsg)
+}
 
-		// Schedule retry using Redis delayed execution
-		retryTime := time.Now().Add(retryDelay)
-		retryJobData, err := retryJob.Serialize()
-		if err != nil {
-			return fmt.Errorf("failed to serialize retry job: %w", err)
-		}
+// ProcessRetryQueue moves ready retry jobs back to the ready queue
+func (jqs *JobQueueService) ProcessRetryQueue() error {
+	return moveDueRetries(jqs.ctx, jqs.client, jqs.EnqueueJob)
+}
 
-		// Use Redis sorted set for delayed execution
-		score := float64(retryTime.Unix())
-		if err := jqs.client.ZAdd(jqs.ctx, QueueRetrying, redis.Z{
-			Score:  score,
-			Member: retryJobData,
-		}).Err(); err != nil {
-			return fmt.Errorf("failed to schedule retry: %w", err)
+// addReadyStats adds the ready totals per priority and per queue of queues to stats
+func addReadyStats(stats map[string]int64, queues []*QueueInfo) {
+	for _, priority := range models.Priorities {
+		stats["ready:"+string(priority)] = 0
+	}
+	for _, queue := range queues {
+		stats["ready"] += queue.Ready
+		stats["queue:"+queue.Name] = queue.Ready
+		for priority, count := range queue.ReadyByPriority {
+			stats["ready:"+string(priority)] += count
 		}
+	}
+}
 
-		log.Printf("Scheduled retry %d/%d for job %s in %v",
-			retryJob.RetryCount, retryJob.MaxRetryCount, job.ID, retryDelay)
-	} else {
-		// Max retries exceeded, mark as permanently failed
-		result := &models.QueueJobResult{
-			JobID:      job.ID,
-			Status:     models.QueueStatusFailed,
-			Success:    false,
-			Error:      errorMsg,
-			RetryCount: job.RetryCount,
-		}
+// recordResult adds a job result to the completed queue, keeping the most recent 1000
+func recordResult(ctx context.Context, client *redis.Client, result *models.QueueJobResult) error {
+	resultData, err := result.Serialize()
+	if err != nil {
+		return fmt.Errorf("failed to serialize job result: %w", err)
+	}
 
-		if err := jqs.CompleteJob(job.ID, result); err != nil {
-			return fmt.Errorf("failed to mark job as permanently failed: %w", err)
-		}
+	if err := client.LPush(ctx, QueueCompleted, resultData).Err(); err != nil {
+		return fmt.Errorf("failed to add job result to completed queue: %w", err)
+	}
 
-		log.Printf("Job %s permanently failed after %d retries", job.ID, job.RetryCount)
+	// Keep only last 1000 completed jobs
+	if err := client.LTrim(ctx, QueueCompleted, 0, 999).Err(); err != nil {
+		log.Printf("Warning: failed to trim completed queue: %v", err)
 	}
+	return nil
+}
 
+// scheduleRetry adds the next attempt of a failed job to the retry queue, due after the delay its retry
+// policy gives, with empty fields of the policy taken from defaults
+func scheduleRetry(ctx context.Context, client *redis.Client, job *models.QueueJob, defaults models.RetryPolicy) error {
+	// Increment retry count and schedule retry
+	retryJob := job.IncrementRetry()
+	retryDelay := retryJob.RetryDelay(defaults)
+
+	// Schedule retry using Redis delayed execution
+	retryTime := time.Now().Add(retryDelay)
+	retryJobData, err := retryJob.Serialize()
+	if err != nil {
+		return fmt.Errorf("failed to serialize retry job: %w", err)
+	}
+
+	// Use Redis sorted set for delayed execution
+	score := float64(retryTime.Unix())
+	if err := client.ZAdd(ctx, QueueRetrying, redis.Z{
+		Score:  score,
+		Member: retryJobData,
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+
+	log.Printf("Scheduled retry %d/%d for job %s in %v",
+		retryJob.RetryCount, retryJob.MaxRetryCount, job.ID, retryDelay)
 	return nil
 }
 
-// ProcessRetryQueue moves ready retry jobs back to the ready queue
-func (jqs *JobQueueService) ProcessRetryQueue() error {
+// deferredScore returns the retry queue score of a job due after delay. Scores count whole seconds,
+// so the delay is rounded up.
+func deferredScore(delay time.Duration) float64 {
+	return float64(time.Now().Add(delay + time.Second - 1).Unix())
+}
+
+// moveDueRetries takes the retry jobs that are due off the retry queue and hands them to enqueue
+func moveDueRetries(ctx context.Context, client *redis.Client, enqueue func(*models.QueueJob) error) error {
 	now := time.Now().Unix()
 
 	// Get jobs that are ready for retry
-	jobs, err := jqs.client.ZRangeByScore(jqs.ctx, QueueRetrying, &redis.ZRangeBy{
+	jobs, err := client.ZRangeByScore(ctx, QueueRetrying, &redis.ZRangeBy{
 		Min: "0",
 		Max: fmt.Sprintf("%d", now),
 	}).Result()
@@ -208,6 +539,7 @@ func (jqs *JobQueueService) ProcessRetryQueue() error {
 	}
 
 	// Move jobs back to ready queue
+	moved := 0
 	for _, jobData := range jobs {
 		job, err := models.DeserializeQueueJob([]byte(jobData))
 		if err != nil {
@@ -215,36 +547,69 @@ func (jqs *JobQueueService) ProcessRetryQueue() error {
 			continue
 		}
 
-		// Remove from retry queue
-		if err := jqs.client.ZRem(jqs.ctx, QueueRetrying, jobData).Err(); err != nil {
+		// Every worker and scheduler drains the retry queue, so only the one that removes a job enqueues it
+		removed, err := client.ZRem(ctx, QueueRetrying, jobData).Result()
+		if err != nil {
 			log.Printf("Warning: failed to remove job from retry queue: %v", err)
+			continue
+		}
+		if removed == 0 {
+			continue
 		}
 
-		// Add back to ready queue
-		if err := jqs.EnqueueJob(job); err != nil {
+		// Add back to ready queue, or return it to the retry queue for the next drain
+		if err := enqueue(job); err != nil {
 			log.Printf("Warning: failed to re-enqueue retry job: %v", err)
+			if err := client.ZAdd(ctx, QueueRetrying, redis.Z{Score: float64(now), Member: jobData}).Err(); err != nil {
+				log.Printf("Warning: failed to return job %s to retry queue: %v", job.ID, err)
+			}
+			continue
 		}
+		moved++
 	}
 
-	if len(jobs) > 0 {
-		log.Printf("Processed %d retry jobs", len(jobs))
+	if moved > 0 {
+		log.Printf("Processed %d retry jobs", moved)
 	}
-
 	return nil
 }
 
-// GetQueueStats returns statistics about the job queues
+// ListQueues returns the depth of every known queue
+func (jqs *JobQueueService) ListQueues() ([]*QueueInfo, error) {
+	names, err := jqs.directory.list()
+	if err != nil {
+		return nil, err
+	}
+
+	queues := make([]*QueueInfo, 0, len(names))
+	for _, name := range names {
+		info := newQueueInfo(name)
+		for _, priority := range models.Priorities {
+			readyLen, err := jqs.client.LLen(jqs.ctx, readyList(name, priority)).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get ready queue length of %s: %w", name, err)
+			}
+			info.ReadyByPriority[priority] = readyLen
+			info.Ready += readyLen
+		}
+		queues = append(queues, info)
+	}
+	return queues, nil
+}
+
+// GetQueueStats returns statistics about the job queues. Besides the total, "ready:<priority>" gives
+// the jobs of each priority and "queue:<name>" the jobs in each queue that are ready to run.
 func (jqs *JobQueueService) GetQueueStats() (map[string]int64, error) {
 	stats := make(map[string]int64)
 
 	// Get queue lengths
-	readyLen, err := jqs.client.LLen(jqs.ctx, QueueReady).Result()
+	queues, err := jqs.ListQueues()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ready queue length: %w", err)
+		return nil, err
 	}
-	stats["ready"] = readyLen
+	addReadyStats(stats, queues)
 
-	processingLen, err := jqs.client.SCard(jqs.ctx, QueueProcessing).Result()
+	processingLen, err := jqs.client.ZCard(jqs.ctx, QueueInFlight).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get processing queue length: %w", err)
 	}
@@ -262,39 +627,11 @@ func (jqs *JobQueueService) GetQueueStats() (map[string]int64, error) {
 	}
 	stats["retrying"] = retryingLen
 
-	return stats, nil
-}
-
-// CleanupStaleJobs removes jobs that have been in processing for too long
-func (jqs *JobQueueService) CleanupStaleJobs(maxProcessingTime time.Duration) error {
-	// Get all jobs in processing queue
-	jobIDs, err := jqs.client.SMembers(jqs.ctx, QueueProcessing).Result()
+	failedLen, err := countDeadLetters(jqs.ctx, jqs.client)
 	if err != nil {
-		return fmt.Errorf("failed to get processing jobs: %w", err)
+		return nil, err
 	}
+	stats["failed"] = failedLen
 
-	staleCount := 0
-	for _, jobID := range jobIDs {
-		// Check if job data exists and is stale
-		exists, err := jqs.client.Exists(jqs.ctx, fmt.Sprintf("job_data:%s", jobID)).Result()
-		if err != nil {
-			log.Printf("Warning: failed to check job data for %s: %v", jobID, err)
-			continue
-		}
-
-		if exists == 0 {
-			// Job data doesn't exist, remove from processing queue
-			if err := jqs.client.SRem(jqs.ctx, QueueProcessing, jobID).Err(); err != nil {
-				log.Printf("Warning: failed to remove stale job %s: %v", jobID, err)
-			} else {
-				staleCount++
-			}
-		}
-	}
-
-	if staleCount > 0 {
-		log.Printf("Cleaned up %d stale jobs from processing queue", staleCount)
-	}
-
-	return nil
+	return stats, nil
 }
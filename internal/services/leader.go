@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	redisclient "github.com/manyu/job-scheduler/internal/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+// LeaderLease describes who holds the leader lock
+type LeaderLease struct {
+	Holder    string    // Instance ID of the leader
+	Term      int64     // Fencing token, increases with every change of leader
+	ExpiresAt time.Time // The lease lapses here unless the leader renews it
+}
+
+// LeaderLock is the backend leader election runs on. Each method must be atomic.
+type LeaderLock interface {
+	// Acquire takes the lock for holder if nobody holds it, at a term above minTerm, and returns the lease
+	// of whoever holds it afterwards
+	Acquire(ctx context.Context, holder string, ttl time.Duration, minTerm int64) (*LeaderLease, error)
+	// Renew extends the lease if holder still holds it at term
+	Renew(ctx context.Context, holder string, term int64, ttl time.Duration) (bool, error)
+	// Release gives up the lock if holder still holds it at term
+	Release(ctx context.Context, holder string, term int64) error
+	// Current returns the lease of the leader, or nil if there is none
+	Current(ctx context.Context) (*LeaderLease, error)
+}
+
+// TermFloor returns the highest term recorded outside the lock, e.g. on claimed schedules. New terms
+// exceed it, so they keep fencing earlier leaders after the lock backend lost its term counter.
+type TermFloor func() (int64, error)
+
+// LeaderStatus is the leader election state as seen by one scheduler
+type LeaderStatus struct {
+	Leader    string     `json:"leader"`              // Instance ID of the leader, empty if there is none
+	Term      int64      `json:"term"`                // Fencing token of the current leader
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // When the leader's lease lapses unless renewed
+	Self      string     `json:"self"`                // Instance ID of this scheduler
+	IsLeader  bool       `json:"isLeader"`            // Whether this scheduler is polling for due jobs
+}
+
+// LeaderElector keeps trying to become leader through a LeaderLock and renews the lease while it is.
+// The lease is renewed every third of its TTL. Leadership is given up locally after two thirds of the TTL
+// without a successful renewal, before the lock itself expires, so two schedulers never both believe
+// they are leader. A standby takes over at most a third of the TTL after the lock expires.
+type LeaderElector struct {
+	lock     LeaderLock
+	identity string
+	ttl      time.Duration
+	floor    TermFloor // nil when terms are not recorded elsewhere
+
+	mu       sync.RWMutex
+	term     int64     // Term held by this scheduler, 0 when it is not leader
+	deadline time.Time // Leadership is assumed only until here
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLeaderElector creates a leader elector that competes for lock as identity, at terms above floor
+func NewLeaderElector(lock LeaderLock, identity string, ttl time.Duration, floor TermFloor) *LeaderElector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LeaderElector{
+		lock:     lock,
+		identity: identity,
+		ttl:      ttl,
+		floor:    floor,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins competing for leadership
+func (le *LeaderElector) Start() {
+	go le.run()
+}
+
+// run attempts to acquire or renew the lease until the elector is stopped
+func (le *LeaderElector) run() {
+	defer close(le.done)
+
+	ticker := time.NewTicker(le.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		le.tick()
+
+		select {
+		case <-le.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick renews the lease when leader and tries to acquire it otherwise
+func (le *LeaderElector) tick() {
+	attemptedAt := time.Now()
+	ctx, cancel := context.WithTimeout(le.ctx, le.ttl/3)
+	defer cancel()
+
+	le.mu.RLock()
+	term := le.term
+	le.mu.RUnlock()
+
+	if term != 0 {
+		renewed, err := le.lock.Renew(ctx, le.identity, term, le.ttl)
+		if err != nil {
+			// Keep leading until the local deadline in case the next renewal gets through
+			log.Printf("Failed to renew leader lease (term %d): %v", term, err)
+			if attemptedAt.After(le.leaderDeadline()) {
+				le.demote(term)
+			}
+			return
+		}
+		if !renewed {
+			le.demote(term)
+			return
+		}
+		le.promote(term, attemptedAt)
+		return
+	}
+
+	// A term at or below one already recorded would be fenced off everywhere it was recorded
+	var minTerm int64
+	if le.floor != nil {
+		floor, err := le.floor()
+		if err != nil {
+			log.Printf("Failed to get the highest recorded leader term: %v", err)
+			return
+		}
+		minTerm = floor
+	}
+
+	lease, err := le.lock.Acquire(ctx, le.identity, le.ttl, minTerm)
+	if err != nil {
+		log.Printf("Failed to acquire leader lease: %v", err)
+		return
+	}
+	if lease != nil && lease.Holder == le.identity {
+		le.promote(lease.Term, attemptedAt)
+		log.Printf("Scheduler %s became leader (term %d)", le.identity, lease.Term)
+	}
+}
+
+// promote records a lease obtained at attemptedAt. The deadline is measured from before the
+// request was sent, so it never outlasts the lease in Redis.
+func (le *LeaderElector) promote(term int64, attemptedAt time.Time) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	le.term = term
+	le.deadline = attemptedAt.Add(le.ttl * 2 / 3)
+}
+
+// demote gives up leadership of term
+func (le *LeaderElector) demote(term int64) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	if le.term != term {
+		return
+	}
+	le.term = 0
+	le.deadline = time.Time{}
+	log.Printf("Scheduler %s lost leadership (term %d)", le.identity, term)
+}
+
+func (le *LeaderElector) leaderDeadline() time.Time {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	return le.deadline
+}
+
+// IsLeader reports whether this scheduler currently holds a valid lease
+func (le *LeaderElector) IsLeader() bool {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	return le.term != 0 && time.Now().Before(le.deadline)
+}
+
+// Term returns the term this scheduler leads, or 0 when it is not leader
+func (le *LeaderElector) Term() int64 {
+	if !le.IsLeader() {
+		return 0
+	}
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	return le.term
+}
+
+// Identity returns the instance ID this elector competes as
+func (le *LeaderElector) Identity() string {
+	return le.identity
+}
+
+// Status returns the current leader as recorded by the lock backend
+func (le *LeaderElector) Status(ctx context.Context) (*LeaderStatus, error) {
+	lease, err := le.lock.Current(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leader: %w", err)
+	}
+
+	status := &LeaderStatus{
+		Self:     le.identity,
+		IsLeader: le.IsLeader(),
+	}
+	if lease != nil {
+		status.Leader = lease.Holder
+		status.Term = lease.Term
+		status.ExpiresAt = &lease.ExpiresAt
+	}
+	return status, nil
+}
+
+// Stop stops competing for leadership and releases the lease so a standby can take over at once
+func (le *LeaderElector) Stop() {
+	le.cancel()
+	<-le.done
+
+	le.mu.Lock()
+	term := le.term
+	le.term = 0
+	le.mu.Unlock()
+
+	if term == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), le.ttl/3)
+	defer cancel()
+	if err := le.lock.Release(ctx, le.identity, term); err != nil {
+		log.Printf("Failed to release leader lease (term %d): %v", term, err)
+	}
+}
+
+// Leader lock keys
+const (
+	LeaderLockKey = "scheduler:leader"      // Hash of holder and term, expiring with the lease
+	LeaderTermKey = "scheduler:leader:term" // Counter the fencing tokens are taken from
+)
+
+// acquireLeaderScript takes the lock with a new term above ARGV[3] if it is free and returns holder,
+// term and TTL in ms. The counter is raised past ARGV[3] should it have been lost or fallen behind.
+var acquireLeaderScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	local term = redis.call('INCR', KEYS[2])
+	if term <= tonumber(ARGV[3]) then
+		term = tonumber(ARGV[3]) + 1
+		redis.call('SET', KEYS[2], term)
+	end
+	redis.call('HSET', KEYS[1], 'holder', ARGV[1], 'term', term)
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return {redis.call('HGET', KEYS[1], 'holder'), tonumber(redis.call('HGET', KEYS[1], 'term')), redis.call('PTTL', KEYS[1])}
+`)
+
+// renewLeaderScript extends the lock if it is held by ARGV[1] at term ARGV[2]
+var renewLeaderScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'holder') == ARGV[1] and redis.call('HGET', KEYS[1], 'term') == ARGV[2] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return 0
+`)
+
+// releaseLeaderScript deletes the lock if it is held by ARGV[1] at term ARGV[2]
+var releaseLeaderScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'holder') == ARGV[1] and redis.call('HGET', KEYS[1], 'term') == ARGV[2] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// currentLeaderScript returns holder, term and TTL in ms, or an empty array when there is no leader
+var currentLeaderScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return {}
+end
+return {redis.call('HGET', KEYS[1], 'holder'), tonumber(redis.call('HGET', KEYS[1], 'term')), redis.call('PTTL', KEYS[1])}
+`)
+
+// RedisLeaderLock implements LeaderLock with a Redis hash that expires with the lease.
+// Terms come from a separate counter so they keep increasing after the lock expires.
+type RedisLeaderLock struct {
+	client *redis.Client
+}
+
+// NewRedisLeaderLock creates a leader lock on Redis
+func NewRedisLeaderLock(redisClient redisclient.RedisClientInterface) *RedisLeaderLock {
+	return &RedisLeaderLock{
+		client: redisClient.GetClient(),
+	}
+}
+
+// Acquire takes the lock for holder at a term above minTerm if nobody holds it
+func (l *RedisLeaderLock) Acquire(ctx context.Context, holder string, ttl time.Duration, minTerm int64) (*LeaderLease, error) {
+	result, err := acquireLeaderScript.Run(ctx, l.client, []string{LeaderLockKey, LeaderTermKey}, holder, ttl.Milliseconds(), minTerm).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire leader lock: %w", err)
+	}
+	return parseLeaderLease(result)
+}
+
+// Renew extends the lease if holder still holds it at term
+func (l *RedisLeaderLock) Renew(ctx context.Context, holder string, term int64, ttl time.Duration) (bool, error) {
+	renewed, err := renewLeaderScript.Run(ctx, l.client, []string{LeaderLockKey}, holder, term, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew leader lock: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// Release gives up the lock if holder still holds it at term
+func (l *RedisLeaderLock) Release(ctx context.Context, holder string, term int64) error {
+	if err := releaseLeaderScript.Run(ctx, l.client, []string{LeaderLockKey}, holder, term).Err(); err != nil {
+		return fmt.Errorf("failed to release leader lock: %w", err)
+	}
+	return nil
+}
+
+// Current returns the lease of the leader, or nil if there is none
+func (l *RedisLeaderLock) Current(ctx context.Context) (*LeaderLease, error) {
+	result, err := currentLeaderScript.Run(ctx, l.client, []string{LeaderLockKey}).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leader lock: %w", err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return parseLeaderLease(result)
+}
+
+// parseLeaderLease converts the holder, term and TTL returned by the lock scripts
+func parseLeaderLease(result []interface{}) (*LeaderLease, error) {
+	if len(result) != 3 {
+		return nil, fmt.Errorf("invalid leader lock result: %v", result)
+	}
+	holder, _ := result[0].(string)
+	term, _ := result[1].(int64)
+	ttl, _ := result[2].(int64)
+	return &LeaderLease{
+		Holder:    holder,
+		Term:      term,
+		ExpiresAt: time.Now().Add(time.Duration(ttl) * time.Millisecond),
+	}, nil
+}
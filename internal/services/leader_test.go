@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLeaderLock is an in-memory LeaderLock shared by the schedulers of a test
+type fakeLeaderLock struct {
+	mu        sync.Mutex
+	holder    string
+	term      int64
+	expiresAt time.Time
+}
+
+func (l *fakeLeaderLock) Acquire(ctx context.Context, holder string, ttl time.Duration, minTerm int64) (*LeaderLease, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == "" || time.Now().After(l.expiresAt) {
+		l.term = max(l.term, minTerm) + 1
+		l.holder = holder
+		l.expiresAt = time.Now().Add(ttl)
+	}
+	return &LeaderLease{Holder: l.holder, Term: l.term, ExpiresAt: l.expiresAt}, nil
+}
+
+func (l *fakeLeaderLock) Renew(ctx context.Context, holder string, term int64, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder != holder || l.term != term || time.Now().After(l.expiresAt) {
+		return false, nil
+	}
+	l.expiresAt = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (l *fakeLeaderLock) Release(ctx context.Context, holder string, term int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == holder && l.term == term {
+		l.holder = ""
+	}
+	return nil
+}
+
+func (l *fakeLeaderLock) Current(ctx context.Context) (*LeaderLease, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == "" || time.Now().After(l.expiresAt) {
+		return nil, nil
+	}
+	return &LeaderLease{Holder: l.holder, Term: l.term, ExpiresAt: l.expiresAt}, nil
+}
+
+// steal hands the lock to another holder, as if the leader had stalled past its lease
+func (l *fakeLeaderLock) steal(holder string, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.term++
+	l.holder = holder
+	l.expiresAt = time.Now().Add(ttl)
+}
+
+const testLeaderTTL = 300 * time.Millisecond
+
+// startElectors starts one elector per identity on a shared lock and waits for a leader
+func startElectors(t *testing.T, lock LeaderLock, identities ...string) []*LeaderElector {
+	electors := make([]*LeaderElector, 0, len(identities))
+	for _, identity := range identities {
+		elector := NewLeaderElector(lock, identity, testLeaderTTL, nil)
+		elector.Start()
+		electors = append(electors, elector)
+	}
+	require.Eventually(t, func() bool { return leaderOf(electors) != nil }, testLeaderTTL, 10*time.Millisecond)
+	return electors
+}
+
+// leaderOf returns the only elector that is leader, or nil if there is none
+func leaderOf(electors []*LeaderElector) *LeaderElector {
+	var leader *LeaderElector
+	for _, elector := range electors {
+		if elector.IsLeader() {
+			if leader != nil {
+				return nil
+			}
+			leader = elector
+		}
+	}
+	return leader
+}
+
+// crash stops an elector without releasing its lease
+func crash(elector *LeaderElector) {
+	elector.cancel()
+	<-elector.done
+}
+
+func TestLeaderElector_ElectsOneLeader(t *testing.T) {
+	lock := &fakeLeaderLock{}
+	electors := startElectors(t, lock, "scheduler-a", "scheduler-b")
+	defer electors[0].Stop()
+	defer electors[1].Stop()
+
+	// Renewals keep the same leader and term
+	leader := leaderOf(electors)
+	term := leader.Term()
+	for i := 0; i < 10; i++ {
+		time.Sleep(testLeaderTTL / 10)
+		assert.Same(t, leader, leaderOf(electors))
+	}
+	assert.Equal(t, term, leader.Term())
+
+	status, err := electors[0].Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, leader.Identity(), status.Leader)
+	assert.Equal(t, term, status.Term)
+	assert.Equal(t, "scheduler-a", status.Self)
+	assert.Equal(t, leader == electors[0], status.IsLeader)
+}
+
+func TestLeaderElector_StandbyTakesOverWhenLeaderDies(t *testing.T) {
+	lock := &fakeLeaderLock{}
+	electors := startElectors(t, lock, "scheduler-a", "scheduler-b")
+
+	leader := leaderOf(electors)
+	standby := electors[0]
+	if standby == leader {
+		standby = electors[1]
+	}
+	defer standby.Stop()
+	term := leader.Term()
+
+	crash(leader)
+
+	// The dead leader's lease runs out, after which the standby acquires it on its next attempt
+	require.Eventually(t, standby.IsLeader, testLeaderTTL*4/3+100*time.Millisecond, 10*time.Millisecond)
+	assert.False(t, leader.IsLeader())
+	assert.Greater(t, standby.Term(), term)
+}
+
+func TestLeaderElector_StopHandsOverAtOnce(t *testing.T) {
+	lock := &fakeLeaderLock{}
+	electors := startElectors(t, lock, "scheduler-a", "scheduler-b")
+
+	leader := leaderOf(electors)
+	standby := electors[0]
+	if standby == leader {
+		standby = electors[1]
+	}
+	defer standby.Stop()
+
+	leader.Stop()
+
+	// Released leases do not have to expire first
+	require.Eventually(t, standby.IsLeader, testLeaderTTL/2, 10*time.Millisecond)
+	assert.False(t, leader.IsLeader())
+}
+
+func TestLeaderElector_StepsDownWhenLeaseLost(t *testing.T) {
+	lock := &fakeLeaderLock{}
+	electors := startElectors(t, lock, "scheduler-a")
+	elector := electors[0]
+	defer elector.Stop()
+
+	lock.steal("scheduler-b", time.Minute)
+
+	require.Eventually(t, func() bool { return !elector.IsLeader() }, testLeaderTTL/2, 10*time.Millisecond)
+	assert.Zero(t, elector.Term())
+}
+
+func TestBackgroundScheduler_OnlyLeaderPolls(t *testing.T) {
+	lock := &fakeLeaderLock{}
+
+	newBackgroundScheduler := func(identity string) *BackgroundScheduler {
+		schedulerService := &SchedulerService{
+			storage:        NewMockSchedulerStorage(),
+			jobQueue:       NewMockJobQueue(),
+			redisClient:    &MockRedisClient{},
+			scheduleParser: utils.NewScheduleParser(),
+			instanceID:     identity,
+		}
+		return NewBackgroundScheduler(schedulerService, 10, NewLeaderElector(lock, identity, testLeaderTTL, nil), nil)
+	}
+	first, second := newBackgroundScheduler("scheduler-a"), newBackgroundScheduler("scheduler-b")
+
+	first.Start(time.Hour)
+	second.Start(time.Hour)
+	defer first.Stop()
+	defer second.Stop()
+
+	require.Eventually(t, func() bool { return first.IsLeader() != second.IsLeader() }, testLeaderTTL, 10*time.Millisecond)
+
+	leader, standby := first, second
+	if second.IsLeader() {
+		leader, standby = second, first
+	}
+
+	leader.Stop()
+	require.Eventually(t, standby.IsLeader, testLeaderTTL/2, 10*time.Millisecond)
+
+	// Without an elector the only scheduler always polls
+	assert.True(t, NewBackgroundScheduler(nil, 10, nil, nil).IsLeader())
+}
+
+func TestLeaderElector_TermsExceedRecordedTermsAfterCounterLoss(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	mockJobQueue := NewMockJobQueue()
+	schedulerService := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       mockJobQueue,
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+		instanceID:     "scheduler-a",
+	}
+
+	job := &models.Job{
+		Schedule:    "@every 1h",
+		API:         "https://httpbin.org/status/200",
+		Type:        models.AT_LEAST_ONCE,
+		IsRecurring: true,
+		IsActive:    true,
+	}
+	mockStorage.CreateJob(job)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: time.Now().Add(-time.Second), LeaderTerm: 7})
+
+	// The lock backend lost its counter, e.g. Redis restarted without persistence
+	lock := &fakeLeaderLock{}
+	elector := NewLeaderElector(lock, "scheduler-a", testLeaderTTL, mockStorage.MaxScheduleLeaderTerm)
+	elector.Start()
+	defer elector.Stop()
+	require.Eventually(t, elector.IsLeader, testLeaderTTL, 10*time.Millisecond)
+
+	assert.Greater(t, elector.Term(), int64(7))
+	require.NoError(t, schedulerService.ProcessReadyJobsAsLeader(context.Background(), 10, elector.Term()))
+	assert.Len(t, mockJobQueue.enqueuedJobs, 1)
+}
@@ -15,7 +15,7 @@ import (
 	time "time"
 
 	models "github.com/manyu/job-scheduler/internal/models"
-	v9 "github.com/redis/go-redis/v9"
+	services "github.com/manyu/job-scheduler/internal/services"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -72,6 +72,21 @@ func (mr *MockSchedulerServiceInterfaceMockRecorder) HandleJobCompletion(jobID,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleJobCompletion", reflect.TypeOf((*MockSchedulerServiceInterface)(nil).HandleJobCompletion), jobID, success)
 }
 
+// ListQueues mocks base method.
+func (m *MockSchedulerServiceInterface) ListQueues() ([]*services.QueueInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListQueues")
+	ret0, _ := ret[0].([]*services.QueueInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListQueues indicates an expected call of ListQueues.
+func (mr *MockSchedulerServiceInterfaceMockRecorder) ListQueues() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListQueues", reflect.TypeOf((*MockSchedulerServiceInterface)(nil).ListQueues))
+}
+
 // ProcessReadyJobs mocks base method.
 func (m *MockSchedulerServiceInterface) ProcessReadyJobs(ctx context.Context, limit int) error {
 	m.ctrl.T.Helper()
@@ -168,6 +183,21 @@ func (mr *MockJobQueueServiceInterfaceMockRecorder) GetQueueStats() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueueStats", reflect.TypeOf((*MockJobQueueServiceInterface)(nil).GetQueueStats))
 }
 
+// ListQueues mocks base method.
+func (m *MockJobQueueServiceInterface) ListQueues() ([]*services.QueueInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListQueues")
+	ret0, _ := ret[0].([]*services.QueueInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListQueues indicates an expected call of ListQueues.
+func (mr *MockJobQueueServiceInterfaceMockRecorder) ListQueues() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListQueues", reflect.TypeOf((*MockJobQueueServiceInterface)(nil).ListQueues))
+}
+
 // ProcessRetryQueue mocks base method.
 func (m *MockJobQueueServiceInterface) ProcessRetryQueue() error {
 	m.ctrl.T.Helper()
@@ -182,82 +212,432 @@ func (mr *MockJobQueueServiceInterfaceMockRecorder) ProcessRetryQueue() *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessRetryQueue", reflect.TypeOf((*MockJobQueueServiceInterface)(nil).ProcessRetryQueue))
 }
 
-// MockRedisClientInterface is a mock of RedisClientInterface interface.
-type MockRedisClientInterface struct {
+// MockWorkerQueueInterface is a mock of WorkerQueueInterface interface.
+type MockWorkerQueueInterface struct {
 	ctrl     *gomock.Controller
-	recorder *MockRedisClientInterfaceMockRecorder
+	recorder *MockWorkerQueueInterfaceMockRecorder
 	isgomock struct{}
 }
 
-// MockRedisClientInterfaceMockRecorder is the mock recorder for MockRedisClientInterface.
-type MockRedisClientInterfaceMockRecorder struct {
-	mock *MockRedisClientInterface
+// MockWorkerQueueInterfaceMockRecorder is the mock recorder for MockWorkerQueueInterface.
+type MockWorkerQueueInterfaceMockRecorder struct {
+	mock *MockWorkerQueueInterface
 }
 
-// NewMockRedisClientInterface creates a new mock instance.
-func NewMockRedisClientInterface(ctrl *gomock.Controller) *MockRedisClientInterface {
-	mock := &MockRedisClientInterface{ctrl: ctrl}
-	mock.recorder = &MockRedisClientInterfaceMockRecorder{mock}
+// NewMockWorkerQueueInterface creates a new mock instance.
+func NewMockWorkerQueueInterface(ctrl *gomock.Controller) *MockWorkerQueueInterface {
+	mock := &MockWorkerQueueInterface{ctrl: ctrl}
+	mock.recorder = &MockWorkerQueueInterfaceMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockRedisClientInterface) EXPECT() *MockRedisClientInterfaceMockRecorder {
+func (m *MockWorkerQueueInterface) EXPECT() *MockWorkerQueueInterfaceMockRecorder {
 	return m.recorder
 }
 
-// Close mocks base method.
-func (m *MockRedisClientInterface) Close() error {
+// CompleteJob mocks base method.
+func (m *MockWorkerQueueInterface) CompleteJob(jobID string, result *models.QueueJobResult) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteJob", jobID, result)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteJob indicates an expected call of CompleteJob.
+func (mr *MockWorkerQueueInterfaceMockRecorder) CompleteJob(jobID, result any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteJob", reflect.TypeOf((*MockWorkerQueueInterface)(nil).CompleteJob), jobID, result)
+}
+
+// DeferJob mocks base method.
+func (m *MockWorkerQueueInterface) DeferJob(job *models.QueueJob, delay time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeferJob", job, delay)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeferJob indicates an expected call of DeferJob.
+func (mr *MockWorkerQueueInterfaceMockRecorder) DeferJob(job, delay any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeferJob", reflect.TypeOf((*MockWorkerQueueInterface)(nil).DeferJob), job, delay)
+}
+
+// DequeueJob mocks base method.
+func (m *MockWorkerQueueInterface) DequeueJob(timeout time.Duration) (*models.QueueJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DequeueJob", timeout)
+	ret0, _ := ret[0].(*models.QueueJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DequeueJob indicates an expected call of DequeueJob.
+func (mr *MockWorkerQueueInterfaceMockRecorder) DequeueJob(timeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DequeueJob", reflect.TypeOf((*MockWorkerQueueInterface)(nil).DequeueJob), timeout)
+}
+
+// DiscardJob mocks base method.
+func (m *MockWorkerQueueInterface) DiscardJob(jobID string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DiscardJob", jobID)
+}
+
+// DiscardJob indicates an expected call of DiscardJob.
+func (mr *MockWorkerQueueInterfaceMockRecorder) DiscardJob(jobID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiscardJob", reflect.TypeOf((*MockWorkerQueueInterface)(nil).DiscardJob), jobID)
+}
+
+// EnqueueJob mocks base method.
+func (m *MockWorkerQueueInterface) EnqueueJob(job *models.QueueJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueJob", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnqueueJob indicates an expected call of EnqueueJob.
+func (mr *MockWorkerQueueInterfaceMockRecorder) EnqueueJob(job any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueJob", reflect.TypeOf((*MockWorkerQueueInterface)(nil).EnqueueJob), job)
+}
+
+// ExpiredJobs mocks base method.
+func (m *MockWorkerQueueInterface) ExpiredJobs() ([]*models.QueueJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpiredJobs")
+	ret0, _ := ret[0].([]*models.QueueJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpiredJobs indicates an expected call of ExpiredJobs.
+func (mr *MockWorkerQueueInterfaceMockRecorder) ExpiredJobs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpiredJobs", reflect.TypeOf((*MockWorkerQueueInterface)(nil).ExpiredJobs))
+}
+
+// ExtendLeases mocks base method.
+func (m *MockWorkerQueueInterface) ExtendLeases() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Close")
+	ret := m.ctrl.Call(m, "ExtendLeases")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Close indicates an expected call of Close.
-func (mr *MockRedisClientInterfaceMockRecorder) Close() *gomock.Call {
+// ExtendLeases indicates an expected call of ExtendLeases.
+func (mr *MockWorkerQueueInterfaceMockRecorder) ExtendLeases() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockRedisClientInterface)(nil).Close))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtendLeases", reflect.TypeOf((*MockWorkerQueueInterface)(nil).ExtendLeases))
 }
 
-// GetClient mocks base method.
-func (m *MockRedisClientInterface) GetClient() *v9.Client {
+// FailJob mocks base method.
+func (m *MockWorkerQueueInterface) FailJob(job *models.QueueJob, errorMsg string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetClient")
-	ret0, _ := ret[0].(*v9.Client)
+	ret := m.ctrl.Call(m, "FailJob", job, errorMsg)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetClient indicates an expected call of GetClient.
-func (mr *MockRedisClientInterfaceMockRecorder) GetClient() *gomock.Call {
+// FailJob indicates an expected call of FailJob.
+func (mr *MockWorkerQueueInterfaceMockRecorder) FailJob(job, errorMsg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockRedisClientInterface)(nil).GetClient))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailJob", reflect.TypeOf((*MockWorkerQueueInterface)(nil).FailJob), job, errorMsg)
 }
 
-// GetContext mocks base method.
-func (m *MockRedisClientInterface) GetContext() context.Context {
+// GetQueueStats mocks base method.
+func (m *MockWorkerQueueInterface) GetQueueStats() (map[string]int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetContext")
-	ret0, _ := ret[0].(context.Context)
+	ret := m.ctrl.Call(m, "GetQueueStats")
+	ret0, _ := ret[0].(map[string]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQueueStats indicates an expected call of GetQueueStats.
+func (mr *MockWorkerQueueInterfaceMockRecorder) GetQueueStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueueStats", reflect.TypeOf((*MockWorkerQueueInterface)(nil).GetQueueStats))
+}
+
+// ListQueues mocks base method.
+func (m *MockWorkerQueueInterface) ListQueues() ([]*services.QueueInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListQueues")
+	ret0, _ := ret[0].([]*services.QueueInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListQueues indicates an expected call of ListQueues.
+func (mr *MockWorkerQueueInterfaceMockRecorder) ListQueues() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListQueues", reflect.TypeOf((*MockWorkerQueueInterface)(nil).ListQueues))
+}
+
+// ProcessRetryQueue mocks base method.
+func (m *MockWorkerQueueInterface) ProcessRetryQueue() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessRetryQueue")
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetContext indicates an expected call of GetContext.
-func (mr *MockRedisClientInterfaceMockRecorder) GetContext() *gomock.Call {
+// ProcessRetryQueue indicates an expected call of ProcessRetryQueue.
+func (mr *MockWorkerQueueInterfaceMockRecorder) ProcessRetryQueue() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContext", reflect.TypeOf((*MockRedisClientInterface)(nil).GetContext))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessRetryQueue", reflect.TypeOf((*MockWorkerQueueInterface)(nil).ProcessRetryQueue))
 }
 
-// Health mocks base method.
-func (m *MockRedisClientInterface) Health() error {
+// ReapJob mocks base method.
+func (m *MockWorkerQueueInterface) ReapJob(job *models.QueueJob, onReaped func()) (bool, bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Health")
+	ret := m.ctrl.Call(m, "ReapJob", job, onReaped)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReapJob indicates an expected call of ReapJob.
+func (mr *MockWorkerQueueInterfaceMockRecorder) ReapJob(job, onReaped any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReapJob", reflect.TypeOf((*MockWorkerQueueInterface)(nil).ReapJob), job, onReaped)
+}
+
+// RequeueJob mocks base method.
+func (m *MockWorkerQueueInterface) RequeueJob(job *models.QueueJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueJob", job)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Health indicates an expected call of Health.
-func (mr *MockRedisClientInterfaceMockRecorder) Health() *gomock.Call {
+// RequeueJob indicates an expected call of RequeueJob.
+func (mr *MockWorkerQueueInterfaceMockRecorder) RequeueJob(job any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueJob", reflect.TypeOf((*MockWorkerQueueInterface)(nil).RequeueJob), job)
+}
+
+// Subscription mocks base method.
+func (m *MockWorkerQueueInterface) Subscription() services.QueueSubscription {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscription")
+	ret0, _ := ret[0].(services.QueueSubscription)
+	return ret0
+}
+
+// Subscription indicates an expected call of Subscription.
+func (mr *MockWorkerQueueInterfaceMockRecorder) Subscription() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscription", reflect.TypeOf((*MockWorkerQueueInterface)(nil).Subscription))
+}
+
+// WorkerID mocks base method.
+func (m *MockWorkerQueueInterface) WorkerID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WorkerID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// WorkerID indicates an expected call of WorkerID.
+func (mr *MockWorkerQueueInterfaceMockRecorder) WorkerID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WorkerID", reflect.TypeOf((*MockWorkerQueueInterface)(nil).WorkerID))
+}
+
+// MockLeaderElectorInterface is a mock of LeaderElectorInterface interface.
+type MockLeaderElectorInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaderElectorInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockLeaderElectorInterfaceMockRecorder is the mock recorder for MockLeaderElectorInterface.
+type MockLeaderElectorInterfaceMockRecorder struct {
+	mock *MockLeaderElectorInterface
+}
+
+// NewMockLeaderElectorInterface creates a new mock instance.
+func NewMockLeaderElectorInterface(ctrl *gomock.Controller) *MockLeaderElectorInterface {
+	mock := &MockLeaderElectorInterface{ctrl: ctrl}
+	mock.recorder = &MockLeaderElectorInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLeaderElectorInterface) EXPECT() *MockLeaderElectorInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Status mocks base method.
+func (m *MockLeaderElectorInterface) Status(ctx context.Context) (*services.LeaderStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status", ctx)
+	ret0, _ := ret[0].(*services.LeaderStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockLeaderElectorInterfaceMockRecorder) Status(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockLeaderElectorInterface)(nil).Status), ctx)
+}
+
+// MockWorkerRegistryInterface is a mock of WorkerRegistryInterface interface.
+type MockWorkerRegistryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkerRegistryInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockWorkerRegistryInterfaceMockRecorder is the mock recorder for MockWorkerRegistryInterface.
+type MockWorkerRegistryInterfaceMockRecorder struct {
+	mock *MockWorkerRegistryInterface
+}
+
+// NewMockWorkerRegistryInterface creates a new mock instance.
+func NewMockWorkerRegistryInterface(ctrl *gomock.Controller) *MockWorkerRegistryInterface {
+	mock := &MockWorkerRegistryInterface{ctrl: ctrl}
+	mock.recorder = &MockWorkerRegistryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorkerRegistryInterface) EXPECT() *MockWorkerRegistryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// List mocks base method.
+func (m *MockWorkerRegistryInterface) List(ctx context.Context) ([]*services.WorkerStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]*services.WorkerStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockWorkerRegistryInterfaceMockRecorder) List(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockWorkerRegistryInterface)(nil).List), ctx)
+}
+
+// MockDeadLetterQueueInterface is a mock of DeadLetterQueueInterface interface.
+type MockDeadLetterQueueInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeadLetterQueueInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockDeadLetterQueueInterfaceMockRecorder is the mock recorder for MockDeadLetterQueueInterface.
+type MockDeadLetterQueueInterfaceMockRecorder struct {
+	mock *MockDeadLetterQueueInterface
+}
+
+// NewMockDeadLetterQueueInterface creates a new mock instance.
+func NewMockDeadLetterQueueInterface(ctrl *gomock.Controller) *MockDeadLetterQueueInterface {
+	mock := &MockDeadLetterQueueInterface{ctrl: ctrl}
+	mock.recorder = &MockDeadLetterQueueInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeadLetterQueueInterface) EXPECT() *MockDeadLetterQueueInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockDeadLetterQueueInterface) Delete(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockDeadLetterQueueInterfaceMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDeadLetterQueueInterface)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockDeadLetterQueueInterface) Get(id string) (*models.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*models.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockDeadLetterQueueInterfaceMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDeadLetterQueueInterface)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockDeadLetterQueueInterface) List(limit, offset int) ([]*models.DeadLetter, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", limit, offset)
+	ret0, _ := ret[0].([]*models.DeadLetter)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// List indicates an expected call of List.
+func (mr *MockDeadLetterQueueInterfaceMockRecorder) List(limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDeadLetterQueueInterface)(nil).List), limit, offset)
+}
+
+// Purge mocks base method.
+func (m *MockDeadLetterQueueInterface) Purge() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockDeadLetterQueueInterfaceMockRecorder) Purge() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockDeadLetterQueueInterface)(nil).Purge))
+}
+
+// Replay mocks base method.
+func (m *MockDeadLetterQueueInterface) Replay(id string) (*models.QueueJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", id)
+	ret0, _ := ret[0].(*models.QueueJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Replay indicates an expected call of Replay.
+func (mr *MockDeadLetterQueueInterfaceMockRecorder) Replay(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockDeadLetterQueueInterface)(nil).Replay), id)
+}
+
+// ReplayAll mocks base method.
+func (m *MockDeadLetterQueueInterface) ReplayAll() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplayAll")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplayAll indicates an expected call of ReplayAll.
+func (mr *MockDeadLetterQueueInterfaceMockRecorder) ReplayAll() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockRedisClientInterface)(nil).Health))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayAll", reflect.TypeOf((*MockDeadLetterQueueInterface)(nil).ReplayAll))
 }
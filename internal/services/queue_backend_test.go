@@ -0,0 +1,460 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	redisclient "github.com/manyu/job-scheduler/internal/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLease is how long an in-flight job stays reserved for its worker without an extension in tests
+const testLease = 100 * time.Millisecond
+
+// queueBackend is a job queue implementation the shared queue tests run against
+type queueBackend struct {
+	name string
+	new  func(t *testing.T, redisClient redisclient.RedisClientInterface) WorkerQueueInterface
+}
+
+var queueBackends = []queueBackend{
+	{
+		name: QueueBackendList,
+		new: func(t *testing.T, redisClient redisclient.RedisClientInterface) WorkerQueueInterface {
+			queue := NewJobQueueService(redisClient)
+			queue.lease = testLease
+			return queue
+		},
+	},
+	{
+		name: QueueBackendStream,
+		new: func(t *testing.T, redisClient redisclient.RedisClientInterface) WorkerQueueInterface {
+			queue, err := NewStreamJobQueueService(redisClient, testLease)
+			require.NoError(t, err)
+			return queue
+		},
+	},
+}
+
+// newTestRedisClient connects to the Redis server tests run against, at TEST_REDIS_HOST:TEST_REDIS_PORT
+// or localhost:6379, and skips the test without one. Tests use database 15 and flush it.
+func newTestRedisClient(t *testing.T) redisclient.RedisClientInterface {
+	host, port := os.Getenv("TEST_REDIS_HOST"), os.Getenv("TEST_REDIS_PORT")
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "6379"
+	}
+	redisClient, err := redisclient.NewRedisClient(fmt.Sprintf("%s:%s", host, port), "", 15)
+	if err != nil {
+		t.Skipf("Redis is not available: %v", err)
+	}
+	t.Cleanup(func() { redisClient.Close() })
+	require.NoError(t, redisClient.GetClient().FlushDB(redisClient.GetContext()).Err())
+	return redisClient
+}
+
+// forEachQueueBackend runs test against every queue backend on an empty Redis database, skipping it
+// without a Redis server like newTestRedisClient
+func forEachQueueBackend(t *testing.T, test func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface)) {
+	redisClient := newTestRedisClient(t)
+
+	for _, backend := range queueBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			require.NoError(t, redisClient.GetClient().FlushDB(redisClient.GetContext()).Err())
+			test(t, backend, redisClient)
+		})
+	}
+}
+
+// newTestQueueJob returns a queue job for job ID jobID
+func newTestQueueJob(jobID uint, jobType models.JobType) *models.QueueJob {
+	return &models.QueueJob{
+		ID:            fmt.Sprintf("job_%d_test", jobID),
+		JobID:         jobID,
+		OccurrenceID:  models.OccurrenceID(jobID, time.Now()),
+		API:           "http://localhost/hook",
+		MaxRetryCount: 1,
+		CreatedAt:     time.Now(),
+		ScheduledAt:   time.Now(),
+		Timeout:       1,
+		Type:          jobType,
+	}
+}
+
+// requireStats asserts the backend-independent queue statistics
+func requireStats(t *testing.T, queue WorkerQueueInterface, ready, processing, completed, retrying, failed int64) {
+	t.Helper()
+	stats, err := queue.GetQueueStats()
+	require.NoError(t, err)
+	assert.Equal(t, ready, stats["ready"], "ready")
+	assert.Equal(t, processing, stats["processing"], "processing")
+	assert.Equal(t, completed, stats["completed"], "completed")
+	assert.Equal(t, retrying, stats["retrying"], "retrying")
+	assert.Equal(t, failed, stats["failed"], "failed")
+}
+
+func TestQueueBackends_DequeueInOrderAndComplete(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		queue := backend.new(t, redisClient)
+		first, second := newTestQueueJob(1, models.AT_LEAST_ONCE), newTestQueueJob(2, models.AT_LEAST_ONCE)
+		require.NoError(t, queue.EnqueueJob(first))
+		require.NoError(t, queue.EnqueueJob(second))
+
+		job, err := queue.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, first.ID, job.ID)
+		assert.Equal(t, first.OccurrenceID, job.OccurrenceID)
+		requireStats(t, queue, 1, 1, 0, 0, 0)
+
+		require.NoError(t, queue.CompleteJob(job.ID, &models.QueueJobResult{JobID: job.ID, Status: models.QueueStatusCompleted, Success: true}))
+		requireStats(t, queue, 1, 0, 1, 0, 0)
+
+		job, err = queue.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, second.ID, job.ID)
+	})
+}
+
+func TestQueueBackends_StrictPriorityDequeuesHigherPrioritiesFirst(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		strict, err := NewPriorityScheduler(PriorityPolicyStrict, nil)
+		require.NoError(t, err)
+		queue, err := NewQueue(redisClient, QueueOptions{Backend: backend.name, Priorities: strict})
+		require.NoError(t, err)
+
+		priorities := []models.JobPriority{models.PriorityLow, "", models.PriorityHigh}
+		for i, priority := range priorities {
+			job := newTestQueueJob(uint(i+1), models.AT_LEAST_ONCE)
+			job.Priority = priority
+			require.NoError(t, queue.EnqueueJob(job))
+		}
+
+		stats, err := queue.GetQueueStats()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stats["ready"])
+		for _, priority := range models.Priorities {
+			assert.Equal(t, int64(1), stats["ready:"+string(priority)], string(priority))
+		}
+
+		// Jobs without a priority wait with the normal ones
+		for _, jobID := range []uint{3, 2, 1} {
+			job, err := queue.DequeueJob(time.Second)
+			require.NoError(t, err)
+			require.NotNil(t, job)
+			assert.Equal(t, jobID, job.JobID)
+			queue.DiscardJob(job.ID)
+		}
+	})
+}
+
+func TestQueueBackends_RoutesJobsToSubscribedQueues(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		producer := backend.new(t, redisClient)
+
+		defaultJob := newTestQueueJob(1, models.AT_LEAST_ONCE)
+		reportsJob := newTestQueueJob(2, models.AT_LEAST_ONCE)
+		reportsJob.Queue = "reports"
+		gpuJob := newTestQueueJob(3, models.AT_LEAST_ONCE)
+		gpuJob.Queue = models.JobTags{"linux", "gpu"}.QueueName()
+		for _, job := range []*models.QueueJob{defaultJob, reportsJob, gpuJob} {
+			require.NoError(t, producer.EnqueueJob(job))
+		}
+
+		stats, err := producer.GetQueueStats()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), stats["ready"])
+		assert.Equal(t, int64(1), stats["queue:default"])
+		assert.Equal(t, int64(1), stats["queue:reports"])
+		assert.Equal(t, int64(1), stats["queue:tags:gpu,linux"])
+
+		queues, err := producer.ListQueues()
+		require.NoError(t, err)
+		require.Len(t, queues, 3)
+		assert.Equal(t, "tags:gpu,linux", queues[2].Name)
+		assert.Equal(t, models.JobTags{"gpu", "linux"}, queues[2].Tags)
+
+		consume := func(subscription QueueSubscription) []uint {
+			queue, err := NewQueue(redisClient, QueueOptions{Backend: backend.name, Subscription: &subscription})
+			require.NoError(t, err)
+			var jobIDs []uint
+			for {
+				job, err := queue.DequeueJob(50 * time.Millisecond)
+				require.NoError(t, err)
+				if job == nil {
+					return jobIDs
+				}
+				jobIDs = append(jobIDs, job.JobID)
+				queue.DiscardJob(job.ID)
+			}
+		}
+
+		// A worker missing one of the tags does not take the tagged job
+		assert.Equal(t, []uint{2}, consume(QueueSubscription{Queues: []string{"reports"}, Tags: models.JobTags{"gpu"}}))
+		assert.Equal(t, []uint{3}, consume(QueueSubscription{Tags: models.JobTags{"arm", "gpu", "linux"}}))
+		assert.Equal(t, []uint{1}, consume(defaultSubscription()))
+	})
+}
+
+func TestQueueBackends_DefersJobsWithoutCountingAnAttempt(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		queue := backend.new(t, redisClient)
+		require.NoError(t, queue.EnqueueJob(newTestQueueJob(1, models.AT_MOST_ONCE)))
+
+		job, err := queue.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		require.NoError(t, queue.DeferJob(job, time.Hour))
+		requireStats(t, queue, 0, 0, 0, 1, 0)
+
+		// The job waits in the retry queue until the delay is over, unchanged
+		retries, err := redisClient.GetClient().ZRangeWithScores(redisClient.GetContext(), QueueRetrying, 0, -1).Result()
+		require.NoError(t, err)
+		require.Len(t, retries, 1)
+		assert.InDelta(t, time.Now().Add(time.Hour).Unix(), retries[0].Score, 2)
+		deferred, err := models.DeserializeQueueJob([]byte(retries[0].Member.(string)))
+		require.NoError(t, err)
+		assert.Equal(t, job.ID, deferred.ID)
+		assert.Equal(t, 0, deferred.RetryCount)
+		assert.Empty(t, deferred.Attempts)
+	})
+}
+
+func TestQueueBackends_DequeueWaitsForTimeout(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		queue := backend.new(t, redisClient)
+
+		start := time.Now()
+		job, err := queue.DequeueJob(300 * time.Millisecond)
+		require.NoError(t, err)
+		assert.Nil(t, job)
+		assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+	})
+}
+
+func TestQueueBackends_RequeueHandsJobToAnotherWorker(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		stopping, other := backend.new(t, redisClient), backend.new(t, redisClient)
+		require.NoError(t, stopping.EnqueueJob(newTestQueueJob(1, models.AT_LEAST_ONCE)))
+
+		job, err := stopping.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		require.NoError(t, stopping.RequeueJob(job))
+		requireStats(t, other, 1, 0, 0, 0, 0)
+
+		requeued, err := other.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, requeued)
+		assert.Equal(t, job.ID, requeued.ID)
+	})
+}
+
+func TestQueueBackends_FailJobRetriesThenGivesUp(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		queue := backend.new(t, redisClient)
+		require.NoError(t, queue.EnqueueJob(newTestQueueJob(1, models.AT_LEAST_ONCE)))
+
+		job, err := queue.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		require.NoError(t, queue.FailJob(job, "unexpected response status 503"))
+		requireStats(t, queue, 0, 0, 0, 1, 0)
+
+		// The last attempt is dead-lettered instead of retried
+		job.RetryCount = job.MaxRetryCount
+		require.NoError(t, queue.FailJob(job, "unexpected response status 503"))
+		requireStats(t, queue, 0, 0, 0, 1, 1)
+	})
+}
+
+func TestQueueBackends_ReplaysDeadLetters(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		queue := backend.new(t, redisClient)
+		deadLetters := NewDeadLetterQueue(redisClient, queue)
+		require.NoError(t, queue.EnqueueJob(newTestQueueJob(1, models.AT_LEAST_ONCE)))
+
+		job, err := queue.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		require.NoError(t, queue.FailJob(job, "unexpected response status 503"))
+		job.RetryCount = job.MaxRetryCount
+		require.NoError(t, queue.FailJob(job, "unexpected response status 500"))
+
+		letters, total, err := deadLetters.List(10, 0)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), total)
+		require.Len(t, letters, 1)
+		letter, err := deadLetters.Get(letters[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, "unexpected response status 500", letter.Error)
+		require.Len(t, letter.Attempts, 2)
+		assert.Equal(t, "unexpected response status 503", letter.Attempts[0].Error)
+
+		replayed, err := deadLetters.Replay(letter.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, letter.ID, replayed.ID)
+		_, err = deadLetters.Get(letter.ID)
+		assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+
+		job, err = queue.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, replayed.ID, job.ID)
+		assert.Equal(t, 0, job.RetryCount)
+		assert.Empty(t, job.Attempts)
+
+		job.RetryCount = job.MaxRetryCount
+		require.NoError(t, queue.FailJob(job, "unexpected response status 503"))
+		purged, err := deadLetters.Purge()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		requireStats(t, queue, 0, 0, 0, 1, 0)
+	})
+}
+
+func TestQueueBackends_ConcurrentRetryDrainsDeliverJobsOnce(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		first, second := backend.new(t, redisClient), backend.new(t, redisClient)
+		const jobs = 20
+		for i := 1; i <= jobs; i++ {
+			require.NoError(t, first.EnqueueJob(newTestQueueJob(uint(i), models.AT_LEAST_ONCE)))
+			job, err := first.DequeueJob(time.Second)
+			require.NoError(t, err)
+			require.NotNil(t, job)
+			require.NoError(t, first.DeferJob(job, 0))
+		}
+		time.Sleep(1100 * time.Millisecond)
+
+		var wg sync.WaitGroup
+		for _, queue := range []WorkerQueueInterface{first, second} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, queue.ProcessRetryQueue())
+			}()
+		}
+		wg.Wait()
+
+		delivered := map[string]int{}
+		for {
+			job, err := second.DequeueJob(100 * time.Millisecond)
+			require.NoError(t, err)
+			if job == nil {
+				break
+			}
+			delivered[job.ID]++
+		}
+		assert.Len(t, delivered, jobs)
+		for id, count := range delivered {
+			assert.Equal(t, 1, count, id)
+		}
+	})
+}
+
+func TestQueueBackends_ReapsJobsOfDeadWorkers(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		dead, healthy := backend.new(t, redisClient), backend.new(t, redisClient)
+		atLeastOnce, atMostOnce := newTestQueueJob(1, models.AT_LEAST_ONCE), newTestQueueJob(2, models.AT_MOST_ONCE)
+		require.NoError(t, dead.EnqueueJob(atLeastOnce))
+		require.NoError(t, dead.EnqueueJob(atMostOnce))
+
+		for range 2 {
+			job, err := dead.DequeueJob(time.Second)
+			require.NoError(t, err)
+			require.NotNil(t, job)
+		}
+		time.Sleep(2 * testLease)
+
+		expired, err := healthy.ExpiredJobs()
+		require.NoError(t, err)
+		require.Len(t, expired, 2)
+
+		requeuedIDs, requeuedCount := map[string]bool{}, int64(0)
+		for _, job := range expired {
+			var onReapedCalled bool
+			reaped, requeued, err := healthy.ReapJob(job, func() {
+				onReapedCalled = true
+				// The job is not delivered again before onReaped returns
+				stats, err := healthy.GetQueueStats()
+				require.NoError(t, err)
+				assert.Equal(t, requeuedCount, stats["ready"], job.ID)
+			})
+			require.NoError(t, err)
+			assert.True(t, reaped, job.ID)
+			assert.True(t, onReapedCalled, job.ID)
+			requeuedIDs[job.ID] = requeued
+			if requeued {
+				requeuedCount++
+			}
+		}
+		assert.Equal(t, map[string]bool{atLeastOnce.ID: true, atMostOnce.ID: false}, requeuedIDs)
+		requireStats(t, healthy, 1, 0, 0, 0, 1)
+
+		redelivered, err := healthy.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, redelivered)
+		assert.Equal(t, atLeastOnce.ID, redelivered.ID)
+
+		// A late acknowledgement from the dead worker leaves the new delivery in flight
+		dead.DiscardJob(atLeastOnce.ID)
+		requireStats(t, healthy, 0, 1, 0, 0, 1)
+	})
+}
+
+func TestQueueBackends_ExtendLeasesKeepsJobsOfLiveWorkers(t *testing.T) {
+	forEachQueueBackend(t, func(t *testing.T, backend queueBackend, redisClient redisclient.RedisClientInterface) {
+		alive, other := backend.new(t, redisClient), backend.new(t, redisClient)
+		require.NoError(t, alive.EnqueueJob(newTestQueueJob(1, models.AT_LEAST_ONCE)))
+
+		job, err := alive.DequeueJob(time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+
+		// The job runs for several leases while its worker keeps extending them
+		for range 6 {
+			time.Sleep(testLease / 2)
+			require.NoError(t, alive.ExtendLeases())
+		}
+		expired, err := other.ExpiredJobs()
+		require.NoError(t, err)
+		assert.Empty(t, expired)
+
+		// Once the extensions stop, the job can be reaped
+		time.Sleep(2 * testLease)
+		expired, err = other.ExpiredJobs()
+		require.NoError(t, err)
+		require.Len(t, expired, 1)
+		assert.Equal(t, job.ID, expired[0].ID)
+	})
+}
+
+func TestQueueBackends_ReapJobLeavesJobsExtendedMeanwhile(t *testing.T) {
+	redisClient := newTestRedisClient(t)
+	queue, other := queueBackends[0].new(t, redisClient), queueBackends[0].new(t, redisClient)
+	require.NoError(t, queue.EnqueueJob(newTestQueueJob(1, models.AT_LEAST_ONCE)))
+
+	job, err := queue.DequeueJob(time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	time.Sleep(2 * testLease)
+
+	expired, err := other.ExpiredJobs()
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+
+	// The worker was only slow, and extends its lease before the job is reaped
+	require.NoError(t, queue.ExtendLeases())
+	reaped, requeued, err := other.ReapJob(expired[0], func() { t.Error("onReaped called for a job that was not reaped") })
+	require.NoError(t, err)
+	assert.False(t, reaped)
+	assert.False(t, requeued)
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/manyu/job-scheduler/internal/models"
+)
+
+// Priority policies
+const (
+	PriorityPolicyWeighted = "weighted" // Serve each priority in proportion to its weight while several have jobs
+	PriorityPolicyStrict   = "strict"   // Serve a priority only while every higher one is empty
+)
+
+// DefaultPriorityWeights gives high priority jobs six and normal ones three of every ten dequeues
+// while all priorities have jobs waiting
+var DefaultPriorityWeights = map[models.JobPriority]int{
+	models.PriorityHigh:   6,
+	models.PriorityNormal: 3,
+	models.PriorityLow:    1,
+}
+
+// PriorityScheduler decides which priority's ready queue a worker takes its next job from
+type PriorityScheduler struct {
+	strict  bool
+	weights map[models.JobPriority]int
+}
+
+// NewPriorityScheduler creates a priority scheduler for policy. Priorities without a weight take
+// their default weight.
+func NewPriorityScheduler(policy string, weights map[models.JobPriority]int) (*PriorityScheduler, error) {
+	switch policy {
+	case PriorityPolicyWeighted, "":
+	case PriorityPolicyStrict:
+		return &PriorityScheduler{strict: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown priority policy %q", policy)
+	}
+
+	resolved := make(map[models.JobPriority]int, len(models.Priorities))
+	for priority, weight := range weights {
+		if !priority.IsValid() {
+			return nil, fmt.Errorf("unknown priority %q", priority)
+		}
+		if weight <= 0 {
+			return nil, fmt.Errorf("weight of priority %s must be positive", priority)
+		}
+		resolved[priority] = weight
+	}
+	for _, priority := range models.Priorities {
+		if _, ok := resolved[priority]; !ok {
+			resolved[priority] = DefaultPriorityWeights[priority]
+		}
+	}
+	return &PriorityScheduler{weights: resolved}, nil
+}
+
+// defaultPriorityScheduler serves the priorities by their default weights
+func defaultPriorityScheduler() *PriorityScheduler {
+	return &PriorityScheduler{weights: DefaultPriorityWeights}
+}
+
+// Order returns every priority in the order a dequeue should try their ready queues. Strict
+// scheduling always returns the highest priority first. Weighted scheduling draws the order at random
+// so that, while all queues have jobs, each priority comes first in proportion to its weight.
+func (ps *PriorityScheduler) Order() []models.JobPriority {
+	if ps.strict {
+		return models.Priorities
+	}
+
+	remaining := append([]models.JobPriority(nil), models.Priorities...)
+	order := make([]models.JobPriority, 0, len(remaining))
+	for len(remaining) > 0 {
+		total := 0
+		for _, priority := range remaining {
+			total += ps.weights[priority]
+		}
+
+		pick := rand.IntN(total)
+		for i, priority := range remaining {
+			pick -= ps.weights[priority]
+			if pick < 0 {
+				order = append(order, priority)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return order
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// QueueRegistry is the set of names of every queue a job was enqueued to
+const QueueRegistry = "job_queue:queues"
+
+// queueRefreshInterval is how long a worker works from its list of known queues before reading it
+// again, so queues of newly used tags are picked up within this long
+const queueRefreshInterval = 5 * time.Second
+
+// QueueSubscription selects the queues a worker takes jobs from: the queues it names, and the
+// queues of every set of tags it has all of
+type QueueSubscription struct {
+	Queues []string       // Named queues
+	Tags   models.JobTags // Tags the worker has
+}
+
+// defaultSubscription takes jobs from the default queue only
+func defaultSubscription() QueueSubscription {
+	return QueueSubscription{Queues: []string{models.DefaultQueue}}
+}
+
+// Matches reports whether the subscription takes jobs from queue
+func (s QueueSubscription) Matches(queue string) bool {
+	if required, ok := models.TagQueueTags(queue); ok {
+		for _, tag := range required {
+			if !slices.Contains(s.Tags, tag) {
+				return false
+			}
+		}
+		return true
+	}
+	return slices.Contains(s.Queues, queue)
+}
+
+// QueueInfo describes the depth of one queue
+type QueueInfo struct {
+	Name            string                       `json:"name"`
+	Tags            models.JobTags               `json:"tags,omitempty"` // Tags a worker needs, for the queues of tagged jobs
+	Ready           int64                        `json:"ready"`
+	ReadyByPriority map[models.JobPriority]int64 `json:"readyByPriority"`
+}
+
+// queueDirectory keeps the names of the known queues, reading them from Redis at most every
+// queueRefreshInterval
+type queueDirectory struct {
+	client *redis.Client
+	ctx    context.Context
+
+	mu          sync.Mutex
+	names       []string
+	refreshedAt time.Time
+}
+
+func newQueueDirectory(client *redis.Client, ctx context.Context) *queueDirectory {
+	return &queueDirectory{client: client, ctx: ctx}
+}
+
+// register records queue as known in the same transaction as the job enqueued to it
+func (d *queueDirectory) register(pipe redis.Pipeliner, queue string) {
+	pipe.SAdd(d.ctx, QueueRegistry, queue)
+}
+
+// list returns the names of every known queue, including the default queue, in order
+func (d *queueDirectory) list() ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.names != nil && time.Since(d.refreshedAt) < queueRefreshInterval {
+		return d.names, nil
+	}
+
+	names, err := d.client.SMembers(d.ctx, QueueRegistry).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queues: %w", err)
+	}
+	if !slices.Contains(names, models.DefaultQueue) {
+		names = append(names, models.DefaultQueue)
+	}
+	slices.Sort(names)
+
+	d.names, d.refreshedAt = names, time.Now()
+	return names, nil
+}
+
+// subscribed returns the known queues subscription takes jobs from, plus the queues it names
+func (d *queueDirectory) subscribed(subscription QueueSubscription) ([]string, error) {
+	names, err := d.list()
+	if err != nil {
+		return nil, err
+	}
+
+	queues := slices.Clone(subscription.Queues)
+	for _, name := range names {
+		if subscription.Matches(name) && !slices.Contains(queues, name) {
+			queues = append(queues, name)
+		}
+	}
+	return queues, nil
+}
+
+// queueKey returns the key of the ready queue or stream of a queue and priority under base. The
+// default queue uses base itself and normal priority adds no suffix, so both keep the keys they had
+// before named queues and priorities existed.
+func queueKey(base, queue string, priority models.JobPriority) string {
+	key := base
+	if queue != "" && queue != models.DefaultQueue {
+		key += ":" + queue
+	}
+	if priority.OrNormal() != models.PriorityNormal {
+		key += ":" + string(priority)
+	}
+	return key
+}
+
+// dequeueOrder returns the keys of the queues in the order a dequeue should try them: by priority
+// in the given order, and among the queues of one priority in a random order so none is favoured
+func dequeueOrder(priorities []models.JobPriority, queues []string, key func(queue string, priority models.JobPriority) string) []string {
+	shuffled := slices.Clone(queues)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	keys := make([]string, 0, len(priorities)*len(shuffled))
+	for _, priority := range priorities {
+		for _, queue := range shuffled {
+			keys = append(keys, key(queue, priority))
+		}
+	}
+	return keys
+}
+
+// newQueueInfo returns an empty QueueInfo for queue
+func newQueueInfo(queue string) *QueueInfo {
+	info := &QueueInfo{
+		Name:            queue,
+		ReadyByPriority: make(map[models.JobPriority]int64, len(models.Priorities)),
+	}
+	if tags, ok := models.TagQueueTags(queue); ok {
+		info.Tags = tags
+	}
+	return info
+}
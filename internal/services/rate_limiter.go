@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	redisclient "github.com/manyu/job-scheduler/internal/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+// Rate limiter keys
+const (
+	RateLimitBucketBase = "ratelimit:bucket:" // Followed by the rate limit key, a hash of the tokens left and when they were counted
+	RateLimitActiveBase = "ratelimit:active:" // Followed by the rate limit key, scores the queue job IDs calling it by when their slot expires
+)
+
+// rateLimitSlotGrace is how long a concurrency slot outlives its job's timeout, so a worker that dies
+// mid-call gives its slot back on its own
+const rateLimitSlotGrace = inFlightLease
+
+// concurrencyRetryDelay is how long a job waits before trying again for a slot of a key at its
+// concurrency cap, since nothing tells when one will free up
+const concurrencyRetryDelay = time.Second
+
+// RateLimit bounds the calls workers make to one target. A zero rate or concurrency leaves that
+// bound off.
+type RateLimit struct {
+	Rate        float64 // Calls per second the bucket refills at
+	Burst       int     // Calls the bucket holds when full; zero is the rate rounded up
+	Concurrency int     // Calls in flight at once across all workers
+}
+
+// IsZero reports whether the limit bounds nothing
+func (l RateLimit) IsZero() bool {
+	return l.Rate <= 0 && l.Concurrency <= 0
+}
+
+// burst returns how many calls the bucket holds
+func (l RateLimit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return max(1, int(math.Ceil(l.Rate)))
+}
+
+// acquireScript takes a concurrency slot and a token for a call, or neither. Concurrency slots whose
+// holder did not release them by their expiry are dropped first. Returns 0 when the call may go ahead,
+// -1 when the key is at its concurrency cap, or the milliseconds until a token is available.
+// KEYS: bucket, active set
+// ARGV: now in ms, rate per second, burst, concurrency, holder, slot expiry in ms
+var acquireScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local concurrency = tonumber(ARGV[4])
+
+if concurrency > 0 then
+	redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', now)
+	if not redis.call('ZSCORE', KEYS[2], ARGV[5]) and redis.call('ZCARD', KEYS[2]) >= concurrency then
+		return -1
+	end
+end
+
+if rate > 0 then
+	local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+	local tokens = tonumber(bucket[1]) or burst
+	local updated = tonumber(bucket[2]) or now
+	tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+	if tokens < 1 then
+		return math.ceil((1 - tokens) * 1000 / rate)
+	end
+	redis.call('HSET', KEYS[1], 'tokens', tostring(tokens - 1), 'updated', now)
+	redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+end
+
+if concurrency > 0 then
+	redis.call('ZADD', KEYS[2], ARGV[6], ARGV[5])
+	redis.call('PEXPIREAT', KEYS[2], redis.call('ZRANGE', KEYS[2], -1, -1, 'WITHSCORES')[2])
+end
+return 0
+`)
+
+// RateLimiter enforces rate limits and concurrency caps per target across all workers. Every target
+// has a token bucket refilled at its rate and a set of the calls in flight, both kept in Redis so
+// that workers share them.
+type RateLimiter struct {
+	client   *redis.Client
+	ctx      context.Context
+	defaults RateLimit            // Applies to keys without a limit of their own
+	limits   map[string]RateLimit // By rate limit key
+}
+
+// NewRateLimiter creates a rate limiter applying limits to their keys and defaults to all other keys
+func NewRateLimiter(redisClient redisclient.RedisClientInterface, defaults RateLimit, limits map[string]RateLimit) *RateLimiter {
+	normalized := make(map[string]RateLimit, len(limits))
+	for key, limit := range limits {
+		normalized[strings.ToLower(key)] = limit
+	}
+	return &RateLimiter{
+		client:   redisClient.GetClient(),
+		ctx:      redisClient.GetContext(),
+		defaults: defaults,
+		limits:   normalized,
+	}
+}
+
+// rateLimitKey returns the key a job's calls are limited under: its rateLimitKey if it declares one,
+// otherwise the host and port of its API. Keys are case insensitive.
+func rateLimitKey(job *models.QueueJob) string {
+	if job.RateLimitKey != "" {
+		return strings.ToLower(job.RateLimitKey)
+	}
+	if u, err := url.Parse(job.API); err == nil && u.Host != "" {
+		return strings.ToLower(u.Host)
+	}
+	return strings.ToLower(job.API)
+}
+
+// Limit returns the limit applied to key
+func (rl *RateLimiter) Limit(key string) RateLimit {
+	if limit, ok := rl.limits[key]; ok {
+		return limit
+	}
+	return rl.defaults
+}
+
+// Acquire takes a token and a concurrency slot for a call of holder under key, holding the slot for
+// at most hold. It returns zero when the call may go ahead, and otherwise how long to wait before
+// trying again. A call that went ahead must be released with Release.
+func (rl *RateLimiter) Acquire(key, holder string, hold time.Duration) (time.Duration, error) {
+	limit := rl.Limit(key)
+	if limit.IsZero() {
+		return 0, nil
+	}
+
+	now := time.Now()
+	keys := []string{RateLimitBucketBase + key, RateLimitActiveBase + key}
+	wait, err := acquireScript.Run(rl.ctx, rl.client, keys,
+		now.UnixMilli(), limit.Rate, limit.burst(), limit.Concurrency, holder, now.Add(hold).UnixMilli()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire rate limit of %s: %w", key, err)
+	}
+
+	switch {
+	case wait < 0:
+		return concurrencyRetryDelay, nil
+	case wait > 0:
+		return time.Duration(wait) * time.Millisecond, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Release gives back the concurrency slot holder took under key
+func (rl *RateLimiter) Release(key, holder string) {
+	if rl.Limit(key).Concurrency <= 0 {
+		return
+	}
+	if err := rl.client.ZRem(rl.ctx, RateLimitActiveBase+key, holder).Err(); err != nil {
+		log.Printf("Warning: failed to release rate limit slot of %s for job %s: %v", key, holder, err)
+	}
+}
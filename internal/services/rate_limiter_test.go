@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitKey(t *testing.T) {
+	assert.Equal(t, "api.partner.com", rateLimitKey(&models.QueueJob{API: "https://API.partner.com/v1/hook"}))
+	assert.Equal(t, "api.partner.com:8443", rateLimitKey(&models.QueueJob{API: "https://api.partner.com:8443/hook"}))
+	assert.Equal(t, "partner-billing", rateLimitKey(&models.QueueJob{API: "https://api.partner.com/hook", RateLimitKey: "Partner-Billing"}))
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	limiter := &RateLimiter{
+		defaults: RateLimit{Rate: 5},
+		limits:   map[string]RateLimit{"api.partner.com": {Concurrency: 2}},
+	}
+
+	assert.Equal(t, RateLimit{Concurrency: 2}, limiter.Limit("api.partner.com"))
+	assert.Equal(t, RateLimit{Rate: 5}, limiter.Limit("example.com"))
+	assert.Equal(t, 5, RateLimit{Rate: 4.5}.burst())
+	assert.Equal(t, 1, RateLimit{Rate: 0.2}.burst())
+	assert.True(t, RateLimit{Burst: 10}.IsZero())
+}
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	redisClient := newTestRedisClient(t)
+	limiter := NewRateLimiter(redisClient, RateLimit{}, map[string]RateLimit{"api.partner.com": {Rate: 10, Burst: 2}})
+
+	// A full bucket lets a burst through, then calls are spaced by the rate
+	for _, holder := range []string{"job_1", "job_2"} {
+		wait, err := limiter.Acquire("api.partner.com", holder, time.Minute)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+	wait, err := limiter.Acquire("api.partner.com", "job_3", time.Minute)
+	require.NoError(t, err)
+	assert.Greater(t, wait, time.Duration(0))
+	assert.LessOrEqual(t, wait, 100*time.Millisecond)
+
+	time.Sleep(wait)
+	wait, err = limiter.Acquire("api.partner.com", "job_3", time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// Keys without a limit are not counted
+	for range 10 {
+		wait, err := limiter.Acquire("example.com", "job_4", time.Minute)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+}
+
+func TestRateLimiter_ConcurrencyCap(t *testing.T) {
+	redisClient := newTestRedisClient(t)
+	limiter := NewRateLimiter(redisClient, RateLimit{Concurrency: 1}, nil)
+
+	wait, err := limiter.Acquire("api.partner.com", "job_1", time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	wait, err = limiter.Acquire("api.partner.com", "job_2", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, concurrencyRetryDelay, wait)
+
+	// Other keys have slots of their own
+	wait, err = limiter.Acquire("example.com", "job_3", time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	limiter.Release("api.partner.com", "job_1")
+	wait, err = limiter.Acquire("api.partner.com", "job_2", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// A slot its holder never released frees up once it expires
+	time.Sleep(100 * time.Millisecond)
+	wait, err = limiter.Acquire("api.partner.com", "job_4", time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/pkg/signature"
+)
+
+// RequestSigner signs the calls of jobs so their targets can verify them with the signature package.
+// Jobs select a named key, such as one per tenant, with signingKey; the others use the default
+// secrets. Every active secret of a key signs each call, so a secret can be rotated by adding the new
+// one, moving the receivers over and then removing the old one.
+type RequestSigner struct {
+	defaults [][]byte            // Secrets of jobs without a signing key; none leaves their calls unsigned
+	keys     map[string][][]byte // Secrets by signing key
+}
+
+// NewRequestSigner creates a signer with the default secrets and the secrets of each named key
+func NewRequestSigner(defaults []string, keys map[string][]string) *RequestSigner {
+	signer := &RequestSigner{
+		defaults: secretBytes(defaults),
+		keys:     make(map[string][][]byte, len(keys)),
+	}
+	for name, secrets := range keys {
+		signer.keys[name] = secretBytes(secrets)
+	}
+	return signer
+}
+
+// secretBytes converts configured secrets to HMAC keys
+func secretBytes(secrets []string) [][]byte {
+	keys := make([][]byte, len(secrets))
+	for i, secret := range secrets {
+		keys[i] = []byte(secret)
+	}
+	return keys
+}
+
+// Sign adds the signature headers to a call of job whose body is body. It fails for a job whose
+// signing key is not configured rather than send the call unsigned.
+func (s *RequestSigner) Sign(req *http.Request, body []byte, job *models.QueueJob) error {
+	secrets := s.defaults
+	if job.SigningKey != "" {
+		var ok bool
+		if secrets, ok = s.keys[job.SigningKey]; !ok || len(secrets) == 0 {
+			return fmt.Errorf("signing key %q is not configured", job.SigningKey)
+		}
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	signature.Sign(req, body, secrets, time.Now())
+	return nil
+}
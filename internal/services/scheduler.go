@@ -2,8 +2,13 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/manyu/job-scheduler/internal/models"
 	redisclient "github.com/manyu/job-scheduler/internal/redis"
@@ -17,49 +22,118 @@ type SchedulerService struct {
 	scheduleParser *utils.ScheduleParser
 	jobQueue       JobQueueServiceInterface
 	redisClient    redisclient.RedisClientInterface
+	instanceID     string // Identifies this scheduler when claiming due schedules
 }
 
+// scheduleClaimLease is how long a claimed schedule stays reserved for this scheduler. It only
+// matters if the scheduler dies between claiming and dispatching, after which another one takes over.
+const scheduleClaimLease = 30 * time.Second
+
+// scheduleDispatchExpiry is how long a dispatched occurrence may go without completing before it is
+// dispatched again. Completions are normally reported by the worker, so this only recovers occurrences
+// whose queue entry or completion was lost; it exceeds the default scheduler.max_job_timeout.
+const scheduleDispatchExpiry = 2 * time.Hour
+
 // NewSchedulerService creates a new scheduler service
-func NewSchedulerService(storage storage.Storage, redisClient redisclient.RedisClientInterface) *SchedulerService {
-	jobQueue := NewJobQueueService(redisClient)
+func NewSchedulerService(storage storage.Storage, jobQueue JobQueueServiceInterface, redisClient redisclient.RedisClientInterface) *SchedulerService {
 	return &SchedulerService{
 		storage:        storage,
 		scheduleParser: utils.NewScheduleParser(),
 		jobQueue:       jobQueue,
 		redisClient:    redisClient,
+		instanceID:     newInstanceID(),
 	}
 }
 
-// ProcessReadyJobs processes jobs that are ready for execution by enqueueing them
-func (s *SchedulerService) ProcessReadyJobs(ctx context.Context, limit int) error {
-	jobs, schedules, err := s.storage.GetJobsReadyForExecution(limit)
+// newInstanceID returns an ID that is unique per scheduler, including several in one process
+func newInstanceID() string {
+	hostname, err := os.Hostname()
 	if err != nil {
-		return fmt.Errorf("failed to get ready jobs: %w", err)
+		hostname = "unknown"
 	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
 
-	if len(jobs) == 0 {
-		return nil
-	}
+// InstanceID returns the ID this scheduler claims due schedules under
+func (s *SchedulerService) InstanceID() string {
+	return s.instanceID
+}
+
+// ProcessReadyJobs processes jobs that are ready for execution by enqueueing them.
+// Due schedules are claimed first, so with several schedulers running each occurrence is
+// dispatched by only one of them.
+func (s *SchedulerService) ProcessReadyJobs(ctx context.Context, limit int) error {
+	return s.ProcessReadyJobsInShards(ctx, limit, nil)
+}
+
+// ProcessReadyJobsInShards is ProcessReadyJobs restricted to the jobs in shards, or all jobs if nil
+func (s *SchedulerService) ProcessReadyJobsInShards(ctx context.Context, limit int, shards *storage.ShardFilter) error {
+	return s.processReadyJobs(ctx, limit, 0, shards)
+}
 
-	// Process retry queue first
+// ProcessReadyJobsAsLeader is ProcessReadyJobs for the elected leader of term. Claims and dispatches
+// are fenced by the term, so should this scheduler stall past its lease, it cannot dispatch the
+// occurrences a later leader claimed.
+func (s *SchedulerService) ProcessReadyJobsAsLeader(ctx context.Context, limit int, term int64) error {
+	return s.processReadyJobs(ctx, limit, term, nil)
+}
+
+// processReadyJobs claims and enqueues due occurrences, fenced by term unless it is 0
+func (s *SchedulerService) processReadyJobs(ctx context.Context, limit int, term int64, shards *storage.ShardFilter) error {
+	// Deferred and retrying jobs that fell due go back to the ready queue even on polls with nothing to claim
 	if err := s.jobQueue.ProcessRetryQueue(); err != nil {
 		log.Printf("Error processing retry queue: %v", err)
 	}
 
+	jobs, schedules, err := s.storage.ClaimJobsReadyForExecution(s.instanceID, term, scheduleClaimLease, scheduleDispatchExpiry, limit, shards)
+	if err != nil {
+		return fmt.Errorf("failed to claim ready jobs: %w", err)
+	}
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
 	// Enqueue jobs for worker processing
 	enqueuedCount := 0
+	now := time.Now()
 	for i, job := range jobs {
 		schedule := schedules[i]
 
+		// Runs missed beyond the job's threshold are handled by its misfire policy first
+		enqueue, err := s.applyMisfirePolicy(job, schedule, now)
+		if err != nil {
+			log.Printf("Failed to apply misfire policy for job %d: %v", job.ID, err)
+			s.releaseClaim(job.ID)
+			continue
+		}
+		if !enqueue {
+			s.releaseClaim(job.ID)
+			continue
+		}
+
 		// Create queue job
 		queueJob := models.NewQueueJob(job, schedule)
 
 		// Enqueue the job
 		if err := s.jobQueue.EnqueueJob(queueJob); err != nil {
 			log.Printf("Failed to enqueue job %d: %v", job.ID, err)
+			s.releaseClaim(job.ID)
 			continue
 		}
 
+		// The claim is only given up once the occurrence is enqueued. Should the scheduler die in
+		// between, the lease expires and the occurrence is dispatched again under the same occurrence ID.
+		if err := s.storage.MarkScheduleDispatched(job.ID, s.instanceID, term); err != nil {
+			if errors.Is(err, storage.ErrScheduleClaimLost) {
+				log.Printf("Claim on job %d expired before dispatch, occurrence %s may be enqueued twice", job.ID, queueJob.OccurrenceID)
+			} else {
+				log.Printf("Failed to mark job %d as dispatched: %v", job.ID, err)
+			}
+		}
+
 		enqueuedCount++
 	}
 
@@ -70,11 +144,147 @@ func (s *SchedulerService) ProcessReadyJobs(ctx context.Context, limit int) erro
 	return nil
 }
 
+// releaseClaim lets another poll pick up a claimed schedule that was not dispatched
+func (s *SchedulerService) releaseClaim(jobID uint) {
+	if err := s.storage.ReleaseScheduleClaim(jobID, s.instanceID); err != nil {
+		log.Printf("Failed to release claim on job %d: %v", jobID, err)
+	}
+}
+
+// maxMisfireScan bounds how many missed occurrences are examined for one job per poll,
+// so a frequent schedule after a long outage is worked off over several polls
+const maxMisfireScan = 1000
+
+// applyMisfirePolicy handles a due schedule whose run is later than the job's misfire threshold.
+// Missed occurrences that will not run are recorded as SKIPPED executions. It returns whether
+// the run at schedule.NextExecutionTime, which the policy may move forward, should be enqueued.
+func (s *SchedulerService) applyMisfirePolicy(job *models.Job, schedule *models.JobSchedule, now time.Time) (bool, error) {
+	cutoff := now.Add(-job.MisfireThreshold())
+	if !schedule.NextExecutionTime.Before(cutoff) {
+		return true, nil
+	}
+
+	missed, next, truncated, err := s.missedOccurrences(job, schedule.NextExecutionTime, cutoff, now)
+	if err != nil {
+		return false, err
+	}
+
+	policy := job.MisfirePolicy
+	if policy == "" {
+		policy = models.MisfireFireOnceNow
+	}
+
+	// When the scan was truncated more occurrences were missed than examined, so none of them is the
+	// latest and all are skipped; the next poll continues from where the scan stopped
+
+	var skipped []time.Time
+	var fire *time.Time
+	switch policy {
+	case models.MisfireSkipToNext:
+		skipped = missed
+	case models.MisfireRunAllMissed:
+		if !truncated && len(missed) <= models.MaxMisfireCatchUpRuns {
+			return true, nil
+		}
+		if truncated {
+			skipped = missed
+		} else {
+			keep := len(missed) - models.MaxMisfireCatchUpRuns
+			skipped, fire = missed[:keep], &missed[keep]
+		}
+	case models.MisfireFireOnceNow:
+		if !truncated && len(missed) == 1 {
+			return true, nil
+		}
+		if truncated {
+			skipped = missed
+		} else {
+			skipped, fire = missed[:len(missed)-1], &missed[len(missed)-1]
+		}
+	default:
+		return false, fmt.Errorf("unknown misfire policy %q", policy)
+	}
+
+	for _, occurrence := range skipped {
+		execution := &models.JobExecution{
+			JobID:         job.ID,
+			OccurrenceID:  models.OccurrenceID(job.ID, occurrence),
+			Status:        models.StatusSkipped,
+			ExecutionTime: occurrence,
+			Error:         fmt.Sprintf("missed by %v, skipped by misfire policy %s", now.Sub(occurrence).Round(time.Second), policy),
+		}
+		if err := s.storage.CreateJobExecution(execution); err != nil {
+			return false, fmt.Errorf("failed to record skipped run: %w", err)
+		}
+	}
+	if len(skipped) > 0 {
+		log.Printf("Job %d missed %d runs, skipped by misfire policy %s", job.ID, len(skipped), policy)
+	}
+
+	if fire != nil {
+		if err := s.storage.UpdateJobSchedule(job.ID, *fire); err != nil {
+			return false, fmt.Errorf("failed to update job schedule: %w", err)
+		}
+		schedule.NextExecutionTime = *fire
+		return true, nil
+	}
+
+	if next == nil {
+		if !job.IsRecurring {
+			return false, s.storage.DeleteJobSchedule(job.ID)
+		}
+		return false, s.finishJob(job, "has no runs left after missed runs")
+	}
+
+	if err := s.storage.UpdateJobSchedule(job.ID, *next); err != nil {
+		return false, fmt.Errorf("failed to update job schedule: %w", err)
+	}
+	return false, nil
+}
+
+// missedOccurrences lists the occurrences from first up to cutoff and returns the occurrence after them,
+// or nil when the job has none left. truncated reports that the scan stopped at maxMisfireScan.
+func (s *SchedulerService) missedOccurrences(job *models.Job, first, cutoff, now time.Time) ([]time.Time, *time.Time, bool, error) {
+	missed := []time.Time{first}
+	if !job.IsRecurring || job.IsOneShot() {
+		return missed, nil, false, nil
+	}
+
+	// Intervals anchored on completion have no grid of missed slots; the next one is an interval from now
+	fromTime, startAt := first, job.StartAt
+	if _, ok := s.scheduleParser.ParseInterval(job.Schedule); ok && job.IntervalAnchor == models.IntervalFromCompletion {
+		fromTime, startAt = now, nil
+	}
+
+	for {
+		next, err := s.scheduleParser.CalculateNextExecutionInWindow(job.Schedule, job.Timezone, fromTime, startAt)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to calculate next execution time: %w", err)
+		}
+		if job.EndAt != nil && next.After(*job.EndAt) {
+			return missed, nil, false, nil
+		}
+		if !next.Before(cutoff) {
+			return missed, &next, false, nil
+		}
+		if len(missed) == maxMisfireScan {
+			return missed, &next, true, nil
+		}
+		missed = append(missed, next)
+		fromTime = next
+	}
+}
+
 // GetQueueStats returns queue statistics
 func (s *SchedulerService) GetQueueStats() (map[string]int64, error) {
 	return s.jobQueue.GetQueueStats()
 }
 
+// ListQueues returns the depth of every known queue
+func (s *SchedulerService) ListQueues() ([]*QueueInfo, error) {
+	return s.jobQueue.ListQueues()
+}
+
 // HandleJobCompletion handles job completion from workers
 func (s *SchedulerService) HandleJobCompletion(jobID uint, success bool) error {
 	// Get the job and schedule
@@ -88,6 +298,12 @@ func (s *SchedulerService) HandleJobCompletion(jobID uint, success bool) error {
 		return fmt.Errorf("failed to get job schedule: %w", err)
 	}
 
+	// Retries of a run complete after the schedule has already moved past it
+	if schedule.NextExecutionTime.After(time.Now()) {
+		log.Printf("Job %d already rescheduled for %v, nothing to do", job.ID, schedule.NextExecutionTime)
+		return nil
+	}
+
 	if success {
 		return s.handleSuccessfulExecution(job, schedule)
 	} else {
@@ -106,18 +322,16 @@ func (s *SchedulerService) handleSuccessfulExecution(job *models.Job, schedule *
 		return nil
 	}
 
-	// For recurring jobs, calculate next execution time
-	nextExecutionTime, err := s.scheduleParser.CalculateNextExecutionFromTime(job.Schedule, schedule.NextExecutionTime)
+	// For recurring jobs, move on to the next execution time
+	nextExecutionTime, err := s.advanceSchedule(job, schedule)
 	if err != nil {
-		return fmt.Errorf("failed to calculate next execution time: %w", err)
+		return err
 	}
-
-	// Update the schedule with next execution time
-	if err := s.storage.UpdateJobSchedule(job.ID, nextExecutionTime); err != nil {
-		return fmt.Errorf("failed to update job schedule: %w", err)
+	if nextExecutionTime == nil {
+		return nil
 	}
 
-	log.Printf("Recurring job %d completed successfully, rescheduled for %v", job.ID, nextExecutionTime)
+	log.Printf("Recurring job %d completed successfully, rescheduled for %v", job.ID, *nextExecutionTime)
 	return nil
 }
 
@@ -133,19 +347,66 @@ func (s *SchedulerService) handleFailedExecution(job *models.Job, schedule *mode
 	}
 
 	// For recurring jobs, reschedule for next occurrence
-	nextExecutionTime, err := s.scheduleParser.CalculateNextExecutionFromTime(job.Schedule, schedule.NextExecutionTime)
+	nextExecutionTime, err := s.advanceSchedule(job, schedule)
 	if err != nil {
-		return fmt.Errorf("failed to calculate next execution time: %w", err)
+		return err
 	}
-	if err := s.storage.UpdateJobSchedule(job.ID, nextExecutionTime); err != nil {
-		return fmt.Errorf("failed to update job schedule: %w", err)
+	if nextExecutionTime == nil {
+		return nil
 	}
 
 	if job.Type == models.AT_MOST_ONCE {
-		log.Printf("Recurring AT_MOST_ONCE job %d failed, rescheduled for next occurrence: %v (no retry)", job.ID, nextExecutionTime)
+		log.Printf("Recurring AT_MOST_ONCE job %d failed, rescheduled for next occurrence: %v (no retry)", job.ID, *nextExecutionTime)
 	} else {
-		log.Printf("Recurring AT_LEAST_ONCE job %d failed, rescheduled for next occurrence: %v", job.ID, nextExecutionTime)
+		log.Printf("Recurring AT_LEAST_ONCE job %d failed, rescheduled for next occurrence: %v", job.ID, *nextExecutionTime)
+	}
+	return nil
+}
+
+// advanceSchedule counts the finished run and moves the schedule to the next execution time.
+// Once maxRuns or endAt is reached the job is deactivated instead and nil is returned.
+func (s *SchedulerService) advanceSchedule(job *models.Job, schedule *models.JobSchedule) (*time.Time, error) {
+	job.RunCount++
+	if job.MaxRuns > 0 && job.RunCount >= job.MaxRuns {
+		return nil, s.finishJob(job, fmt.Sprintf("reached maxRuns (%d)", job.MaxRuns))
+	}
+
+	// Intervals anchored on completion are measured from now rather than from the scheduled time
+	fromTime, startAt := schedule.NextExecutionTime, job.StartAt
+	if _, ok := s.scheduleParser.ParseInterval(job.Schedule); ok && job.IntervalAnchor == models.IntervalFromCompletion {
+		fromTime, startAt = time.Now(), nil
+	}
+
+	nextExecutionTime, err := s.scheduleParser.CalculateNextExecutionInWindow(job.Schedule, job.Timezone, fromTime, startAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate next execution time: %w", err)
 	}
+
+	if job.EndAt != nil && nextExecutionTime.After(*job.EndAt) {
+		return nil, s.finishJob(job, fmt.Sprintf("reached endAt (%v)", *job.EndAt))
+	}
+
+	if err := s.storage.UpdateJob(job); err != nil {
+		return nil, fmt.Errorf("failed to update job run count: %w", err)
+	}
+	if err := s.storage.UpdateJobSchedule(job.ID, nextExecutionTime); err != nil {
+		return nil, fmt.Errorf("failed to update job schedule: %w", err)
+	}
+
+	return &nextExecutionTime, nil
+}
+
+// finishJob deactivates a recurring job that has no runs left and removes its schedule
+func (s *SchedulerService) finishJob(job *models.Job, reason string) error {
+	job.IsActive = false
+	if err := s.storage.UpdateJob(job); err != nil {
+		return fmt.Errorf("failed to deactivate finished job: %w", err)
+	}
+	if err := s.storage.DeleteJobSchedule(job.ID); err != nil {
+		return fmt.Errorf("failed to delete schedule for finished job: %w", err)
+	}
+
+	log.Printf("Recurring job %d %s, deactivated after %d runs", job.ID, reason, job.RunCount)
 	return nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/storage"
 	"github.com/manyu/job-scheduler/internal/utils"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
@@ -14,9 +15,10 @@ import (
 
 // MockStorage for testing scheduler service
 type MockSchedulerStorage struct {
-	jobs      map[uint]*models.Job
-	schedules map[uint]*models.JobSchedule
-	nextID    uint
+	jobs       map[uint]*models.Job
+	schedules  map[uint]*models.JobSchedule
+	executions []*models.JobExecution
+	nextID     uint
 }
 
 func NewMockSchedulerStorage() *MockSchedulerStorage {
@@ -68,6 +70,33 @@ func (m *MockSchedulerStorage) GetAllJobs() ([]*models.Job, error) {
 	return activeJobs, nil
 }
 
+func (m *MockSchedulerStorage) UpdateJob(job *models.Job) error {
+	if _, exists := m.jobs[job.ID]; !exists {
+		return assert.AnError
+	}
+	job.UpdatedAt = time.Now()
+	m.jobs[job.ID] = job
+	return nil
+}
+
+func (m *MockSchedulerStorage) UpdateJobWithSchedule(job *models.Job, schedule *models.JobSchedule) error {
+	if err := m.UpdateJob(job); err != nil {
+		return err
+	}
+	schedule.JobID = job.ID
+	m.schedules[job.ID] = schedule
+	return nil
+}
+
+func (m *MockSchedulerStorage) DeleteJob(id uint) error {
+	if _, exists := m.jobs[id]; !exists {
+		return assert.AnError
+	}
+	delete(m.jobs, id)
+	delete(m.schedules, id)
+	return nil
+}
+
 func (m *MockSchedulerStorage) CreateJobSchedule(schedule *models.JobSchedule) error {
 	schedule.ID = m.nextID
 	schedule.CreatedAt = time.Now()
@@ -91,6 +120,7 @@ func (m *MockSchedulerStorage) UpdateJobSchedule(jobID uint, nextExecutionTime t
 		return assert.AnError
 	}
 	schedule.NextExecutionTime = nextExecutionTime
+	schedule.DispatchedAt = nil
 	schedule.CreatedAt = time.Now()
 	return nil
 }
@@ -100,20 +130,32 @@ func (m *MockSchedulerStorage) DeleteJobSchedule(jobID uint) error {
 	return nil
 }
 
-func (m *MockSchedulerStorage) GetJobsReadyForExecution(limit int) ([]*models.Job, []*models.JobSchedule, error) {
+func (m *MockSchedulerStorage) ClaimJobsReadyForExecution(owner string, term int64, lease, dispatchExpiry time.Duration, limit int, shards *storage.ShardFilter) ([]*models.Job, []*models.JobSchedule, error) {
 	var readyJobs []*models.Job
 	var readySchedules []*models.JobSchedule
 	now := time.Now()
 
 	for _, job := range m.jobs {
-		if !job.IsActive {
+		if !job.IsActive || !shards.Contains(job.ID) {
 			continue
 		}
 		schedule, exists := m.schedules[job.ID]
-		if !exists {
+		if !exists || (schedule.DispatchedAt != nil && !schedule.DispatchedAt.Before(now.Add(-dispatchExpiry))) {
+			continue
+		}
+		if schedule.LeaseExpiresAt != nil && !schedule.LeaseExpiresAt.Before(now) {
+			continue
+		}
+		if term > 0 && schedule.LeaderTerm > term {
 			continue
 		}
 		if schedule.NextExecutionTime.Before(now) || schedule.NextExecutionTime.Equal(now) {
+			expiresAt := now.Add(lease)
+			schedule.LeaseOwner = owner
+			schedule.LeaseExpiresAt = &expiresAt
+			if term > 0 {
+				schedule.LeaderTerm = term
+			}
 			readyJobs = append(readyJobs, job)
 			readySchedules = append(readySchedules, schedule)
 		}
@@ -122,10 +164,39 @@ func (m *MockSchedulerStorage) GetJobsReadyForExecution(limit int) ([]*models.Jo
 	return readyJobs, readySchedules, nil
 }
 
+func (m *MockSchedulerStorage) MarkScheduleDispatched(jobID uint, owner string, term int64) error {
+	schedule, exists := m.schedules[jobID]
+	if !exists || schedule.LeaseOwner != owner || (term > 0 && schedule.LeaderTerm > term) {
+		return storage.ErrScheduleClaimLost
+	}
+	now := time.Now()
+	schedule.DispatchedAt = &now
+	schedule.LeaseOwner = ""
+	schedule.LeaseExpiresAt = nil
+	return nil
+}
+
+func (m *MockSchedulerStorage) MaxScheduleLeaderTerm() (int64, error) {
+	var term int64
+	for _, schedule := range m.schedules {
+		term = max(term, schedule.LeaderTerm)
+	}
+	return term, nil
+}
+
+func (m *MockSchedulerStorage) ReleaseScheduleClaim(jobID uint, owner string) error {
+	if schedule, exists := m.schedules[jobID]; exists && schedule.LeaseOwner == owner {
+		schedule.LeaseOwner = ""
+		schedule.LeaseExpiresAt = nil
+	}
+	return nil
+}
+
 func (m *MockSchedulerStorage) CreateJobExecution(execution *models.JobExecution) error {
 	execution.ID = m.nextID
 	execution.CreatedAt = time.Now()
 	execution.UpdatedAt = time.Now()
+	m.executions = append(m.executions, execution)
 	m.nextID++
 	return nil
 }
@@ -144,10 +215,55 @@ func (m *MockSchedulerStorage) GetJobExecutionInProgress(jobID uint) (*models.Jo
 	return nil, nil
 }
 
+func (m *MockSchedulerStorage) GetJobByIdempotencyKey(key string) (*models.Job, error) {
+	return nil, storage.ErrJobNotFound
+}
+
+func (m *MockSchedulerStorage) GetJobExecutionsInProgress(jobID uint) ([]*models.JobExecution, error) {
+	return nil, nil
+}
+
+func (m *MockSchedulerStorage) ExpireJobExecutions(jobID uint, startedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockSchedulerStorage) CancelJobExecution(id uint, reason string) (bool, error) {
+	return false, nil
+}
+
+func (m *MockSchedulerStorage) CreateCredential(credential *models.Credential) error {
+	return nil
+}
+
+func (m *MockSchedulerStorage) GetCredential(id uint) (*models.Credential, error) {
+	return nil, storage.ErrCredentialNotFound
+}
+
+func (m *MockSchedulerStorage) GetCredentialByName(name string) (*models.Credential, error) {
+	return nil, storage.ErrCredentialNotFound
+}
+
+func (m *MockSchedulerStorage) ListCredentials() ([]*models.Credential, error) {
+	return nil, nil
+}
+
+func (m *MockSchedulerStorage) UpdateCredential(credential *models.Credential) error {
+	return nil
+}
+
+func (m *MockSchedulerStorage) DeleteCredential(id uint) error {
+	return nil
+}
+
+func (m *MockSchedulerStorage) CountJobsUsingCredential(name string) (int64, error) {
+	return 0, nil
+}
+
 // MockJobQueue for testing scheduler service
 type MockJobQueue struct {
-	enqueuedJobs []*models.QueueJob
-	stats        map[string]int64
+	enqueuedJobs   []*models.QueueJob
+	stats          map[string]int64
+	retryQueueRuns int
 }
 
 func NewMockJobQueue() *MockJobQueue {
@@ -181,7 +297,12 @@ func (m *MockJobQueue) GetQueueStats() (map[string]int64, error) {
 	return m.stats, nil
 }
 
+func (m *MockJobQueue) ListQueues() ([]*QueueInfo, error) {
+	return []*QueueInfo{newQueueInfo(models.DefaultQueue)}, nil
+}
+
 func (m *MockJobQueue) ProcessRetryQueue() error {
+	m.retryQueueRuns++
 	return nil
 }
 
@@ -245,6 +366,179 @@ func TestSchedulerService_ProcessReadyJobs_Unit(t *testing.T) {
 	assert.Equal(t, job.ID, mockJobQueue.enqueuedJobs[0].JobID)
 }
 
+func TestSchedulerService_ProcessReadyJobs_DispatchesOccurrenceOnce_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	mockJobQueue := NewMockJobQueue()
+
+	// Two schedulers polling the same storage
+	newScheduler := func(instanceID string) *SchedulerService {
+		return &SchedulerService{
+			storage:        mockStorage,
+			jobQueue:       mockJobQueue,
+			redisClient:    &MockRedisClient{},
+			scheduleParser: utils.NewScheduleParser(),
+			instanceID:     instanceID,
+		}
+	}
+	first, second := newScheduler("scheduler-a"), newScheduler("scheduler-b")
+
+	job := &models.Job{
+		Schedule:      "@every 1h",
+		API:           "https://httpbin.org/status/200",
+		Type:          models.AT_LEAST_ONCE,
+		IsRecurring:   true,
+		MaxRetryCount: 3,
+		IsActive:      true,
+	}
+	mockStorage.CreateJob(job)
+
+	scheduledAt := time.Now().Add(-10 * time.Second).Truncate(time.Second)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: scheduledAt})
+
+	ctx := context.Background()
+	require.NoError(t, first.ProcessReadyJobs(ctx, 100))
+	require.NoError(t, second.ProcessReadyJobs(ctx, 100))
+	require.NoError(t, first.ProcessReadyJobs(ctx, 100))
+
+	require.Len(t, mockJobQueue.enqueuedJobs, 1)
+	assert.Equal(t, models.OccurrenceID(job.ID, scheduledAt), mockJobQueue.enqueuedJobs[0].OccurrenceID)
+
+	schedule, err := mockStorage.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, schedule.DispatchedAt)
+	assert.Empty(t, schedule.LeaseOwner)
+
+	// Retries keep the occurrence ID
+	retry := mockJobQueue.enqueuedJobs[0].IncrementRetry()
+	assert.NotEqual(t, mockJobQueue.enqueuedJobs[0].ID, retry.ID)
+	assert.Equal(t, mockJobQueue.enqueuedJobs[0].OccurrenceID, retry.OccurrenceID)
+
+	// Once the schedule moves on, the next occurrence is dispatched by whichever scheduler claims it
+	nextAt := scheduledAt.Add(5 * time.Second)
+	require.NoError(t, mockStorage.UpdateJobSchedule(job.ID, nextAt))
+	require.NoError(t, second.ProcessReadyJobs(ctx, 100))
+	require.NoError(t, first.ProcessReadyJobs(ctx, 100))
+
+	require.Len(t, mockJobQueue.enqueuedJobs, 2)
+	assert.Equal(t, models.OccurrenceID(job.ID, nextAt), mockJobQueue.enqueuedJobs[1].OccurrenceID)
+}
+
+func TestSchedulerService_ProcessReadyJobs_RedispatchesExpiredDispatch_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	mockJobQueue := NewMockJobQueue()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       mockJobQueue,
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+		instanceID:     "scheduler-a",
+	}
+
+	job := &models.Job{
+		Schedule:    "@every 1h",
+		API:         "https://httpbin.org/status/200",
+		Type:        models.AT_LEAST_ONCE,
+		IsRecurring: true,
+		IsActive:    true,
+	}
+	mockStorage.CreateJob(job)
+
+	// An occurrence dispatched recently is still running; one past the expiry was lost
+	recently := time.Now().Add(-time.Minute)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: time.Now().Add(-time.Minute), DispatchedAt: &recently})
+	require.NoError(t, scheduler.ProcessReadyJobs(context.Background(), 100))
+	assert.Empty(t, mockJobQueue.enqueuedJobs)
+
+	lost := time.Now().Add(-scheduleDispatchExpiry - time.Minute)
+	mockStorage.schedules[job.ID].DispatchedAt = &lost
+	require.NoError(t, scheduler.ProcessReadyJobs(context.Background(), 100))
+	assert.Len(t, mockJobQueue.enqueuedJobs, 1)
+}
+
+func TestSchedulerService_ProcessReadyJobsAsLeader_FencesEarlierTerms_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	mockJobQueue := NewMockJobQueue()
+	newScheduler := func(instanceID string) *SchedulerService {
+		return &SchedulerService{
+			storage:        mockStorage,
+			jobQueue:       mockJobQueue,
+			redisClient:    &MockRedisClient{},
+			scheduleParser: utils.NewScheduleParser(),
+			instanceID:     instanceID,
+		}
+	}
+	stalled, leader := newScheduler("scheduler-a"), newScheduler("scheduler-b")
+
+	job := &models.Job{
+		Schedule:    "@every 1h",
+		API:         "https://httpbin.org/status/200",
+		Type:        models.AT_LEAST_ONCE,
+		IsRecurring: true,
+		IsActive:    true,
+	}
+	mockStorage.CreateJob(job)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: time.Now().Add(-time.Second)})
+
+	// The leader of term 2 dispatches the occurrence, and the schedule is due again once it completes
+	ctx := context.Background()
+	require.NoError(t, leader.ProcessReadyJobsAsLeader(ctx, 100, 2))
+	require.Len(t, mockJobQueue.enqueuedJobs, 1)
+	require.NoError(t, mockStorage.UpdateJobSchedule(job.ID, time.Now().Add(-time.Second)))
+
+	// The leader of term 1 resumes after its lease lapsed and cannot claim the schedule
+	require.NoError(t, stalled.ProcessReadyJobsAsLeader(ctx, 100, 1))
+	assert.Len(t, mockJobQueue.enqueuedJobs, 1)
+
+	schedule, err := mockStorage.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), schedule.LeaderTerm)
+	assert.ErrorIs(t, mockStorage.MarkScheduleDispatched(job.ID, "scheduler-a", 1), storage.ErrScheduleClaimLost)
+
+	require.NoError(t, leader.ProcessReadyJobsAsLeader(ctx, 100, 2))
+	assert.Len(t, mockJobQueue.enqueuedJobs, 2)
+}
+
+func TestSchedulerService_ProcessReadyJobs_SkipsScheduleClaimedElsewhere_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	mockJobQueue := NewMockJobQueue()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       mockJobQueue,
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+		instanceID:     "scheduler-a",
+	}
+
+	job := &models.Job{
+		Schedule:    "@every 1h",
+		API:         "https://httpbin.org/status/200",
+		Type:        models.AT_LEAST_ONCE,
+		IsRecurring: true,
+		IsActive:    true,
+	}
+	mockStorage.CreateJob(job)
+
+	// Another scheduler claimed the occurrence but has not dispatched it yet
+	leaseExpiresAt := time.Now().Add(time.Minute)
+	schedule := &models.JobSchedule{
+		JobID:             job.ID,
+		NextExecutionTime: time.Now().Add(-10 * time.Second),
+		LeaseOwner:        "scheduler-b",
+		LeaseExpiresAt:    &leaseExpiresAt,
+	}
+	mockStorage.CreateJobSchedule(schedule)
+
+	require.NoError(t, scheduler.ProcessReadyJobs(context.Background(), 100))
+	assert.Empty(t, mockJobQueue.enqueuedJobs)
+
+	// An expired lease means its owner died before dispatching, so the occurrence is taken over
+	expired := time.Now().Add(-time.Second)
+	schedule.LeaseExpiresAt = &expired
+
+	require.NoError(t, scheduler.ProcessReadyJobs(context.Background(), 100))
+	assert.Len(t, mockJobQueue.enqueuedJobs, 1)
+}
+
 func TestSchedulerService_ProcessReadyJobs_NoJobs_Unit(t *testing.T) {
 	mockStorage := NewMockSchedulerStorage()
 	mockJobQueue := NewMockJobQueue()
@@ -265,6 +559,8 @@ func TestSchedulerService_ProcessReadyJobs_NoJobs_Unit(t *testing.T) {
 	// Assertions
 	require.NoError(t, err)
 	assert.Len(t, mockJobQueue.enqueuedJobs, 0)
+	// Deferred jobs are still promoted when nothing is due
+	assert.Equal(t, 1, mockJobQueue.retryQueueRuns)
 }
 
 func TestSchedulerService_GetQueueStats_Unit(t *testing.T) {
@@ -526,3 +822,495 @@ func TestQueueJob_AT_LEAST_ONCE_ShouldRetry(t *testing.T) {
 	job.RetryCount = job.MaxRetryCount
 	assert.False(t, job.ShouldRetry())
 }
+
+func TestQueueJob_ShouldRetry_HonoursRetryableStatusCodes(t *testing.T) {
+	job := &models.QueueJob{
+		ID:            "test-job-3",
+		JobID:         3,
+		MaxRetryCount: 3,
+		Type:          models.AT_LEAST_ONCE,
+		SuccessCriteria: models.SuccessCriteria{
+			RetryableStatusCodes:    []int{429, 503},
+			NonRetryableStatusCodes: []int{400},
+		},
+	}
+
+	// Transport failures have no status code and are always retryable
+	job.LastStatusCode = 0
+	assert.True(t, job.ShouldRetry())
+
+	job.LastStatusCode = 503
+	assert.True(t, job.ShouldRetry())
+
+	// Non-retryable codes are final
+	job.LastStatusCode = 400
+	assert.False(t, job.ShouldRetry())
+
+	// When a retryable set is declared, codes outside it are final
+	job.LastStatusCode = 500
+	assert.False(t, job.ShouldRetry())
+
+	// Without a retryable set, only the non-retryable codes are final
+	job.SuccessCriteria.RetryableStatusCodes = nil
+	assert.True(t, job.ShouldRetry())
+}
+
+func TestQueueJob_RetryDelay_Strategies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy models.RetryPolicy
+		delays []time.Duration // For retries 1, 2, 3...
+	}{
+		{
+			name:   "default exponential",
+			policy: models.RetryPolicy{},
+			delays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:   "fixed",
+			policy: models.RetryPolicy{Strategy: models.RetryFixed, BaseDelaySeconds: 30},
+			delays: []time.Duration{30 * time.Second, 30 * time.Second, 30 * time.Second},
+		},
+		{
+			name:   "linear",
+			policy: models.RetryPolicy{Strategy: models.RetryLinear, BaseDelaySeconds: 10},
+			delays: []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second},
+		},
+		{
+			name:   "exponential with multiplier",
+			policy: models.RetryPolicy{Strategy: models.RetryExponential, BaseDelaySeconds: 5, Multiplier: 3},
+			delays: []time.Duration{5 * time.Second, 15 * time.Second, 45 * time.Second},
+		},
+		{
+			name:   "capped by max delay",
+			policy: models.RetryPolicy{Strategy: models.RetryLinear, BaseDelaySeconds: 60, MaxDelaySeconds: 150},
+			delays: []time.Duration{time.Minute, 2 * time.Minute, 150 * time.Second, 150 * time.Second},
+		},
+		{
+			name:   "default cap",
+			policy: models.RetryPolicy{BaseDelaySeconds: 60},
+			delays: []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.QueueJob{RetryPolicy: tt.policy}
+			for i, expected := range tt.delays {
+				job.RetryCount = i + 1
+				assert.Equal(t, expected, job.RetryDelay(models.DefaultRetryPolicy), "retry %d", job.RetryCount)
+			}
+		})
+	}
+}
+
+func TestQueueJob_RetryDelay_Jitter(t *testing.T) {
+	policy := models.RetryPolicy{Strategy: models.RetryFixed, BaseDelaySeconds: 10}
+
+	full := &models.QueueJob{RetryCount: 1, RetryPolicy: policy}
+	full.RetryPolicy.Jitter = models.JitterFull
+	equal := &models.QueueJob{RetryCount: 1, RetryPolicy: policy}
+	equal.RetryPolicy.Jitter = models.JitterEqual
+
+	fullDelays := map[time.Duration]bool{}
+	for range 100 {
+		delay := full.RetryDelay(models.DefaultRetryPolicy)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, 10*time.Second)
+		fullDelays[delay] = true
+
+		delay = equal.RetryDelay(models.DefaultRetryPolicy)
+		assert.GreaterOrEqual(t, delay, 5*time.Second)
+		assert.Less(t, delay, 10*time.Second)
+	}
+	assert.Greater(t, len(fullDelays), 1, "jittered delays should vary")
+
+	// Jitter applies after the max delay, so a capped delay never exceeds it
+	capped := &models.QueueJob{RetryCount: 50, RetryPolicy: models.RetryPolicy{MaxDelaySeconds: 60, Jitter: models.JitterEqual}}
+	for range 100 {
+		assert.LessOrEqual(t, capped.RetryDelay(models.DefaultRetryPolicy), time.Minute)
+	}
+}
+
+func TestQueueJob_RetryDelay_WorkerDefaults(t *testing.T) {
+	defaults := models.RetryPolicy{Strategy: models.RetryFixed, BaseDelaySeconds: 20, MaxDelaySeconds: 600, Jitter: models.JitterNone}
+
+	// A job without a policy follows the worker's
+	job := &models.QueueJob{RetryCount: 3}
+	assert.Equal(t, 20*time.Second, job.RetryDelay(defaults))
+
+	// A job's own fields take precedence over the defaults
+	job.RetryPolicy = models.RetryPolicy{Strategy: models.RetryLinear}
+	assert.Equal(t, time.Minute, job.RetryDelay(defaults))
+}
+
+func TestPriorityScheduler_Strict(t *testing.T) {
+	scheduler, err := NewPriorityScheduler(PriorityPolicyStrict, nil)
+	require.NoError(t, err)
+
+	for range 10 {
+		assert.Equal(t, []models.JobPriority{models.PriorityHigh, models.PriorityNormal, models.PriorityLow}, scheduler.Order())
+	}
+}
+
+func TestPriorityScheduler_Weighted(t *testing.T) {
+	scheduler, err := NewPriorityScheduler(PriorityPolicyWeighted, map[models.JobPriority]int{
+		models.PriorityHigh: 3,
+		models.PriorityLow:  2,
+	})
+	require.NoError(t, err)
+
+	// Each priority comes first in proportion to its weight: high 3, normal 3 by default, low 2
+	first := map[models.JobPriority]int{}
+	const draws = 8000
+	for range draws {
+		order := scheduler.Order()
+		require.ElementsMatch(t, models.Priorities, order)
+		first[order[0]]++
+	}
+	assert.InDelta(t, draws*3/8, first[models.PriorityHigh], draws/20)
+	assert.InDelta(t, draws*3/8, first[models.PriorityNormal], draws/20)
+	assert.InDelta(t, draws*2/8, first[models.PriorityLow], draws/20)
+}
+
+func TestPriorityScheduler_RejectsInvalidSettings(t *testing.T) {
+	_, err := NewPriorityScheduler("fifo", nil)
+	assert.Error(t, err)
+
+	_, err = NewPriorityScheduler(PriorityPolicyWeighted, map[models.JobPriority]int{"urgent": 1})
+	assert.Error(t, err)
+
+	_, err = NewPriorityScheduler(PriorityPolicyWeighted, map[models.JobPriority]int{models.PriorityLow: 0})
+	assert.Error(t, err)
+}
+
+func TestQueueJob_QueueName(t *testing.T) {
+	assert.Equal(t, models.DefaultQueue, models.NewQueueJob(&models.Job{ID: 1}, &models.JobSchedule{ID: 1}).QueueName())
+	assert.Equal(t, "reports", models.NewQueueJob(&models.Job{ID: 1, Queue: "reports"}, &models.JobSchedule{ID: 1}).QueueName())
+
+	// Tags take precedence, and jobs with the same tags share a queue whatever their order
+	tagged := models.NewQueueJob(&models.Job{ID: 1, Queue: models.DefaultQueue, Tags: models.JobTags{"linux", "gpu", "gpu"}}, &models.JobSchedule{ID: 1})
+	assert.Equal(t, "tags:gpu,linux", tagged.QueueName())
+}
+
+func TestQueueSubscription_Matches(t *testing.T) {
+	subscription := QueueSubscription{Queues: []string{"default", "reports"}, Tags: models.JobTags{"gpu", "linux"}}
+
+	assert.True(t, subscription.Matches("reports"))
+	assert.False(t, subscription.Matches("billing"))
+	assert.True(t, subscription.Matches(models.JobTags{"gpu"}.QueueName()))
+	assert.True(t, subscription.Matches(models.JobTags{"linux", "gpu"}.QueueName()))
+	assert.False(t, subscription.Matches(models.JobTags{"gpu", "windows"}.QueueName()))
+}
+
+func TestQueueKey(t *testing.T) {
+	assert.Equal(t, QueueReady, queueKey(QueueReady, models.DefaultQueue, models.PriorityNormal))
+	assert.Equal(t, QueueReady, queueKey(QueueReady, "", ""))
+	assert.Equal(t, QueueReady+":high", queueKey(QueueReady, models.DefaultQueue, models.PriorityHigh))
+	assert.Equal(t, QueueReady+":reports", queueKey(QueueReady, "reports", models.PriorityNormal))
+	assert.Equal(t, StreamReady+":reports:low", queueKey(StreamReady, "reports", models.PriorityLow))
+
+	// Every priority of every queue is tried, priority first
+	keys := dequeueOrder(models.Priorities, []string{"default", "reports"}, readyList)
+	assert.ElementsMatch(t, []string{QueueReady + ":high", QueueReady + ":reports:high"}, keys[:2])
+	assert.ElementsMatch(t, []string{QueueReady, QueueReady + ":reports"}, keys[2:4])
+	assert.ElementsMatch(t, []string{QueueReady + ":low", QueueReady + ":reports:low"}, keys[4:])
+}
+
+func TestValidateQueueName(t *testing.T) {
+	assert.NoError(t, models.ValidateQueueName("reports"))
+	assert.NoError(t, models.ValidateQueueName("eu-west_1"))
+	assert.Error(t, models.ValidateQueueName(""))
+	assert.Error(t, models.ValidateQueueName("Reports"))
+	assert.Error(t, models.ValidateQueueName("tags:gpu"))
+	assert.Error(t, models.ValidateQueueName("high"))
+}
+
+func TestConcurrencyPolicy(t *testing.T) {
+	assert.Equal(t, models.ConcurrencyForbid, models.ConcurrencyPolicy("").OrForbid())
+	assert.Equal(t, models.ConcurrencyQueue, models.ConcurrencyQueue.OrForbid())
+	assert.True(t, models.ConcurrencyReplace.IsValid())
+	assert.False(t, models.ConcurrencyPolicy("").IsValid())
+	assert.False(t, models.ConcurrencyPolicy("forbid").IsValid())
+}
+
+func TestSchedulerService_HandleJobCompletion_StopsAtMaxRuns_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       NewMockJobQueue(),
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+	}
+
+	job := &models.Job{
+		Schedule:      "@every 90s",
+		API:           "https://httpbin.org/status/200",
+		Type:          models.AT_LEAST_ONCE,
+		IsRecurring:   true,
+		MaxRetryCount: 3,
+		IsActive:      true,
+		MaxRuns:       2,
+	}
+	mockStorage.CreateJob(job)
+
+	scheduledAt := time.Now().Add(-time.Second).Truncate(time.Second)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: scheduledAt})
+
+	// First run: rescheduled one interval after the scheduled time
+	require.NoError(t, scheduler.HandleJobCompletion(job.ID, true))
+	schedule, err := mockStorage.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.True(t, scheduledAt.Add(90*time.Second).Equal(schedule.NextExecutionTime))
+	assert.Equal(t, 1, job.RunCount)
+	assert.True(t, job.IsActive)
+
+	// Second run (failed) reaches maxRuns: deactivated and unscheduled
+	schedule.NextExecutionTime = time.Now().Add(-time.Second)
+	require.NoError(t, scheduler.HandleJobCompletion(job.ID, false))
+	assert.Equal(t, 2, job.RunCount)
+	assert.False(t, job.IsActive)
+	_, err = mockStorage.GetJobSchedule(job.ID)
+	assert.Error(t, err)
+}
+
+func TestSchedulerService_HandleJobCompletion_StopsAtEndAt_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       NewMockJobQueue(),
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+	}
+
+	endAt := time.Now().Add(30 * time.Second)
+	job := &models.Job{
+		Schedule:      "@every 1m",
+		API:           "https://httpbin.org/status/200",
+		Type:          models.AT_LEAST_ONCE,
+		IsRecurring:   true,
+		MaxRetryCount: 3,
+		IsActive:      true,
+		EndAt:         &endAt,
+	}
+	mockStorage.CreateJob(job)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: time.Now().Add(-time.Second)})
+
+	require.NoError(t, scheduler.HandleJobCompletion(job.ID, true))
+
+	assert.False(t, job.IsActive)
+	assert.Equal(t, 1, job.RunCount)
+	_, err := mockStorage.GetJobSchedule(job.ID)
+	assert.Error(t, err)
+}
+
+func TestSchedulerService_HandleJobCompletion_IntervalFromCompletion_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       NewMockJobQueue(),
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+	}
+
+	job := &models.Job{
+		Schedule:       "@every 90s",
+		IntervalAnchor: models.IntervalFromCompletion,
+		API:            "https://httpbin.org/status/200",
+		Type:           models.AT_LEAST_ONCE,
+		IsRecurring:    true,
+		MaxRetryCount:  3,
+		IsActive:       true,
+	}
+	mockStorage.CreateJob(job)
+
+	// The run was scheduled ten minutes ago but only just finished
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: time.Now().Add(-10 * time.Minute)})
+
+	before := time.Now()
+	require.NoError(t, scheduler.HandleJobCompletion(job.ID, true))
+
+	schedule, err := mockStorage.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.False(t, schedule.NextExecutionTime.Before(before.Add(90*time.Second)))
+	assert.True(t, schedule.NextExecutionTime.Before(time.Now().Add(91*time.Second)))
+}
+
+func TestSchedulerService_HandleJobCompletion_IgnoresAlreadyRescheduledRun_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       NewMockJobQueue(),
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+	}
+
+	job := &models.Job{
+		Schedule:      "@every 1h",
+		API:           "https://httpbin.org/status/200",
+		Type:          models.AT_LEAST_ONCE,
+		IsRecurring:   true,
+		MaxRetryCount: 3,
+		IsActive:      true,
+	}
+	mockStorage.CreateJob(job)
+
+	nextExecution := time.Now().Add(30 * time.Minute)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: nextExecution})
+
+	// A retry finishing after the first attempt already moved the schedule on
+	require.NoError(t, scheduler.HandleJobCompletion(job.ID, true))
+
+	schedule, err := mockStorage.GetJobSchedule(job.ID)
+	require.NoError(t, err)
+	assert.True(t, nextExecution.Equal(schedule.NextExecutionTime))
+	assert.Zero(t, job.RunCount)
+}
+
+func TestSchedulerService_ProcessReadyJobs_MisfirePolicies_Unit(t *testing.T) {
+	// Every minute, with the scheduler back after half an hour of downtime. With a 90s threshold the
+	// occurrences firstMissed+0m..+28m are missed and firstMissed+29m is merely late.
+	firstMissed := time.Now().Add(-30*time.Minute - 15*time.Second)
+	occurrence := func(i int) time.Time { return firstMissed.Add(time.Duration(i) * time.Minute) }
+
+	tests := []struct {
+		name             string
+		policy           models.MisfirePolicy
+		expectEnqueuedAt []time.Time
+		expectSkipped    int
+		expectNext       time.Time
+	}{
+		{
+			name:             "fire_once_now runs the latest missed occurrence",
+			policy:           models.MisfireFireOnceNow,
+			expectEnqueuedAt: []time.Time{occurrence(28)},
+			expectSkipped:    28,
+			expectNext:       occurrence(28),
+		},
+		{
+			name:             "default policy is fire_once_now",
+			expectEnqueuedAt: []time.Time{occurrence(28)},
+			expectSkipped:    28,
+			expectNext:       occurrence(28),
+		},
+		{
+			name:          "skip_to_next skips every missed occurrence",
+			policy:        models.MisfireSkipToNext,
+			expectSkipped: 29,
+			expectNext:    occurrence(29),
+		},
+		{
+			name:             "run_all_missed runs the most recent ones up to the bound",
+			policy:           models.MisfireRunAllMissed,
+			expectEnqueuedAt: []time.Time{occurrence(29 - models.MaxMisfireCatchUpRuns)},
+			expectSkipped:    29 - models.MaxMisfireCatchUpRuns,
+			expectNext:       occurrence(29 - models.MaxMisfireCatchUpRuns),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := NewMockSchedulerStorage()
+			mockJobQueue := NewMockJobQueue()
+			scheduler := &SchedulerService{
+				storage:        mockStorage,
+				jobQueue:       mockJobQueue,
+				redisClient:    &MockRedisClient{},
+				scheduleParser: utils.NewScheduleParser(),
+			}
+
+			job := &models.Job{
+				Schedule:                "@every 1m",
+				API:                     "https://httpbin.org/status/200",
+				Type:                    models.AT_LEAST_ONCE,
+				IsRecurring:             true,
+				MaxRetryCount:           3,
+				IsActive:                true,
+				MisfirePolicy:           tt.policy,
+				MisfireThresholdSeconds: 90,
+			}
+			mockStorage.CreateJob(job)
+			mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: firstMissed})
+
+			require.NoError(t, scheduler.ProcessReadyJobs(context.Background(), 100))
+
+			require.Len(t, mockJobQueue.enqueuedJobs, len(tt.expectEnqueuedAt))
+			for i, expected := range tt.expectEnqueuedAt {
+				assert.True(t, expected.Equal(mockJobQueue.enqueuedJobs[i].ScheduledAt), "enqueued %v, want %v", mockJobQueue.enqueuedJobs[i].ScheduledAt, expected)
+			}
+
+			require.Len(t, mockStorage.executions, tt.expectSkipped)
+			for i, execution := range mockStorage.executions {
+				assert.Equal(t, models.StatusSkipped, execution.Status)
+				assert.True(t, occurrence(i).Equal(execution.ExecutionTime))
+			}
+
+			schedule, err := mockStorage.GetJobSchedule(job.ID)
+			require.NoError(t, err)
+			assert.True(t, tt.expectNext.Equal(schedule.NextExecutionTime), "next %v, want %v", schedule.NextExecutionTime, tt.expectNext)
+		})
+	}
+}
+
+func TestSchedulerService_ProcessReadyJobs_LateWithinThreshold_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	mockJobQueue := NewMockJobQueue()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       mockJobQueue,
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+	}
+
+	job := &models.Job{
+		Schedule:      "@every 10s",
+		API:           "https://httpbin.org/status/200",
+		Type:          models.AT_LEAST_ONCE,
+		IsRecurring:   true,
+		MaxRetryCount: 3,
+		IsActive:      true,
+		MisfirePolicy: models.MisfireSkipToNext,
+	}
+	mockStorage.CreateJob(job)
+
+	scheduledAt := time.Now().Add(-30 * time.Second)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: scheduledAt})
+
+	require.NoError(t, scheduler.ProcessReadyJobs(context.Background(), 100))
+
+	// Within the default 60s threshold the run is late, not missed
+	require.Len(t, mockJobQueue.enqueuedJobs, 1)
+	assert.True(t, scheduledAt.Equal(mockJobQueue.enqueuedJobs[0].ScheduledAt))
+	assert.Empty(t, mockStorage.executions)
+}
+
+func TestSchedulerService_ProcessReadyJobs_MissedOneShotSkipped_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	mockJobQueue := NewMockJobQueue()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       mockJobQueue,
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+	}
+
+	runAt := time.Now().Add(-time.Hour)
+	job := &models.Job{
+		RunAt:         &runAt,
+		API:           "https://httpbin.org/status/200",
+		Type:          models.AT_LEAST_ONCE,
+		MaxRetryCount: 3,
+		IsActive:      true,
+		MisfirePolicy: models.MisfireSkipToNext,
+	}
+	mockStorage.CreateJob(job)
+	mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: runAt})
+
+	require.NoError(t, scheduler.ProcessReadyJobs(context.Background(), 100))
+
+	assert.Empty(t, mockJobQueue.enqueuedJobs)
+	require.Len(t, mockStorage.executions, 1)
+	assert.Equal(t, models.StatusSkipped, mockStorage.executions[0].Status)
+	_, err := mockStorage.GetJobSchedule(job.ID)
+	assert.Error(t, err)
+}
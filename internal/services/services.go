@@ -11,6 +11,7 @@ import (
 type SchedulerServiceInterface interface {
 	ProcessReadyJobs(ctx context.Context, limit int) error
 	GetQueueStats() (map[string]int64, error)
+	ListQueues() ([]*QueueInfo, error)
 	HandleJobCompletion(jobID uint, success bool) error
 }
 
@@ -20,5 +21,44 @@ type JobQueueServiceInterface interface {
 	DequeueJob(timeout time.Duration) (*models.QueueJob, error)
 	CompleteJob(jobID string, result *models.QueueJobResult) error
 	GetQueueStats() (map[string]int64, error)
+	ListQueues() ([]*QueueInfo, error)
 	ProcessRetryQueue() error
 }
+
+// WorkerQueueInterface is a job queue as seen by the workers consuming it. Dequeued jobs stay in
+// flight until they are completed, discarded, failed, requeued or deferred, for as long as their worker keeps
+// extending their leases; jobs left in flight by a worker that died are found by ExpiredJobs and
+// recovered by ReapJob, which calls onReaped once the job is taken from its worker and, for a requeued
+// job, before it can be delivered again.
+type WorkerQueueInterface interface {
+	JobQueueServiceInterface
+	WorkerID() string
+	Subscription() QueueSubscription
+	ExtendLeases() error
+	DiscardJob(jobID string)
+	FailJob(job *models.QueueJob, errorMsg string) error
+	RequeueJob(job *models.QueueJob) error
+	DeferJob(job *models.QueueJob, delay time.Duration) error
+	ExpiredJobs() ([]*models.QueueJob, error)
+	ReapJob(job *models.QueueJob, onReaped func()) (reaped bool, requeued bool, err error)
+}
+
+// LeaderElectorInterface reports the state of scheduler leader election
+type LeaderElectorInterface interface {
+	Status(ctx context.Context) (*LeaderStatus, error)
+}
+
+// WorkerRegistryInterface lists the workers registered as live
+type WorkerRegistryInterface interface {
+	List(ctx context.Context) ([]*WorkerStats, error)
+}
+
+// DeadLetterQueueInterface inspects, replays and purges jobs that failed permanently
+type DeadLetterQueueInterface interface {
+	List(limit, offset int) ([]*models.DeadLetter, int64, error)
+	Get(id string) (*models.DeadLetter, error)
+	Replay(id string) (*models.QueueJob, error)
+	ReplayAll() (int, error)
+	Delete(id string) error
+	Purge() (int64, error)
+}
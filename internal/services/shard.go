@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	redisclient "github.com/manyu/job-scheduler/internal/redis"
+	"github.com/manyu/job-scheduler/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultShardCount is how many shards jobs are split into when scanning is sharded
+const DefaultShardCount = 64
+
+// ShardBackend tracks scheduler membership and the lease on each shard. Each method must be atomic.
+type ShardBackend interface {
+	// Heartbeat registers member as live until ttl from now
+	Heartbeat(ctx context.Context, member string, ttl time.Duration) error
+	// Leave removes member at once instead of waiting for its heartbeat to lapse
+	Leave(ctx context.Context, member string) error
+	// Members returns the live members
+	Members(ctx context.Context) ([]string, error)
+	// AcquireShard takes or renews the lease on shard for member, failing if another member holds it
+	AcquireShard(ctx context.Context, shard int, member string, ttl time.Duration) (bool, error)
+	// ReleaseShard gives up the lease on shard if member holds it
+	ReleaseShard(ctx context.Context, shard int, member string) error
+}
+
+// ShardCoordinator decides which shards of the job ID space this scheduler scans.
+// Live members split the shards into contiguous ranges by their sorted IDs, so ownership
+// rebalances whenever a member joins or leaves. A shard is only scanned under a lease, which
+// its previous owner gives up on the next heartbeat after the rebalance or which lapses if the
+// owner died, so no shard is scanned by two schedulers and none is left without an owner. A scan
+// already running during a handover is covered by the schedule claims it makes.
+type ShardCoordinator struct {
+	backend    ShardBackend
+	identity   string
+	shardCount int
+	ttl        time.Duration
+
+	mu    sync.RWMutex
+	owned map[int]time.Time // Leased shards and until when the lease is assumed valid
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewShardCoordinator creates a shard coordinator that heartbeats as identity every third of ttl
+func NewShardCoordinator(backend ShardBackend, identity string, shardCount int, ttl time.Duration) *ShardCoordinator {
+	if shardCount <= 0 {
+		shardCount = DefaultShardCount
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ShardCoordinator{
+		backend:    backend,
+		identity:   identity,
+		shardCount: shardCount,
+		ttl:        ttl,
+		owned:      make(map[int]time.Time),
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start begins heartbeating and taking over assigned shards
+func (sc *ShardCoordinator) Start() {
+	go sc.run()
+}
+
+// run heartbeats and rebalances until the coordinator is stopped
+func (sc *ShardCoordinator) run() {
+	defer close(sc.done)
+
+	ticker := time.NewTicker(sc.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		sc.tick()
+
+		select {
+		case <-sc.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick heartbeats, releases shards that moved to another member and acquires or renews assigned ones
+func (sc *ShardCoordinator) tick() {
+	attemptedAt := time.Now()
+	ctx, cancel := context.WithTimeout(sc.ctx, sc.ttl/3)
+	defer cancel()
+
+	if err := sc.backend.Heartbeat(ctx, sc.identity, sc.ttl); err != nil {
+		log.Printf("Failed to send scheduler heartbeat: %v", err)
+		return
+	}
+
+	members, err := sc.backend.Members(ctx)
+	if err != nil {
+		log.Printf("Failed to get scheduler members: %v", err)
+		return
+	}
+	assigned := assignShards(members, sc.identity, sc.shardCount)
+
+	for _, shard := range sc.ownedShards() {
+		if assigned[shard] {
+			continue
+		}
+		// Stop scanning before giving up the lease, so the new owner never overlaps with us
+		sc.setOwned(shard, time.Time{})
+		if err := sc.backend.ReleaseShard(ctx, shard, sc.identity); err != nil {
+			log.Printf("Failed to release shard %d: %v", shard, err)
+		}
+	}
+
+	for shard := range assigned {
+		acquired, err := sc.backend.AcquireShard(ctx, shard, sc.identity, sc.ttl)
+		if err != nil {
+			log.Printf("Failed to acquire shard %d: %v", shard, err)
+			continue
+		}
+		if !acquired {
+			// Still held by its previous owner until it rebalances or its lease lapses
+			sc.setOwned(shard, time.Time{})
+			continue
+		}
+		// Like leadership, a lease is assumed valid for two thirds of its TTL from before the request
+		sc.setOwned(shard, attemptedAt.Add(sc.ttl*2/3))
+	}
+}
+
+// assignShards returns the contiguous range of shards that member owns among members
+func assignShards(members []string, member string, shardCount int) map[int]bool {
+	sorted := append([]string(nil), members...)
+	sort.Strings(sorted)
+
+	index := sort.SearchStrings(sorted, member)
+	if index == len(sorted) || sorted[index] != member {
+		return map[int]bool{}
+	}
+
+	first := index * shardCount / len(sorted)
+	last := (index + 1) * shardCount / len(sorted)
+	assigned := make(map[int]bool, last-first)
+	for shard := first; shard < last; shard++ {
+		assigned[shard] = true
+	}
+	return assigned
+}
+
+func (sc *ShardCoordinator) setOwned(shard int, validUntil time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if validUntil.IsZero() {
+		delete(sc.owned, shard)
+		return
+	}
+	sc.owned[shard] = validUntil
+}
+
+// ownedShards returns every shard held, including ones whose lease may have lapsed
+func (sc *ShardCoordinator) ownedShards() []int {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	shards := make([]int, 0, len(sc.owned))
+	for shard := range sc.owned {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+	return shards
+}
+
+// Filter returns the shards this scheduler may scan right now
+func (sc *ShardCoordinator) Filter() *storage.ShardFilter {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	now := time.Now()
+	shards := make([]int, 0, len(sc.owned))
+	for shard, validUntil := range sc.owned {
+		if now.Before(validUntil) {
+			shards = append(shards, shard)
+		}
+	}
+	sort.Ints(shards)
+	return &storage.ShardFilter{Count: sc.shardCount, Shards: shards}
+}
+
+// Stop stops heartbeating, releases every shard and leaves so the others rebalance at once
+func (sc *ShardCoordinator) Stop() {
+	sc.cancel()
+	<-sc.done
+
+	shards := sc.ownedShards()
+	sc.mu.Lock()
+	sc.owned = make(map[int]time.Time)
+	sc.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sc.ttl/3)
+	defer cancel()
+	for _, shard := range shards {
+		if err := sc.backend.ReleaseShard(ctx, shard, sc.identity); err != nil {
+			log.Printf("Failed to release shard %d: %v", shard, err)
+		}
+	}
+	if err := sc.backend.Leave(ctx, sc.identity); err != nil {
+		log.Printf("Failed to leave scheduler members: %v", err)
+	}
+}
+
+// Shard membership keys
+const (
+	ShardMembersKey   = "scheduler:members" // Sorted set of members scored by heartbeat expiry in ms
+	ShardLeaseKeyBase = "scheduler:shard:"  // Followed by the shard number, holds the owning member
+)
+
+// acquireShardScript takes the shard lease for ARGV[1] if it is free or already held by ARGV[1]
+var acquireShardScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder == false or holder == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseShardScript deletes the shard lease if it is held by ARGV[1]
+var releaseShardScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisShardBackend implements ShardBackend with a sorted set of heartbeats and a key per shard lease
+type RedisShardBackend struct {
+	client *redis.Client
+}
+
+// NewRedisShardBackend creates a shard backend on Redis
+func NewRedisShardBackend(redisClient redisclient.RedisClientInterface) *RedisShardBackend {
+	return &RedisShardBackend{
+		client: redisClient.GetClient(),
+	}
+}
+
+// Heartbeat registers member as live until ttl from now
+func (b *RedisShardBackend) Heartbeat(ctx context.Context, member string, ttl time.Duration) error {
+	expiresAt := time.Now().Add(ttl).UnixMilli()
+	if err := b.client.ZAdd(ctx, ShardMembersKey, redis.Z{Score: float64(expiresAt), Member: member}).Err(); err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+	return nil
+}
+
+// Leave removes member from the live members
+func (b *RedisShardBackend) Leave(ctx context.Context, member string) error {
+	if err := b.client.ZRem(ctx, ShardMembersKey, member).Err(); err != nil {
+		return fmt.Errorf("failed to leave members: %w", err)
+	}
+	return nil
+}
+
+// Members prunes members whose heartbeat lapsed and returns the rest
+func (b *RedisShardBackend) Members(ctx context.Context) ([]string, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if err := b.client.ZRemRangeByScore(ctx, ShardMembersKey, "-inf", "("+now).Err(); err != nil {
+		return nil, fmt.Errorf("failed to prune members: %w", err)
+	}
+	members, err := b.client.ZRange(ctx, ShardMembersKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	return members, nil
+}
+
+// AcquireShard takes or renews the lease on shard for member
+func (b *RedisShardBackend) AcquireShard(ctx context.Context, shard int, member string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireShardScript.Run(ctx, b.client, []string{shardLeaseKey(shard)}, member, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire shard %d: %w", shard, err)
+	}
+	return acquired == 1, nil
+}
+
+// ReleaseShard gives up the lease on shard if member holds it
+func (b *RedisShardBackend) ReleaseShard(ctx context.Context, shard int, member string) error {
+	if err := releaseShardScript.Run(ctx, b.client, []string{shardLeaseKey(shard)}, member).Err(); err != nil {
+		return fmt.Errorf("failed to release shard %d: %w", shard, err)
+	}
+	return nil
+}
+
+func shardLeaseKey(shard int) string {
+	return ShardLeaseKeyBase + strconv.Itoa(shard)
+}
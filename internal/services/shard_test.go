@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/internal/storage"
+	"github.com/manyu/job-scheduler/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeShardBackend is an in-memory ShardBackend shared by the schedulers of a test
+type fakeShardBackend struct {
+	mu      sync.Mutex
+	members map[string]time.Time
+	leases  map[int]shardLease
+}
+
+type shardLease struct {
+	member    string
+	expiresAt time.Time
+}
+
+func newFakeShardBackend() *fakeShardBackend {
+	return &fakeShardBackend{
+		members: make(map[string]time.Time),
+		leases:  make(map[int]shardLease),
+	}
+}
+
+func (b *fakeShardBackend) Heartbeat(ctx context.Context, member string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.members[member] = time.Now().Add(ttl)
+	return nil
+}
+
+func (b *fakeShardBackend) Leave(ctx context.Context, member string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.members, member)
+	return nil
+}
+
+func (b *fakeShardBackend) Members(ctx context.Context) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var members []string
+	for member, expiresAt := range b.members {
+		if time.Now().Before(expiresAt) {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (b *fakeShardBackend) AcquireShard(ctx context.Context, shard int, member string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lease, held := b.leases[shard]
+	if held && lease.member != member && time.Now().Before(lease.expiresAt) {
+		return false, nil
+	}
+	b.leases[shard] = shardLease{member: member, expiresAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (b *fakeShardBackend) ReleaseShard(ctx context.Context, shard int, member string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.leases[shard].member == member {
+		delete(b.leases, shard)
+	}
+	return nil
+}
+
+const testShardCount = 16
+
+// assertShardsPartitioned checks that no shard is scanned by two coordinators and, if complete
+// is set, that every shard is scanned by one
+func assertShardsPartitioned(t *testing.T, coordinators []*ShardCoordinator, complete bool) {
+	t.Helper()
+	scannedBy := make(map[int]string)
+	for _, coordinator := range coordinators {
+		for _, shard := range coordinator.Filter().Shards {
+			if owner, ok := scannedBy[shard]; ok {
+				t.Fatalf("shard %d is scanned by both %s and %s", shard, owner, coordinator.identity)
+			}
+			scannedBy[shard] = coordinator.identity
+		}
+	}
+	if complete {
+		assert.Len(t, scannedBy, testShardCount)
+	}
+}
+
+// tickAll runs one heartbeat round, checking the partition after every single tick
+func tickAll(t *testing.T, coordinators []*ShardCoordinator) {
+	for _, coordinator := range coordinators {
+		coordinator.tick()
+		assertShardsPartitioned(t, coordinators, false)
+	}
+}
+
+func TestAssignShards(t *testing.T) {
+	members := []string{"c", "a", "b"}
+
+	var all []int
+	for _, member := range members {
+		for shard := range assignShards(members, member, 64) {
+			all = append(all, shard)
+		}
+	}
+	sort.Ints(all)
+
+	require.Len(t, all, 64)
+	for i, shard := range all {
+		assert.Equal(t, i, shard)
+	}
+	assert.Len(t, assignShards(members, "a", 64), 21)
+	assert.Empty(t, assignShards(members, "d", 64))
+}
+
+func TestShardCoordinator_RebalancesOnJoinAndLeave(t *testing.T) {
+	backend := newFakeShardBackend()
+	ttl := time.Minute
+
+	a := NewShardCoordinator(backend, "scheduler-a", testShardCount, ttl)
+	b := NewShardCoordinator(backend, "scheduler-b", testShardCount, ttl)
+	coordinators := []*ShardCoordinator{a, b}
+
+	// The first member grabs its share on the first round; the second round settles the split
+	tickAll(t, coordinators)
+	tickAll(t, coordinators)
+	assertShardsPartitioned(t, coordinators, true)
+	assert.Len(t, a.Filter().Shards, testShardCount/2)
+
+	// A joining member only takes shards once their previous owner has handed them over
+	c := NewShardCoordinator(backend, "scheduler-c", testShardCount, ttl)
+	coordinators = append(coordinators, c)
+	tickAll(t, coordinators)
+	tickAll(t, coordinators)
+	assertShardsPartitioned(t, coordinators, true)
+	assert.NotEmpty(t, c.Filter().Shards)
+
+	// A member that leaves hands its shards over at once
+	close(b.done) // b is ticked by hand, so there is no loop for Stop to wait for
+	b.Stop()
+	coordinators = []*ShardCoordinator{a, c}
+	tickAll(t, coordinators)
+	tickAll(t, coordinators)
+	assertShardsPartitioned(t, coordinators, true)
+}
+
+func TestShardCoordinator_TakesOverShardsOfDeadMember(t *testing.T) {
+	backend := newFakeShardBackend()
+	ttl := 150 * time.Millisecond
+
+	a := NewShardCoordinator(backend, "scheduler-a", testShardCount, ttl)
+	b := NewShardCoordinator(backend, "scheduler-b", testShardCount, ttl)
+	coordinators := []*ShardCoordinator{a, b}
+	tickAll(t, coordinators)
+	tickAll(t, coordinators)
+	assertShardsPartitioned(t, coordinators, true)
+
+	// b stops heartbeating without releasing anything; its leases are assumed void before they expire
+	time.Sleep(ttl * 2 / 3)
+	assert.Empty(t, b.Filter().Shards)
+
+	require.Eventually(t, func() bool {
+		a.tick()
+		return len(a.Filter().Shards) == testShardCount
+	}, 2*ttl, ttl/3)
+}
+
+func TestSchedulerService_ProcessReadyJobsInShards_Unit(t *testing.T) {
+	mockStorage := NewMockSchedulerStorage()
+	mockJobQueue := NewMockJobQueue()
+	scheduler := &SchedulerService{
+		storage:        mockStorage,
+		jobQueue:       mockJobQueue,
+		redisClient:    &MockRedisClient{},
+		scheduleParser: utils.NewScheduleParser(),
+		instanceID:     "scheduler-a",
+	}
+
+	for i := 0; i < 4; i++ {
+		job := &models.Job{
+			Schedule:    "@every 1h",
+			API:         "https://httpbin.org/status/200",
+			Type:        models.AT_LEAST_ONCE,
+			IsRecurring: true,
+			IsActive:    true,
+		}
+		mockStorage.CreateJob(job)
+		mockStorage.CreateJobSchedule(&models.JobSchedule{JobID: job.ID, NextExecutionTime: time.Now().Add(-time.Second)})
+	}
+
+	// Job IDs are 1, 3, 5 and 7 as schedules share the ID counter; with 4 shards they fall in shards 1 and 3
+	filter := &storage.ShardFilter{Count: 4, Shards: []int{1}}
+	require.NoError(t, scheduler.ProcessReadyJobsInShards(context.Background(), 100, filter))
+
+	require.Len(t, mockJobQueue.enqueuedJobs, 2)
+	for _, queueJob := range mockJobQueue.enqueuedJobs {
+		assert.True(t, filter.Contains(queueJob.JobID))
+	}
+}
@@ -0,0 +1,537 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	redisclient "github.com/manyu/job-scheduler/internal/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamJobQueueService is a job queue on Redis streams, one per queue and priority, read through a consumer group.
+// Every worker is a named consumer of the group, so a job it dequeues stays in the group's pending
+// entries list under its name until it is acknowledged. Workers reset the idle time of their entries
+// with ExtendLeases while they are alive, so entries idle for longer than claimAfter belong to a worker
+// that died or hung, and are claimed by the next healthy worker that calls ExpiredJobs. Retries and
+// results use the same keys as the list backend.
+type StreamJobQueueService struct {
+	client     *redis.Client
+	ctx        context.Context
+	consumer   string        // Name of this worker in the consumer group
+	claimAfter time.Duration // How long an entry may stay idle before it is claimed
+
+	retryDefaults models.RetryPolicy // Fills in the retry policies of failed jobs
+	priorities    *PriorityScheduler // Order the stream of each priority is read in
+	subscription  QueueSubscription  // Queues this worker takes jobs from
+	directory     *queueDirectory
+
+	mu         sync.Mutex
+	deliveries map[string]streamDelivery // Jobs this consumer holds, by queue job ID
+	groups     map[string]bool           // Streams the consumer group is known to exist on
+}
+
+// streamDelivery is a stream entry held by this consumer
+type streamDelivery struct {
+	stream  string
+	entryID string
+	data    string
+}
+
+// Stream names
+const (
+	StreamReady = "job_stream:ready" // Stream of the default queue's normal priority jobs, followed by ":<queue>" and ":<priority>" like the ready lists; an entry is deleted once it is acknowledged
+	StreamGroup = "workers"          // Consumer group every worker reads the stream through
+	streamField = "job"              // Entry field holding the job data
+)
+
+// NewStreamJobQueueService creates a stream job queue, creating the consumer group of the default
+// queue if needed. Groups of other queues are created when they are first read.
+func NewStreamJobQueueService(redisClient redisclient.RedisClientInterface, claimAfter time.Duration) (*StreamJobQueueService, error) {
+	sqs := &StreamJobQueueService{
+		client:     redisClient.GetClient(),
+		ctx:        redisClient.GetContext(),
+		consumer:   newInstanceID(),
+		claimAfter: claimAfter,
+		deliveries: make(map[string]streamDelivery),
+		groups:     make(map[string]bool),
+
+		retryDefaults: models.DefaultRetryPolicy,
+		priorities:    defaultPriorityScheduler(),
+		subscription:  defaultSubscription(),
+		directory:     newQueueDirectory(redisClient.GetClient(), redisClient.GetContext()),
+	}
+
+	for _, priority := range models.Priorities {
+		if err := sqs.ensureGroup(readyStream(models.DefaultQueue, priority)); err != nil {
+			return nil, err
+		}
+	}
+	return sqs, nil
+}
+
+// readyStream returns the stream of a queue and priority
+func readyStream(queue string, priority models.JobPriority) string {
+	return queueKey(StreamReady, queue, priority)
+}
+
+// ensureGroup creates the consumer group on a stream unless it is known to exist
+func (sqs *StreamJobQueueService) ensureGroup(stream string) error {
+	sqs.mu.Lock()
+	known := sqs.groups[stream]
+	sqs.mu.Unlock()
+	if known {
+		return nil
+	}
+
+	// Start at the beginning of the stream so jobs added before the group existed are delivered too
+	err := sqs.client.XGroupCreateMkStream(sqs.ctx, stream, StreamGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group on %s: %w", stream, err)
+	}
+
+	sqs.mu.Lock()
+	sqs.groups[stream] = true
+	sqs.mu.Unlock()
+	return nil
+}
+
+// WorkerID returns the consumer name this service reads the stream as
+func (sqs *StreamJobQueueService) WorkerID() string {
+	return sqs.consumer
+}
+
+// Subscription returns the queues this worker takes jobs from
+func (sqs *StreamJobQueueService) Subscription() QueueSubscription {
+	return sqs.subscription
+}
+
+// EnqueueJob appends a job to the stream of its queue and priority
+func (sqs *StreamJobQueueService) EnqueueJob(job *models.QueueJob) error {
+	jobData, err := job.Serialize()
+	if err != nil {
+		return fmt.Errorf("failed to serialize job: %w", err)
+	}
+
+	_, err = sqs.client.TxPipelined(sqs.ctx, func(pipe redis.Pipeliner) error {
+		sqs.directory.register(pipe, job.QueueName())
+		pipe.XAdd(sqs.ctx, streamAddArgs(readyStream(job.QueueName(), job.Priority), string(jobData)))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	log.Printf("Enqueued job %s (JobID: %d) to queue %s with %s priority", job.ID, job.JobID, job.QueueName(), job.Priority.OrNormal())
+	return nil
+}
+
+// DequeueJob reads the next undelivered job for this consumer, waiting up to timeout for one. Only
+// the queues the worker subscribes to are read; their streams are read one at a time by priority in
+// the order the priority scheduler picks, so an empty set of streams is polled rather than blocked
+// on. The job stays pending under this consumer until it is completed, discarded, failed or requeued.
+func (sqs *StreamJobQueueService) DequeueJob(timeout time.Duration) (*models.QueueJob, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		queues, err := sqs.directory.subscribed(sqs.subscription)
+		if err != nil {
+			return nil, err
+		}
+		for _, stream := range dequeueOrder(sqs.priorities.Order(), queues, readyStream) {
+			job, err := sqs.read(stream)
+			if err != nil || job != nil {
+				return job, err
+			}
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil // No job available
+		}
+		time.Sleep(min(remaining, dequeuePollInterval))
+	}
+}
+
+// read takes the next undelivered entry of a stream for this consumer without blocking
+func (sqs *StreamJobQueueService) read(stream string) (*models.QueueJob, error) {
+	if err := sqs.ensureGroup(stream); err != nil {
+		return nil, err
+	}
+
+	streams, err := sqs.client.XReadGroup(sqs.ctx, &redis.XReadGroupArgs{
+		Group:    StreamGroup,
+		Consumer: sqs.consumer,
+		Streams:  []string{stream, ">"},
+		Count:    1,
+		Block:    -1, // Do not block at all; a zero block would wait forever
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue job: %w", err)
+	}
+
+	for _, result := range streams {
+		for _, message := range result.Messages {
+			if job, ok := sqs.hold(result.Stream, message); ok {
+				return job, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// hold records a stream entry delivered to this consumer and returns its job.
+// Entries that cannot be decoded are acknowledged and dropped, since no worker could run them.
+func (sqs *StreamJobQueueService) hold(stream string, message redis.XMessage) (*models.QueueJob, bool) {
+	data, _ := message.Values[streamField].(string)
+	job, err := models.DeserializeQueueJob([]byte(data))
+	if data == "" || err != nil {
+		log.Printf("Warning: dropping undecodable stream entry %s of %s: %v", message.ID, stream, err)
+		sqs.remove(stream, message.ID)
+		return nil, false
+	}
+
+	sqs.mu.Lock()
+	sqs.deliveries[job.ID] = streamDelivery{stream: stream, entryID: message.ID, data: data}
+	sqs.mu.Unlock()
+	return job, true
+}
+
+// release forgets a job this consumer holds and returns its stream entry
+func (sqs *StreamJobQueueService) release(jobID string) (streamDelivery, bool) {
+	sqs.mu.Lock()
+	defer sqs.mu.Unlock()
+	delivery, ok := sqs.deliveries[jobID]
+	delete(sqs.deliveries, jobID)
+	return delivery, ok
+}
+
+// remove acknowledges a stream entry and deletes it from its stream
+func (sqs *StreamJobQueueService) remove(stream, entryID string) error {
+	_, err := sqs.client.TxPipelined(sqs.ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(sqs.ctx, stream, StreamGroup, entryID)
+		pipe.XDel(sqs.ctx, stream, entryID)
+		return nil
+	})
+	return err
+}
+
+// requeue appends a held entry's job to its stream again and removes the entry, in one transaction
+func (sqs *StreamJobQueueService) requeue(delivery streamDelivery) error {
+	_, err := sqs.client.TxPipelined(sqs.ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(sqs.ctx, streamAddArgs(delivery.stream, delivery.data))
+		pipe.XAck(sqs.ctx, delivery.stream, StreamGroup, delivery.entryID)
+		pipe.XDel(sqs.ctx, delivery.stream, delivery.entryID)
+		return nil
+	})
+	return err
+}
+
+// DeferJob removes a job this consumer dequeued but did not start from its stream and adds it to the
+// retry queue, due after delay, without counting an attempt
+func (sqs *StreamJobQueueService) DeferJob(job *models.QueueJob, delay time.Duration) error {
+	delivery, ok := sqs.release(job.ID)
+	if !ok {
+		return nil
+	}
+
+	_, err := sqs.client.TxPipelined(sqs.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(sqs.ctx, QueueRetrying, redis.Z{Score: deferredScore(delay), Member: delivery.data})
+		pipe.XAck(sqs.ctx, delivery.stream, StreamGroup, delivery.entryID)
+		pipe.XDel(sqs.ctx, delivery.stream, delivery.entryID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to defer job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// acknowledge removes a job this consumer is done with from the stream
+func (sqs *StreamJobQueueService) acknowledge(jobID string) {
+	delivery, ok := sqs.release(jobID)
+	if !ok {
+		return
+	}
+	if err := sqs.remove(delivery.stream, delivery.entryID); err != nil {
+		log.Printf("Warning: failed to acknowledge job %s: %v", jobID, err)
+	}
+}
+
+// RequeueJob puts a job this consumer dequeued but did not start back on its stream for any consumer
+func (sqs *StreamJobQueueService) RequeueJob(job *models.QueueJob) error {
+	delivery, ok := sqs.release(job.ID)
+	if !ok {
+		return nil
+	}
+	if err := sqs.requeue(delivery); err != nil {
+		return fmt.Errorf("failed to requeue job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// ExtendLeases resets the idle time of the entries this consumer holds, so they are not claimed while
+// their jobs run. Entries another consumer claimed in the meantime are left with it.
+func (sqs *StreamJobQueueService) ExtendLeases() error {
+	sqs.mu.Lock()
+	held := make(map[string]map[string]bool)
+	for _, delivery := range sqs.deliveries {
+		if held[delivery.stream] == nil {
+			held[delivery.stream] = make(map[string]bool)
+		}
+		held[delivery.stream][delivery.entryID] = true
+	}
+	sqs.mu.Unlock()
+
+	for stream, entries := range held {
+		if err := sqs.extendLeases(stream, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extendLeases resets the idle time of the held entries of one stream
+func (sqs *StreamJobQueueService) extendLeases(stream string, held map[string]bool) error {
+	pending, err := sqs.client.XPendingExt(sqs.ctx, &redis.XPendingExtArgs{
+		Stream:   stream,
+		Group:    StreamGroup,
+		Start:    "-",
+		End:      "+",
+		Count:    int64(len(held)) + reapBatchSize,
+		Consumer: sqs.consumer,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get pending jobs of %s: %w", stream, err)
+	}
+
+	entryIDs := make([]string, 0, len(pending))
+	for _, entry := range pending {
+		if held[entry.ID] {
+			entryIDs = append(entryIDs, entry.ID)
+		}
+	}
+	if len(entryIDs) == 0 {
+		return nil
+	}
+
+	// Claiming an entry for its own consumer resets its idle time
+	err = sqs.client.XClaimJustID(sqs.ctx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    StreamGroup,
+		Consumer: sqs.consumer,
+		Messages: entryIDs,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to extend job leases: %w", err)
+	}
+	return nil
+}
+
+// ExpiredJobs claims entries that have been idle under any consumer for longer than claimAfter
+// with XAUTOCLAIM and returns their jobs. Claiming is atomic, so each expired job is returned to one
+// worker only, and the claimed entries are pending under this consumer until ReapJob handles them.
+func (sqs *StreamJobQueueService) ExpiredJobs() ([]*models.QueueJob, error) {
+	streams, err := sqs.allStreams()
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*models.QueueJob
+	for _, stream := range streams {
+		if len(jobs) >= reapBatchSize {
+			break
+		}
+		if err := sqs.ensureGroup(stream); err != nil {
+			return nil, err
+		}
+		messages, _, err := sqs.client.XAutoClaim(sqs.ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    StreamGroup,
+			Consumer: sqs.consumer,
+			MinIdle:  sqs.claimAfter,
+			Start:    "0-0",
+			Count:    int64(reapBatchSize - len(jobs)),
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim expired jobs of %s: %w", stream, err)
+		}
+
+		for _, message := range messages {
+			if job, ok := sqs.hold(stream, message); ok {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+// ReapJob handles a job claimed by ExpiredJobs. AT_LEAST_ONCE jobs are appended to their stream again
+// to be delivered anew; AT_MOST_ONCE jobs may already have run, so they are dead-lettered instead.
+// It reports whether the job was reaped and whether it was requeued.
+func (sqs *StreamJobQueueService) ReapJob(job *models.QueueJob, onReaped func()) (bool, bool, error) {
+	delivery, ok := sqs.release(job.ID)
+	if !ok {
+		return false, false, nil
+	}
+	// ExpiredJobs claimed the entry from its worker, and it stays pending here until it is requeued
+	onReaped()
+
+	if job.Type == models.AT_LEAST_ONCE {
+		if err := sqs.requeue(delivery); err != nil {
+			return false, false, fmt.Errorf("failed to reap job %s: %w", job.ID, err)
+		}
+		log.Printf("Job %s (JobID: %d) was not acknowledged within %v, requeued", job.ID, job.JobID, sqs.claimAfter)
+		return true, true, nil
+	}
+
+	if err := sqs.remove(delivery.stream, delivery.entryID); err != nil {
+		return false, false, fmt.Errorf("failed to reap job %s: %w", job.ID, err)
+	}
+	errorMsg := fmt.Sprintf("job was not acknowledged within %v", sqs.claimAfter)
+	job.RecordFailure(errorMsg)
+	if err := deadLetter(sqs.ctx, sqs.client, job, errorMsg); err != nil {
+		return true, false, err
+	}
+	log.Printf("AT_MOST_ONCE job %s (JobID: %d) was not acknowledged within %v, not requeued", job.ID, job.JobID, sqs.claimAfter)
+	return true, false, nil
+}
+
+// CompleteJob acknowledges a job and records its result
+func (sqs *StreamJobQueueService) CompleteJob(jobID string, result *models.QueueJobResult) error {
+	sqs.acknowledge(jobID)
+
+	if err := recordResult(sqs.ctx, sqs.client, result); err != nil {
+		return err
+	}
+
+	log.Printf("Completed job %s with status %s", jobID, result.Status)
+	return nil
+}
+
+// DiscardJob acknowledges a job without recording a result
+func (sqs *StreamJobQueueService) DiscardJob(jobID string) {
+	sqs.acknowledge(jobID)
+}
+
+// FailJob acknowledges a failed job, records the attempt and schedules a retry or dead-letters the job
+func (sqs *StreamJobQueueService) FailJob(job *models.QueueJob, errorMsg string) error {
+	sqs.acknowledge(job.ID)
+
+	job.RecordFailure(errorMsg)
+	if job.ShouldRetry() {
+		return scheduleRetry(sqs.ctx, sqs.client, job, sqs.retryDefaults)
+	}
+	return deadLetter(sqs.ctx, sqs.client, job, errorMsg)
+}
+
+// ProcessRetryQueue appends retry jobs that are due to their streams
+func (sqs *StreamJobQueueService) ProcessRetryQueue() error {
+	return moveDueRetries(sqs.ctx, sqs.client, sqs.EnqueueJob)
+}
+
+// allStreams returns the streams of every known queue and priority, since any of them may hold
+// entries of a dead consumer
+func (sqs *StreamJobQueueService) allStreams() ([]string, error) {
+	queues, err := sqs.directory.list()
+	if err != nil {
+		return nil, err
+	}
+	return dequeueOrder(models.Priorities, queues, readyStream), nil
+}
+
+// ListQueues returns the depth of every known queue
+func (sqs *StreamJobQueueService) ListQueues() ([]*QueueInfo, error) {
+	return sqs.listQueues(nil)
+}
+
+// listQueues returns the undelivered jobs of every known queue, passing the pending entries of each
+// stream to onPending if it is set
+func (sqs *StreamJobQueueService) listQueues(onPending func(*redis.XPending)) ([]*QueueInfo, error) {
+	names, err := sqs.directory.list()
+	if err != nil {
+		return nil, err
+	}
+
+	queues := make([]*QueueInfo, 0, len(names))
+	for _, name := range names {
+		info := newQueueInfo(name)
+		for _, priority := range models.Priorities {
+			stream := readyStream(name, priority)
+			if err := sqs.ensureGroup(stream); err != nil {
+				return nil, err
+			}
+
+			// Acknowledged entries are deleted, so the stream holds the undelivered and the pending ones
+			streamLen, err := sqs.client.XLen(sqs.ctx, stream).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get length of %s: %w", stream, err)
+			}
+
+			pending, err := sqs.client.XPending(sqs.ctx, stream, StreamGroup).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get pending jobs of %s: %w", stream, err)
+			}
+			info.ReadyByPriority[priority] = streamLen - pending.Count
+			info.Ready += streamLen - pending.Count
+			if onPending != nil {
+				onPending(pending)
+			}
+		}
+		queues = append(queues, info)
+	}
+	return queues, nil
+}
+
+// GetQueueStats returns statistics about the job queues. Besides the totals, "ready:<priority>" gives
+// the undelivered jobs of each priority, "queue:<name>" those of each queue and "processing:<consumer>"
+// the jobs pending under each consumer.
+func (sqs *StreamJobQueueService) GetQueueStats() (map[string]int64, error) {
+	stats := make(map[string]int64)
+
+	queues, err := sqs.listQueues(func(pending *redis.XPending) {
+		stats["processing"] += pending.Count
+		for consumer, count := range pending.Consumers {
+			stats["processing:"+consumer] += count
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	addReadyStats(stats, queues)
+
+	completedLen, err := sqs.client.LLen(sqs.ctx, QueueCompleted).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get completed queue length: %w", err)
+	}
+	stats["completed"] = completedLen
+
+	retryingLen, err := sqs.client.ZCard(sqs.ctx, QueueRetrying).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retry queue length: %w", err)
+	}
+	stats["retrying"] = retryingLen
+
+	failedLen, err := countDeadLetters(sqs.ctx, sqs.client)
+	if err != nil {
+		return nil, err
+	}
+	stats["failed"] = failedLen
+
+	return stats, nil
+}
+
+// streamAddArgs returns the arguments that append job data to a stream
+func streamAddArgs(stream, data string) *redis.XAddArgs {
+	return &redis.XAddArgs{
+		Stream: stream,
+		Values: map[string]interface{}{streamField: data},
+	}
+}
@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -14,43 +16,96 @@ import (
 	"github.com/manyu/job-scheduler/internal/storage"
 )
 
+// Version is the build version workers report in the worker registry, set with
+// -ldflags "-X github.com/manyu/job-scheduler/internal/services.Version=<version>"
+var Version = "dev"
+
+// workerHeartbeatInterval is how often a worker extends the leases of its jobs and re-registers itself
+const workerHeartbeatInterval = inFlightLease / 3
+
+// workerRegistrationTTL is how long a worker stays listed after its last heartbeat
+const workerRegistrationTTL = 3 * workerHeartbeatInterval
+
+// executionExpiryGrace is how long past its timeout an execution may stay in progress before it is
+// expired. A worker that dies mid-run has its job reaped within inFlightLease, so an execution older
+// than that was lost without being closed.
+const executionExpiryGrace = inFlightLease
+
+// overlapRetryDelay is how long a run of a job with the Queue concurrency policy waits before checking
+// again whether the earlier runs finished
+const overlapRetryDelay = time.Second
+
+// retryPromotionInterval is how often deferred and retrying jobs that fell due are moved back to the
+// ready queue. It is kept short because rate limit and concurrency deferrals last about a second.
+const retryPromotionInterval = time.Second
+
+// reapInterval is how often in-flight jobs past their visibility deadline are recovered
+const reapInterval = 10 * time.Second
+
+// errExecutionReplaced stops an execution that a newer run replaced
+var errExecutionReplaced = errors.New("execution replaced by a newer run")
+
 // WorkerService handles job execution from the Redis queue
 type WorkerService struct {
-	jobQueue   *JobQueueService
-	storage    *storage.PostgresStorage
-	scheduler  SchedulerServiceInterface
-	httpClient *http.Client
-	workerPool chan struct{} // Semaphore for limiting concurrent workers
-	ctx        context.Context
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
-	shutdown   bool
-	shutdownMu sync.RWMutex
+	jobQueue       WorkerQueueInterface
+	storage        *storage.PostgresStorage
+	scheduler      SchedulerServiceInterface
+	registry       *WorkerRegistry // nil when the worker does not register itself
+	limiter        *RateLimiter    // nil when calls are not rate limited
+	signer         *RequestSigner  // nil when calls are not signed
+	credentials    *CredentialProvider
+	host           string
+	startedAt      time.Time
+	activeJobs     map[string]struct{}              // Queue job IDs being processed
+	running        map[uint]context.CancelCauseFunc // Stops the execution, by execution ID
+	activeJobsMu   sync.Mutex
+	httpClient     *http.Client
+	defaultTimeout time.Duration // Used for queue entries without a per-job timeout
+	workerPool     chan struct{} // Semaphore for limiting concurrent workers
+	ctx            context.Context
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+	shutdown       bool
+	shutdownMu     sync.RWMutex
 }
 
 // NewWorkerService creates a new worker service
-func NewWorkerService(jobQueue *JobQueueService, storage *storage.PostgresStorage, scheduler SchedulerServiceInterface) *WorkerService {
+func NewWorkerService(jobQueue WorkerQueueInterface, storage *storage.PostgresStorage, scheduler SchedulerServiceInterface, registry *WorkerRegistry, limiter *RateLimiter, signer *RequestSigner, credentials *CredentialProvider) *WorkerService {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Get worker configuration from environment
 	maxWorkers := getEnvIntOrDefault("WORKER_POOL_SIZE", 10)
 	httpTimeout := getEnvIntOrDefault("WORKER_HTTP_TIMEOUT", 90) // 90 seconds default
 
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
 	return &WorkerService{
-		jobQueue:  jobQueue,
-		storage:   storage,
-		scheduler: scheduler,
+		jobQueue:    jobQueue,
+		storage:     storage,
+		scheduler:   scheduler,
+		registry:    registry,
+		limiter:     limiter,
+		signer:      signer,
+		credentials: credentials,
+		host:        host,
+		startedAt:   time.Now(),
+		activeJobs:  make(map[string]struct{}),
+		running:     make(map[uint]context.CancelCauseFunc),
+		// Timeouts are applied per job through the request context
 		httpClient: &http.Client{
-			Timeout: time.Duration(httpTimeout) * time.Second,
 			Transport: &http.Transport{
 				MaxIdleConns:        100,
 				MaxIdleConnsPerHost: 100,
 				IdleConnTimeout:     90 * time.Second,
 			},
 		},
-		workerPool: make(chan struct{}, maxWorkers),
-		ctx:        ctx,
-		cancel:     cancel,
+		defaultTimeout: time.Duration(httpTimeout) * time.Second,
+		workerPool:     make(chan struct{}, maxWorkers),
+		ctx:            ctx,
+		cancel:         cancel,
 	}
 }
 
@@ -62,6 +117,16 @@ func (ws *WorkerService) Start() {
 	ws.wg.Add(1)
 	go ws.processRetryQueue()
 
+	// Start heartbeat, which keeps the leases of running jobs and the registration alive
+	ws.wg.Add(1)
+	go ws.heartbeat()
+
+	// Stop executions that newer runs on other workers replaced
+	if ws.registry != nil {
+		ws.wg.Add(1)
+		go ws.listenForCancellations()
+	}
+
 	// Start main worker loop
 	ws.wg.Add(1)
 	go ws.workerLoop()
@@ -76,6 +141,14 @@ func (ws *WorkerService) Stop() {
 	log.Println("Stopping worker service...")
 	ws.cancel()
 	ws.wg.Wait()
+
+	if ws.registry != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), workerHeartbeatInterval)
+		defer cancel()
+		if err := ws.registry.Deregister(ctx, ws.jobQueue.WorkerID()); err != nil {
+			log.Printf("Failed to deregister worker: %v", err)
+		}
+	}
 	log.Println("Worker service stopped")
 }
 
@@ -119,7 +192,9 @@ func (ws *WorkerService) workerLoop() {
 				go ws.processJob(job)
 			case <-ws.ctx.Done():
 				// Context cancelled, put job back in queue if possible
-				ws.jobQueue.EnqueueJob(job)
+				if err := ws.jobQueue.RequeueJob(job); err != nil {
+					log.Printf("Failed to requeue job %s on shutdown: %v", job.ID, err)
+				}
 				return
 			}
 		}
@@ -131,29 +206,47 @@ func (ws *WorkerService) processJob(job *models.QueueJob) {
 	defer ws.wg.Done()
 	defer func() { <-ws.workerPool }() // Release worker slot
 
+	ws.setActive(job.ID, true)
+	defer ws.setActive(job.ID, false)
+
 	log.Printf("Processing job %s (JobID: %d, attempt %d/%d)",
 		job.ID, job.JobID, job.RetryCount+1, job.MaxRetryCount+1)
 
-	// Check if there's already an execution in progress for this job
-	existingExecution, err := ws.storage.GetJobExecutionInProgress(job.JobID)
+	// Queue entries can outlive their job, so drop them if the job was deleted or paused since enqueueing
+	dbJob, err := ws.storage.GetJob(job.JobID)
 	if err != nil {
-		log.Printf("Failed to check for existing execution for job %s: %v", job.ID, err)
-		ws.jobQueue.FailJob(job, fmt.Sprintf("Failed to check for existing execution: %v", err))
+		if err == storage.ErrJobNotFound {
+			log.Printf("Job %s (JobID: %d) no longer exists, discarding", job.ID, job.JobID)
+			ws.jobQueue.DiscardJob(job.ID)
+			return
+		}
+		log.Printf("Failed to load job %s: %v", job.ID, err)
+		ws.failRun(job, fmt.Sprintf("Failed to load job: %v", err))
 		return
 	}
 
-	if existingExecution != nil {
-		log.Printf("Job %s (JobID: %d) already has an execution in progress, skipping", job.ID, job.JobID)
-		// Remove from processing queue since we're not processing it
-		if err := ws.jobQueue.client.SRem(ws.jobQueue.ctx, "job_queue:processing", job.ID).Err(); err != nil {
-			log.Printf("Warning: failed to remove job %s from processing queue: %v", job.ID, err)
-		}
+	if !dbJob.IsActive {
+		log.Printf("Job %s (JobID: %d) is paused, discarding", job.ID, job.JobID)
+		ws.jobQueue.DiscardJob(job.ID)
+		return
+	}
+
+	// Earlier runs still in progress are allowed, skipped over, replaced or waited for
+	if !ws.applyConcurrencyPolicy(job, dbJob.ConcurrencyPolicy.OrForbid()) {
 		return
 	}
 
+	// A target at its rate limit or concurrency cap gets the job later, without holding this slot meanwhile
+	release, ok := ws.acquireRateLimit(job)
+	if !ok {
+		return
+	}
+	defer release()
+
 	// Create job execution record
 	execution := &models.JobExecution{
 		JobID:         job.JobID,
+		OccurrenceID:  job.OccurrenceID,
 		Status:        models.StatusScheduled,
 		ExecutionTime: time.Now(),
 		RetryCount:    job.RetryCount,
@@ -161,7 +254,7 @@ func (ws *WorkerService) processJob(job *models.QueueJob) {
 
 	if err := ws.storage.CreateJobExecution(execution); err != nil {
 		log.Printf("Failed to create execution record for job %s: %v", job.ID, err)
-		ws.jobQueue.FailJob(job, fmt.Sprintf("Failed to create execution record: %v", err))
+		ws.failRun(job, fmt.Sprintf("Failed to create execution record: %v", err))
 		return
 	}
 
@@ -172,19 +265,38 @@ func (ws *WorkerService) processJob(job *models.QueueJob) {
 	}
 
 	// Execute the job
+	runCtx, stop := context.WithCancelCause(ws.ctx)
+	ws.setRunning(execution.ID, stop)
 	startTime := time.Now()
-	success := ws.callJobAPI(job.API)
+	callCtx, cancel := context.WithTimeout(runCtx, ws.jobTimeout(job))
+	result := ws.callJobAPI(callCtx, job, execution.ID)
+	cancel()
+	ws.setRunning(execution.ID, nil)
+	stop(nil)
 	executionDuration := time.Since(startTime)
+
+	// A replaced execution was already closed by the run that replaced it
+	if errors.Is(context.Cause(runCtx), errExecutionReplaced) {
+		log.Printf("Job %s (JobID: %d) was replaced by a newer run, stopped", job.ID, job.JobID)
+		ws.jobQueue.DiscardJob(job.ID)
+		return
+	}
+
 	execution.ExecutionDuration = &executionDuration
+	result.applyTo(execution)
+	success := result.Success
 
 	// Update execution status based on result
 	if success {
 		execution.Status = models.StatusSuccess
 		log.Printf("Job %s executed successfully (attempt %d)", job.ID, job.RetryCount+1)
+	} else if result.ErrorClass == models.ErrorClassTimeout {
+		execution.Status = models.StatusTimeout
+		log.Printf("Job %s timed out after %v (attempt %d/%d)", job.ID, ws.jobTimeout(job), job.RetryCount+1, job.MaxRetryCount+1)
 	} else {
 		execution.Status = models.StatusFailed
-		execution.Error = "API call failed"
-		log.Printf("Job %s failed (attempt %d/%d)", job.ID, job.RetryCount+1, job.MaxRetryCount+1)
+		job.LastStatusCode = result.StatusCode
+		log.Printf("Job %s failed (attempt %d/%d): %s", job.ID, job.RetryCount+1, job.MaxRetryCount+1, execution.Error)
 	}
 
 	if err := ws.storage.UpdateJobExecution(execution); err != nil {
@@ -200,23 +312,140 @@ func (ws *WorkerService) processJob(job *models.QueueJob) {
 	}
 }
 
-// callJobAPI makes HTTP call to the job's API endpoint
-func (ws *WorkerService) callJobAPI(apiURL string) bool {
-	req, err := http.NewRequestWithContext(ws.ctx, "POST", apiURL, nil)
+// applyConcurrencyPolicy handles the runs of job still in progress under policy and reports whether
+// this run may go ahead. Runs past their deadline are expired first, so a run that was lost without
+// being closed does not block the job forever.
+func (ws *WorkerService) applyConcurrencyPolicy(job *models.QueueJob, policy models.ConcurrencyPolicy) bool {
+	if policy == models.ConcurrencyAllow {
+		return true
+	}
+
+	expired, err := ws.storage.ExpireJobExecutions(job.JobID, time.Now().Add(-(ws.jobTimeout(job) + executionExpiryGrace)))
 	if err != nil {
-		log.Printf("Failed to create request for %s: %v", apiURL, err)
-		return false
+		log.Printf("Failed to expire stuck executions of job %s: %v", job.ID, err)
+	} else if expired > 0 {
+		log.Printf("Expired %d stuck execution(s) of job %s (JobID: %d)", expired, job.ID, job.JobID)
 	}
 
-	resp, err := ws.httpClient.Do(req)
+	inProgress, err := ws.storage.GetJobExecutionsInProgress(job.JobID)
 	if err != nil {
-		log.Printf("Failed to call API %s: %v", apiURL, err)
+		log.Printf("Failed to check for existing execution for job %s: %v", job.ID, err)
+		ws.failRun(job, fmt.Sprintf("Failed to check for existing execution: %v", err))
 		return false
 	}
-	defer resp.Body.Close()
+	if len(inProgress) == 0 {
+		return true
+	}
+
+	switch policy {
+	case models.ConcurrencyReplace:
+		for _, execution := range inProgress {
+			ws.replaceExecution(execution, job)
+		}
+		return true
 
-	// Consider 2xx status codes as success
-	return resp.StatusCode >= 200 && resp.StatusCode < 300
+	case models.ConcurrencyQueue:
+		log.Printf("Job %s (JobID: %d) waits for %d execution(s) in progress", job.ID, job.JobID, len(inProgress))
+		if err := ws.jobQueue.DeferJob(job, overlapRetryDelay); err != nil {
+			log.Printf("Failed to defer job %s: %v", job.ID, err)
+		}
+		return false
+
+	default:
+		log.Printf("Job %s (JobID: %d) already has an execution in progress, skipping", job.ID, job.JobID)
+		skipped := &models.JobExecution{
+			JobID:         job.JobID,
+			OccurrenceID:  job.OccurrenceID,
+			Status:        models.StatusSkipped,
+			Error:         fmt.Sprintf("execution %d of the job was still in progress", inProgress[len(inProgress)-1].ID),
+			ExecutionTime: time.Now(),
+			RetryCount:    job.RetryCount,
+		}
+		if err := ws.storage.CreateJobExecution(skipped); err != nil {
+			log.Printf("Failed to record skipped run of job %s: %v", job.ID, err)
+		}
+		// Remove from processing queue since we're not processing it
+		ws.jobQueue.DiscardJob(job.ID)
+		return false
+	}
+}
+
+// replaceExecution cancels an execution in progress for the run of job that replaces it, and stops it
+// on whichever worker runs it
+func (ws *WorkerService) replaceExecution(execution *models.JobExecution, job *models.QueueJob) {
+	cancelled, err := ws.storage.CancelJobExecution(execution.ID, fmt.Sprintf("replaced by run %s", job.OccurrenceID))
+	if err != nil {
+		log.Printf("Failed to cancel execution %d of job %s: %v", execution.ID, job.ID, err)
+		return
+	}
+	if !cancelled {
+		return // Finished in the meantime
+	}
+	log.Printf("Job %s (JobID: %d) replaces execution %d", job.ID, job.JobID, execution.ID)
+
+	if ws.registry == nil {
+		ws.cancelRunning(execution.ID)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ws.ctx, workerHeartbeatInterval)
+	defer cancel()
+	if err := ws.registry.CancelExecution(ctx, execution.ID); err != nil {
+		log.Printf("Failed to stop execution %d of job %s: %v", execution.ID, job.ID, err)
+	}
+}
+
+// listenForCancellations stops executions of this worker that newer runs replaced, until the service stops
+func (ws *WorkerService) listenForCancellations() {
+	defer ws.wg.Done()
+
+	for executionID := range ws.registry.Cancellations(ws.ctx) {
+		ws.cancelRunning(executionID)
+	}
+}
+
+// cancelRunning stops an execution if this worker runs it
+func (ws *WorkerService) cancelRunning(executionID uint) {
+	ws.activeJobsMu.Lock()
+	stop, ok := ws.running[executionID]
+	ws.activeJobsMu.Unlock()
+	if ok {
+		stop(errExecutionReplaced)
+	}
+}
+
+// setRunning records the function that stops a running execution, or forgets it when stop is nil
+func (ws *WorkerService) setRunning(executionID uint, stop context.CancelCauseFunc) {
+	ws.activeJobsMu.Lock()
+	defer ws.activeJobsMu.Unlock()
+	if stop != nil {
+		ws.running[executionID] = stop
+	} else {
+		delete(ws.running, executionID)
+	}
+}
+
+// acquireRateLimit takes a call to the job's target from the rate limiter and returns the function
+// that gives it back. It reports false, after deferring the job, when the target is at its limit.
+// Jobs run unlimited when the limiter cannot be reached.
+func (ws *WorkerService) acquireRateLimit(job *models.QueueJob) (func(), bool) {
+	if ws.limiter == nil {
+		return func() {}, true
+	}
+
+	key := rateLimitKey(job)
+	wait, err := ws.limiter.Acquire(key, job.ID, ws.jobTimeout(job)+rateLimitSlotGrace)
+	if err != nil {
+		log.Printf("Failed to check rate limit of job %s, running it unlimited: %v", job.ID, err)
+		return func() {}, true
+	}
+	if wait > 0 {
+		log.Printf("Job %s (JobID: %d) is rate limited under %s, deferring it by %v", job.ID, job.JobID, key, wait)
+		if err := ws.jobQueue.DeferJob(job, wait); err != nil {
+			log.Printf("Failed to defer job %s: %v", job.ID, err)
+		}
+		return nil, false
+	}
+	return func() { ws.limiter.Release(key, job.ID) }, true
 }
 
 // handleSuccessfulJob handles a successfully executed job
@@ -234,6 +463,11 @@ func (ws *WorkerService) handleSuccessfulJob(job *models.QueueJob, execution *mo
 		log.Printf("Failed to complete job %s: %v", job.ID, err)
 	}
 
+	// Notify scheduler so recurring jobs move on to their next run
+	if err := ws.scheduler.HandleJobCompletion(job.JobID, true); err != nil {
+		log.Printf("Failed to notify scheduler about job completion %s: %v", job.ID, err)
+	}
+
 	log.Printf("Job %s completed successfully", job.ID)
 }
 
@@ -258,18 +492,35 @@ func (ws *WorkerService) handleFailedJob(job *models.QueueJob, execution *models
 	}
 }
 
+// failRun fails a run that could not be executed. A run that will not be retried completes for the
+// scheduler, since its schedule otherwise stays dispatched and a recurring job never runs again.
+func (ws *WorkerService) failRun(job *models.QueueJob, errorMsg string) {
+	if err := ws.jobQueue.FailJob(job, errorMsg); err != nil {
+		log.Printf("Failed to handle failed job %s: %v", job.ID, err)
+	}
+	if job.ShouldRetry() {
+		return
+	}
+
+	if err := ws.scheduler.HandleJobCompletion(job.JobID, false); err != nil {
+		log.Printf("Failed to notify scheduler about job failure %s: %v", job.ID, err)
+	}
+}
+
 // processRetryQueue processes jobs that are ready for retry
 func (ws *WorkerService) processRetryQueue() {
 	defer ws.wg.Done()
 
-	ticker := time.NewTicker(10 * time.Second) // Check every 10 seconds for faster cleanup
-	defer ticker.Stop()
+	promoteTicker := time.NewTicker(retryPromotionInterval)
+	defer promoteTicker.Stop()
+	reapTicker := time.NewTicker(reapInterval)
+	defer reapTicker.Stop()
 
 	for {
 		select {
 		case <-ws.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-promoteTicker.C:
 			if ws.IsShutdown() {
 				return
 			}
@@ -277,28 +528,134 @@ func (ws *WorkerService) processRetryQueue() {
 			if err := ws.jobQueue.ProcessRetryQueue(); err != nil {
 				log.Printf("Error processing retry queue: %v", err)
 			}
-
-			// Cleanup stale jobs every 10 seconds
-			if err := ws.jobQueue.CleanupStaleJobs(1 * time.Hour); err != nil {
-				log.Printf("Error cleaning up stale jobs: %v", err)
+		case <-reapTicker.C:
+			if ws.IsShutdown() {
+				return
 			}
+
+			// Recover jobs of workers that died or hung
+			ws.reapExpiredJobs()
 		}
 	}
 }
 
-// GetStats returns worker statistics
-func (ws *WorkerService) GetStats() map[string]interface{} {
-	queueStats, err := ws.jobQueue.GetQueueStats()
+// reapExpiredJobs recovers in-flight jobs whose worker missed their visibility deadline.
+// AT_LEAST_ONCE jobs are delivered again and AT_MOST_ONCE jobs are failed.
+func (ws *WorkerService) reapExpiredJobs() {
+	jobs, err := ws.jobQueue.ExpiredJobs()
 	if err != nil {
-		log.Printf("Failed to get queue stats: %v", err)
-		queueStats = make(map[string]int64)
+		log.Printf("Error getting expired jobs: %v", err)
+		return
 	}
 
-	return map[string]interface{}{
-		"active_workers": len(ws.workerPool),
-		"max_workers":    cap(ws.workerPool),
-		"queue_stats":    queueStats,
-		"is_shutdown":    ws.IsShutdown(),
+	for _, job := range jobs {
+		// The lost worker left its execution running, which would make the next delivery skip the job
+		// as already in progress, so it is closed once the job is reaped and before it is requeued. A
+		// worker that extended its lease in the meantime is still running the job and keeps it.
+		reaped, requeued, err := ws.jobQueue.ReapJob(job, func() { ws.failLostExecution(job) })
+		if err != nil {
+			log.Printf("Error reaping job %s: %v", job.ID, err)
+			continue
+		}
+		if !reaped || requeued {
+			continue
+		}
+
+		// The run will not be retried, so let recurring jobs move on
+		if err := ws.scheduler.HandleJobCompletion(job.JobID, false); err != nil {
+			log.Printf("Failed to notify scheduler about lost job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// failLostExecution marks the execution a lost worker left in progress for job as failed
+func (ws *WorkerService) failLostExecution(job *models.QueueJob) {
+	execution, err := ws.storage.GetJobExecutionInProgress(job.JobID)
+	if err != nil {
+		log.Printf("Failed to check for lost execution of job %s: %v", job.ID, err)
+		return
+	}
+	if execution == nil || (execution.OccurrenceID != "" && execution.OccurrenceID != job.OccurrenceID) {
+		return
+	}
+
+	execution.Status = models.StatusFailed
+	execution.Error = "worker stopped responding before the job finished"
+	if err := ws.storage.UpdateJobExecution(execution); err != nil {
+		log.Printf("Failed to fail lost execution of job %s: %v", job.ID, err)
+	}
+}
+
+// heartbeat extends the leases of the jobs this worker holds and re-registers it until the service stops
+func (ws *WorkerService) heartbeat() {
+	defer ws.wg.Done()
+
+	ticker := time.NewTicker(workerHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		ws.sendHeartbeat()
+
+		select {
+		case <-ws.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendHeartbeat extends the leases of held jobs and registers the worker with its current stats
+func (ws *WorkerService) sendHeartbeat() {
+	if err := ws.jobQueue.ExtendLeases(); err != nil {
+		log.Printf("Failed to extend job leases: %v", err)
+	}
+
+	if ws.registry == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ws.ctx, workerHeartbeatInterval)
+	defer cancel()
+
+	stats := ws.GetStats()
+	stats.HeartbeatAt = time.Now()
+	if err := ws.registry.Heartbeat(ctx, stats, workerRegistrationTTL); err != nil {
+		log.Printf("Failed to send worker heartbeat: %v", err)
+	}
+}
+
+// setActive records whether a job is being processed
+func (ws *WorkerService) setActive(jobID string, active bool) {
+	ws.activeJobsMu.Lock()
+	defer ws.activeJobsMu.Unlock()
+	if active {
+		ws.activeJobs[jobID] = struct{}{}
+	} else {
+		delete(ws.activeJobs, jobID)
+	}
+}
+
+// GetStats returns worker statistics
+func (ws *WorkerService) GetStats() *WorkerStats {
+	ws.activeJobsMu.Lock()
+	activeJobIDs := make([]string, 0, len(ws.activeJobs))
+	for jobID := range ws.activeJobs {
+		activeJobIDs = append(activeJobIDs, jobID)
+	}
+	ws.activeJobsMu.Unlock()
+	sort.Strings(activeJobIDs)
+
+	subscription := ws.jobQueue.Subscription()
+	return &WorkerStats{
+		ID:            ws.jobQueue.WorkerID(),
+		Host:          ws.host,
+		Version:       Version,
+		StartedAt:     ws.startedAt,
+		PoolSize:      cap(ws.workerPool),
+		ActiveWorkers: len(ws.workerPool),
+		ActiveJobIDs:  activeJobIDs,
+		Queues:        subscription.Queues,
+		Tags:          subscription.Tags,
+		IsShutdown:    ws.IsShutdown(),
 	}
 }
 
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	redisclient "github.com/manyu/job-scheduler/internal/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+// WorkerStats describes a worker and what it is running
+type WorkerStats struct {
+	ID            string         `json:"id"`             // Instance ID the worker consumes the queue as
+	Host          string         `json:"host"`           // Hostname of the machine or container
+	Version       string         `json:"version"`        // Build version of the worker binary
+	StartedAt     time.Time      `json:"startedAt"`      // When the worker service was created
+	HeartbeatAt   time.Time      `json:"heartbeatAt"`    // When the worker last registered itself
+	PoolSize      int            `json:"poolSize"`       // Jobs the worker runs at most at once
+	ActiveWorkers int            `json:"activeWorkers"`  // Jobs the worker is running now
+	ActiveJobIDs  []string       `json:"activeJobIds"`   // Queue job IDs of the jobs it is running
+	Queues        []string       `json:"queues"`         // Named queues the worker takes jobs from
+	Tags          models.JobTags `json:"tags,omitempty"` // Tags the worker has, selecting the tagged jobs it takes
+	IsShutdown    bool           `json:"isShutdown"`     // Whether the worker is shutting down
+}
+
+// WorkerRegistry records live workers in Redis. Each worker re-registers itself on every heartbeat
+// and counts as live until its registration expires.
+type WorkerRegistry struct {
+	client *redis.Client
+}
+
+// Worker registry keys
+const (
+	WorkerMembersKey    = "workers:live"   // Sorted set of worker IDs scored by registration expiry in ms
+	WorkerKeyBase       = "workers:info:"  // Followed by the worker ID, holds its WorkerStats as JSON
+	WorkerCancelChannel = "workers:cancel" // Pub/sub channel carrying the IDs of executions to stop
+)
+
+// NewWorkerRegistry creates a worker registry on Redis
+func NewWorkerRegistry(redisClient redisclient.RedisClientInterface) *WorkerRegistry {
+	return &WorkerRegistry{
+		client: redisClient.GetClient(),
+	}
+}
+
+// Heartbeat registers a worker as live with stats until ttl from now
+func (r *WorkerRegistry) Heartbeat(ctx context.Context, stats *WorkerStats, ttl time.Duration) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("failed to serialize worker stats: %w", err)
+	}
+
+	expiresAt := time.Now().Add(ttl).UnixMilli()
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, workerKey(stats.ID), data, ttl)
+		pipe.ZAdd(ctx, WorkerMembersKey, redis.Z{Score: float64(expiresAt), Member: stats.ID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register worker: %w", err)
+	}
+	return nil
+}
+
+// Deregister removes a worker at once instead of waiting for its registration to expire
+func (r *WorkerRegistry) Deregister(ctx context.Context, workerID string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, workerKey(workerID))
+		pipe.ZRem(ctx, WorkerMembersKey, workerID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deregister worker: %w", err)
+	}
+	return nil
+}
+
+// List prunes workers whose registration expired and returns the live ones ordered by ID
+func (r *WorkerRegistry) List(ctx context.Context) ([]*WorkerStats, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if err := r.client.ZRemRangeByScore(ctx, WorkerMembersKey, "-inf", "("+now).Err(); err != nil {
+		return nil, fmt.Errorf("failed to prune workers: %w", err)
+	}
+	workerIDs, err := r.client.ZRangeByScore(ctx, WorkerMembersKey, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workers: %w", err)
+	}
+
+	workers := make([]*WorkerStats, 0, len(workerIDs))
+	if len(workerIDs) == 0 {
+		return workers, nil
+	}
+
+	keys := make([]string, len(workerIDs))
+	for i, workerID := range workerIDs {
+		keys[i] = workerKey(workerID)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worker stats: %w", err)
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // Expired between the two reads
+		}
+		var stats WorkerStats
+		if err := json.Unmarshal([]byte(data), &stats); err != nil {
+			log.Printf("Warning: failed to deserialize stats of worker %s: %v", workerIDs[i], err)
+			continue
+		}
+		workers = append(workers, &stats)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers, nil
+}
+
+func workerKey(workerID string) string {
+	return WorkerKeyBase + workerID
+}
+
+// CancelExecution asks whichever worker runs an execution to stop it. Workers that do not run it
+// ignore the request.
+func (r *WorkerRegistry) CancelExecution(ctx context.Context, executionID uint) error {
+	if err := r.client.Publish(ctx, WorkerCancelChannel, executionID).Err(); err != nil {
+		return fmt.Errorf("failed to publish cancellation of execution %d: %w", executionID, err)
+	}
+	return nil
+}
+
+// Cancellations delivers the IDs of executions to stop, as published by CancelExecution, until ctx is done
+func (r *WorkerRegistry) Cancellations(ctx context.Context) <-chan uint {
+	pubsub := r.client.Subscribe(ctx, WorkerCancelChannel)
+	executionIDs := make(chan uint)
+
+	// Wait for the subscription to be confirmed, so no cancellation published after this returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Printf("Warning: failed to subscribe to execution cancellations: %v", err)
+	}
+
+	go func() {
+		defer close(executionIDs)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				executionID, err := strconv.ParseUint(message.Payload, 10, 64)
+				if err != nil {
+					log.Printf("Warning: ignoring malformed execution cancellation %q", message.Payload)
+					continue
+				}
+				select {
+				case executionIDs <- uint(executionID):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return executionIDs
+}
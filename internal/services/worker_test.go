@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manyu/job-scheduler/internal/models"
+	"github.com/manyu/job-scheduler/pkg/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWorkerService creates a worker service that only has what callJobAPI needs
+func newTestWorkerService() *WorkerService {
+	return &WorkerService{
+		httpClient:     &http.Client{},
+		defaultTimeout: 5 * time.Second,
+		ctx:            context.Background(),
+	}
+}
+
+func TestWorkerService_CallJobAPI_DefaultsToBarePost(t *testing.T) {
+	var method string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	job := &models.QueueJob{ID: "job_1", JobID: 1, API: server.URL}
+
+	assert.True(t, newTestWorkerService().callJobAPI(context.Background(), job, 0).Success)
+	assert.Equal(t, http.MethodPost, method)
+	assert.Empty(t, body)
+}
+
+func TestWorkerService_CallJobAPI_UsesRequestSpec(t *testing.T) {
+	var captured *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	job := &models.QueueJob{
+		ID:         "job_7",
+		JobID:      7,
+		API:        server.URL + "/hook?source=scheduler",
+		RetryCount: 1,
+		Request: models.HTTPRequestSpec{
+			Method:  "put",
+			Headers: map[string]string{"X-Tenant": "acme"},
+			Query:   map[string]string{"dryRun": "true"},
+			Body:    `{"jobId": {{.JobID}}, "attempt": {{.Attempt}}}`,
+		},
+	}
+
+	require.True(t, newTestWorkerService().callJobAPI(context.Background(), job, 0).Success)
+	assert.Equal(t, http.MethodPut, captured.Method)
+	assert.Equal(t, "/hook", captured.URL.Path)
+	assert.Equal(t, "scheduler", captured.URL.Query().Get("source"))
+	assert.Equal(t, "true", captured.URL.Query().Get("dryRun"))
+	assert.Equal(t, "acme", captured.Header.Get("X-Tenant"))
+	assert.Equal(t, "application/json", captured.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"jobId": 7, "attempt": 2}`, string(body))
+}
+
+func TestWorkerService_BuildJobRequest_IdempotencyKey(t *testing.T) {
+	ws := newTestWorkerService()
+	scheduledAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	newJob := func(jobType models.JobType, retryCount int) *models.QueueJob {
+		return &models.QueueJob{
+			ID:           fmt.Sprintf("job_3_%d", retryCount),
+			JobID:        3,
+			OccurrenceID: models.OccurrenceID(3, scheduledAt),
+			API:          "http://localhost/hook",
+			RetryCount:   retryCount,
+			ScheduledAt:  scheduledAt,
+			Type:         jobType,
+		}
+	}
+
+	// Every retry of an occurrence carries the same key
+	first, err := ws.buildJobRequest(context.Background(), newJob(models.AT_LEAST_ONCE, 0), 0)
+	require.NoError(t, err)
+	retry, err := ws.buildJobRequest(context.Background(), newJob(models.AT_LEAST_ONCE, 2), 0)
+	require.NoError(t, err)
+	assert.Equal(t, models.OccurrenceID(3, scheduledAt), first.Header.Get(models.IdempotencyKeyHeader))
+	assert.Equal(t, first.Header.Get(models.IdempotencyKeyHeader), retry.Header.Get(models.IdempotencyKeyHeader))
+
+	// Entries without an occurrence ID derive the same key
+	legacy := newJob(models.AT_LEAST_ONCE, 0)
+	legacy.OccurrenceID = ""
+	req, err := ws.buildJobRequest(context.Background(), legacy, 0)
+	require.NoError(t, err)
+	assert.Equal(t, models.OccurrenceID(3, scheduledAt), req.Header.Get(models.IdempotencyKeyHeader))
+
+	req, err = ws.buildJobRequest(context.Background(), newJob(models.AT_MOST_ONCE, 0), 0)
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get(models.IdempotencyKeyHeader))
+
+	// A key the job sends itself is kept
+	custom := newJob(models.AT_LEAST_ONCE, 0)
+	custom.Request.Headers = map[string]string{"Idempotency-Key": "order-42"}
+	req, err = ws.buildJobRequest(context.Background(), custom, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "order-42", req.Header.Get(models.IdempotencyKeyHeader))
+}
+
+func TestWorkerService_CallJobAPI_CapturesNon2xxResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc123")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(strings.Repeat("x", maxResponseBodySnippet+100)))
+	}))
+	defer server.Close()
+
+	job := &models.QueueJob{ID: "job_1", JobID: 1, API: server.URL}
+
+	result := newTestWorkerService().callJobAPI(context.Background(), job, 0)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, http.StatusInternalServerError, result.StatusCode)
+	assert.Equal(t, models.ErrorClassHTTPStatus, result.ErrorClass)
+	assert.Equal(t, "abc123", result.Headers["X-Request-Id"])
+	assert.NotContains(t, result.Headers, "Set-Cookie")
+	assert.Len(t, result.BodySnippet, maxResponseBodySnippet)
+	assert.Greater(t, result.Latency.TimeToFirstByte, time.Duration(0))
+
+	execution := &models.JobExecution{}
+	result.applyTo(execution)
+	assert.Equal(t, http.StatusInternalServerError, execution.ResponseStatusCode)
+	assert.Equal(t, models.ErrorClassHTTPStatus, execution.ErrorClass)
+	assert.Contains(t, execution.Error, "500")
+}
+
+func TestWorkerService_CallJobAPI_ClassifiesTransportErrors(t *testing.T) {
+	t.Run("connection refused", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		listener.Close()
+
+		job := &models.QueueJob{ID: "job_1", JobID: 1, API: "http://" + addr}
+		result := newTestWorkerService().callJobAPI(context.Background(), job, 0)
+
+		assert.False(t, result.Success)
+		assert.Equal(t, models.ErrorClassConnectionRefused, result.ErrorClass)
+		assert.Zero(t, result.StatusCode)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		job := &models.QueueJob{ID: "job_1", JobID: 1, API: server.URL}
+		result := newTestWorkerService().callJobAPI(ctx, job, 0)
+
+		assert.False(t, result.Success)
+		assert.Equal(t, models.ErrorClassTimeout, result.ErrorClass)
+	})
+
+	t.Run("timeout while reading the body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		job := &models.QueueJob{ID: "job_1", JobID: 1, API: server.URL}
+		result := newTestWorkerService().callJobAPI(ctx, job, 0)
+
+		assert.False(t, result.Success)
+		assert.Equal(t, models.ErrorClassTimeout, result.ErrorClass)
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		assert.Equal(t, "partial", result.BodySnippet)
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		job := &models.QueueJob{ID: "job_1", JobID: 1, API: server.URL}
+		result := newTestWorkerService().callJobAPI(context.Background(), job, 0)
+
+		assert.False(t, result.Success)
+		assert.Equal(t, models.ErrorClassTLS, result.ErrorClass)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		job := &models.QueueJob{ID: "job_1", JobID: 1, API: "://bad-url"}
+		result := newTestWorkerService().callJobAPI(context.Background(), job, 0)
+
+		assert.False(t, result.Success)
+		assert.Equal(t, models.ErrorClassRequest, result.ErrorClass)
+	})
+}
+
+func TestWorkerService_CallJobAPI_SuccessCriteria(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		criteria      models.SuccessCriteria
+		expectSuccess bool
+		expectClass   models.ExecutionErrorClass
+	}{
+		{
+			name:          "409 accepted as already done",
+			status:        http.StatusConflict,
+			criteria:      models.SuccessCriteria{StatusCodes: []int{200, 409}},
+			expectSuccess: true,
+		},
+		{
+			name:          "2xx outside allowed set",
+			status:        http.StatusAccepted,
+			criteria:      models.SuccessCriteria{StatusCodes: []int{200}},
+			expectSuccess: false,
+			expectClass:   models.ErrorClassHTTPStatus,
+		},
+		{
+			name:          "200 with error payload",
+			status:        http.StatusOK,
+			body:          `{"status": "error"}`,
+			criteria:      models.SuccessCriteria{BodyJSONPath: "$.status", BodyJSONValue: "ok"},
+			expectSuccess: false,
+			expectClass:   models.ErrorClassBodyMismatch,
+		},
+		{
+			name:          "200 with ok payload",
+			status:        http.StatusOK,
+			body:          `{"result": {"items": [{"state": "done"}]}}`,
+			criteria:      models.SuccessCriteria{BodyJSONPath: "$.result.items[0].state", BodyJSONValue: "done"},
+			expectSuccess: true,
+		},
+		{
+			name:          "JSON path must exist",
+			status:        http.StatusOK,
+			body:          `{"result": {}}`,
+			criteria:      models.SuccessCriteria{BodyJSONPath: "$.result.id"},
+			expectSuccess: false,
+			expectClass:   models.ErrorClassBodyMismatch,
+		},
+		{
+			name:          "regex match",
+			status:        http.StatusOK,
+			body:          "OK: processed 3 records",
+			criteria:      models.SuccessCriteria{BodyRegex: `^OK: processed \d+`},
+			expectSuccess: true,
+		},
+		{
+			name:          "regex mismatch",
+			status:        http.StatusOK,
+			body:          "ERROR: upstream unavailable",
+			criteria:      models.SuccessCriteria{BodyRegex: `^OK`},
+			expectSuccess: false,
+			expectClass:   models.ErrorClassBodyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			job := &models.QueueJob{ID: "job_1", JobID: 1, API: server.URL, SuccessCriteria: tt.criteria}
+			result := newTestWorkerService().callJobAPI(context.Background(), job, 0)
+
+			assert.Equal(t, tt.expectSuccess, result.Success)
+			assert.Equal(t, tt.expectClass, result.ErrorClass)
+			assert.Equal(t, tt.status, result.StatusCode)
+		})
+	}
+}
+
+func TestWorkerService_JobTimeout(t *testing.T) {
+	ws := newTestWorkerService()
+
+	assert.Equal(t, 30*time.Second, ws.jobTimeout(&models.QueueJob{Timeout: 30}))
+
+	// Entries enqueued before per-job timeouts fall back to the worker default
+	assert.Equal(t, 5*time.Second, ws.jobTimeout(&models.QueueJob{}))
+}
+
+// failingQueue records the failures of jobs the way the queues do, without Redis
+type failingQueue struct {
+	WorkerQueueInterface
+	failed []string
+}
+
+func (q *failingQueue) FailJob(job *models.QueueJob, errorMsg string) error {
+	job.RecordFailure(errorMsg)
+	q.failed = append(q.failed, job.ID)
+	return nil
+}
+
+// completionRecorder records the completions reported to the scheduler
+type completionRecorder struct {
+	SchedulerServiceInterface
+	completed []uint
+}
+
+func (s *completionRecorder) HandleJobCompletion(jobID uint, success bool) error {
+	s.completed = append(s.completed, jobID)
+	return nil
+}
+
+func TestWorkerService_FailRunCompletesRunsNotRetried(t *testing.T) {
+	tests := []struct {
+		name          string
+		job           *models.QueueJob
+		wantCompleted bool
+	}{
+		{name: "retried", job: &models.QueueJob{ID: "job_1", JobID: 1, Type: models.AT_LEAST_ONCE, MaxRetryCount: 3}, wantCompleted: false},
+		{name: "retries exhausted", job: &models.QueueJob{ID: "job_2", JobID: 2, Type: models.AT_LEAST_ONCE, MaxRetryCount: 3, RetryCount: 3}, wantCompleted: true},
+		{name: "at most once", job: &models.QueueJob{ID: "job_3", JobID: 3, Type: models.AT_MOST_ONCE, MaxRetryCount: 3}, wantCompleted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue, scheduler := &failingQueue{}, &completionRecorder{}
+			ws := newTestWorkerService()
+			ws.jobQueue, ws.scheduler = queue, scheduler
+
+			ws.failRun(tt.job, "Failed to load job: connection refused")
+
+			assert.Equal(t, []string{tt.job.ID}, queue.failed)
+			if tt.wantCompleted {
+				assert.Equal(t, []uint{tt.job.JobID}, scheduler.completed)
+			} else {
+				assert.Empty(t, scheduler.completed)
+			}
+		})
+	}
+}
+
+func TestWorkerService_CancelRunningStopsReplacedExecution(t *testing.T) {
+	ws := newTestWorkerService()
+	ws.running = map[uint]context.CancelCauseFunc{}
+
+	runCtx, stop := context.WithCancelCause(context.Background())
+	ws.setRunning(7, stop)
+
+	// Executions this worker does not run are ignored
+	ws.cancelRunning(8)
+	assert.NoError(t, runCtx.Err())
+
+	ws.cancelRunning(7)
+	assert.ErrorIs(t, context.Cause(runCtx), errExecutionReplaced)
+
+	ws.setRunning(7, nil)
+	assert.Empty(t, ws.running)
+}
+
+func TestWorkerRegistry_CancelExecutionReachesOtherWorkers(t *testing.T) {
+	redisClient := newTestRedisClient(t)
+	registry := NewWorkerRegistry(redisClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancellations := registry.Cancellations(ctx)
+
+	require.NoError(t, registry.CancelExecution(ctx, 42))
+	select {
+	case executionID := <-cancellations:
+		assert.Equal(t, uint(42), executionID)
+	case <-time.After(time.Second):
+		t.Fatal("cancellation was not received")
+	}
+}
+
+func TestWorkerService_CallJobAPI_SignsRequests(t *testing.T) {
+	defaultSecret, tenantSecret := "default-secret-0123456789", "tenant-secret-0123456789"
+	var verifyErr error
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		verifier := &signature.Verifier{Secrets: [][]byte{[]byte(tenantSecret)}}
+		if r.Header.Get("X-Tenant") == "" {
+			verifier.Secrets = [][]byte{[]byte(defaultSecret)}
+		}
+		_, verifyErr = verifier.VerifyRequest(r)
+	}))
+	defer server.Close()
+
+	ws := newTestWorkerService()
+	ws.signer = NewRequestSigner([]string{defaultSecret}, map[string][]string{"acme": {tenantSecret}})
+
+	job := &models.QueueJob{ID: "job_5", JobID: 5, API: server.URL + "/hook", Request: models.HTTPRequestSpec{Body: `{"jobId": {{.JobID}}}`}}
+	require.True(t, ws.callJobAPI(context.Background(), job, 31).Success)
+	require.NoError(t, verifyErr)
+	assert.Equal(t, "5", header.Get(signature.HeaderJobID))
+	assert.Equal(t, "31", header.Get(signature.HeaderExecutionID))
+
+	// Jobs with a signing key are signed with its secrets
+	job.SigningKey = "acme"
+	job.Request.Headers = map[string]string{"X-Tenant": "acme"}
+	require.True(t, ws.callJobAPI(context.Background(), job, 32).Success)
+	require.NoError(t, verifyErr)
+
+	// A job whose key the worker does not have is not sent unsigned
+	job.SigningKey = "globex"
+	result := ws.callJobAPI(context.Background(), job, 33)
+	assert.False(t, result.Success)
+	assert.Equal(t, models.ErrorClassRequest, result.ErrorClass)
+}
@@ -14,6 +14,7 @@ import (
 	time "time"
 
 	models "github.com/manyu/job-scheduler/internal/models"
+	storage "github.com/manyu/job-scheduler/internal/storage"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -41,6 +42,66 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// CancelJobExecution mocks base method.
+func (m *MockStorage) CancelJobExecution(id uint, reason string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelJobExecution", id, reason)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelJobExecution indicates an expected call of CancelJobExecution.
+func (mr *MockStorageMockRecorder) CancelJobExecution(id, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelJobExecution", reflect.TypeOf((*MockStorage)(nil).CancelJobExecution), id, reason)
+}
+
+// ClaimJobsReadyForExecution mocks base method.
+func (m *MockStorage) ClaimJobsReadyForExecution(owner string, term int64, lease, dispatchExpiry time.Duration, limit int, shards *storage.ShardFilter) ([]*models.Job, []*models.JobSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimJobsReadyForExecution", owner, term, lease, dispatchExpiry, limit, shards)
+	ret0, _ := ret[0].([]*models.Job)
+	ret1, _ := ret[1].([]*models.JobSchedule)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ClaimJobsReadyForExecution indicates an expected call of ClaimJobsReadyForExecution.
+func (mr *MockStorageMockRecorder) ClaimJobsReadyForExecution(owner, term, lease, dispatchExpiry, limit, shards any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimJobsReadyForExecution", reflect.TypeOf((*MockStorage)(nil).ClaimJobsReadyForExecution), owner, term, lease, dispatchExpiry, limit, shards)
+}
+
+// CountJobsUsingCredential mocks base method.
+func (m *MockStorage) CountJobsUsingCredential(name string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountJobsUsingCredential", name)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountJobsUsingCredential indicates an expected call of CountJobsUsingCredential.
+func (mr *MockStorageMockRecorder) CountJobsUsingCredential(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountJobsUsingCredential", reflect.TypeOf((*MockStorage)(nil).CountJobsUsingCredential), name)
+}
+
+// CreateCredential mocks base method.
+func (m *MockStorage) CreateCredential(credential *models.Credential) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCredential", credential)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateCredential indicates an expected call of CreateCredential.
+func (mr *MockStorageMockRecorder) CreateCredential(credential any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCredential", reflect.TypeOf((*MockStorage)(nil).CreateCredential), credential)
+}
+
 // CreateJob mocks base method.
 func (m *MockStorage) CreateJob(job *models.Job) error {
 	m.ctrl.T.Helper()
@@ -97,6 +158,34 @@ func (mr *MockStorageMockRecorder) CreateJobWithSchedule(job, schedule any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJobWithSchedule", reflect.TypeOf((*MockStorage)(nil).CreateJobWithSchedule), job, schedule)
 }
 
+// DeleteCredential mocks base method.
+func (m *MockStorage) DeleteCredential(id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCredential", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCredential indicates an expected call of DeleteCredential.
+func (mr *MockStorageMockRecorder) DeleteCredential(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCredential", reflect.TypeOf((*MockStorage)(nil).DeleteCredential), id)
+}
+
+// DeleteJob mocks base method.
+func (m *MockStorage) DeleteJob(id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteJob", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteJob indicates an expected call of DeleteJob.
+func (mr *MockStorageMockRecorder) DeleteJob(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteJob", reflect.TypeOf((*MockStorage)(nil).DeleteJob), id)
+}
+
 // DeleteJobSchedule mocks base method.
 func (m *MockStorage) DeleteJobSchedule(jobID uint) error {
 	m.ctrl.T.Helper()
@@ -111,6 +200,21 @@ func (mr *MockStorageMockRecorder) DeleteJobSchedule(jobID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteJobSchedule", reflect.TypeOf((*MockStorage)(nil).DeleteJobSchedule), jobID)
 }
 
+// ExpireJobExecutions mocks base method.
+func (m *MockStorage) ExpireJobExecutions(jobID uint, startedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireJobExecutions", jobID, startedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireJobExecutions indicates an expected call of ExpireJobExecutions.
+func (mr *MockStorageMockRecorder) ExpireJobExecutions(jobID, startedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireJobExecutions", reflect.TypeOf((*MockStorage)(nil).ExpireJobExecutions), jobID, startedBefore)
+}
+
 // GetAllJobs mocks base method.
 func (m *MockStorage) GetAllJobs() ([]*models.Job, error) {
 	m.ctrl.T.Helper()
@@ -126,6 +230,36 @@ func (mr *MockStorageMockRecorder) GetAllJobs() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllJobs", reflect.TypeOf((*MockStorage)(nil).GetAllJobs))
 }
 
+// GetCredential mocks base method.
+func (m *MockStorage) GetCredential(id uint) (*models.Credential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredential", id)
+	ret0, _ := ret[0].(*models.Credential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCredential indicates an expected call of GetCredential.
+func (mr *MockStorageMockRecorder) GetCredential(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredential", reflect.TypeOf((*MockStorage)(nil).GetCredential), id)
+}
+
+// GetCredentialByName mocks base method.
+func (m *MockStorage) GetCredentialByName(name string) (*models.Credential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredentialByName", name)
+	ret0, _ := ret[0].(*models.Credential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCredentialByName indicates an expected call of GetCredentialByName.
+func (mr *MockStorageMockRecorder) GetCredentialByName(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredentialByName", reflect.TypeOf((*MockStorage)(nil).GetCredentialByName), name)
+}
+
 // GetJob mocks base method.
 func (m *MockStorage) GetJob(id uint) (*models.Job, error) {
 	m.ctrl.T.Helper()
@@ -141,6 +275,36 @@ func (mr *MockStorageMockRecorder) GetJob(id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJob", reflect.TypeOf((*MockStorage)(nil).GetJob), id)
 }
 
+// GetJobByIdempotencyKey mocks base method.
+func (m *MockStorage) GetJobByIdempotencyKey(key string) (*models.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJobByIdempotencyKey", key)
+	ret0, _ := ret[0].(*models.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJobByIdempotencyKey indicates an expected call of GetJobByIdempotencyKey.
+func (mr *MockStorageMockRecorder) GetJobByIdempotencyKey(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobByIdempotencyKey", reflect.TypeOf((*MockStorage)(nil).GetJobByIdempotencyKey), key)
+}
+
+// GetJobExecutionInProgress mocks base method.
+func (m *MockStorage) GetJobExecutionInProgress(jobID uint) (*models.JobExecution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJobExecutionInProgress", jobID)
+	ret0, _ := ret[0].(*models.JobExecution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJobExecutionInProgress indicates an expected call of GetJobExecutionInProgress.
+func (mr *MockStorageMockRecorder) GetJobExecutionInProgress(jobID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobExecutionInProgress", reflect.TypeOf((*MockStorage)(nil).GetJobExecutionInProgress), jobID)
+}
+
 // GetJobExecutions mocks base method.
 func (m *MockStorage) GetJobExecutions(jobID uint, limit int) ([]*models.JobExecution, error) {
 	m.ctrl.T.Helper()
@@ -156,6 +320,21 @@ func (mr *MockStorageMockRecorder) GetJobExecutions(jobID, limit any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobExecutions", reflect.TypeOf((*MockStorage)(nil).GetJobExecutions), jobID, limit)
 }
 
+// GetJobExecutionsInProgress mocks base method.
+func (m *MockStorage) GetJobExecutionsInProgress(jobID uint) ([]*models.JobExecution, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJobExecutionsInProgress", jobID)
+	ret0, _ := ret[0].([]*models.JobExecution)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJobExecutionsInProgress indicates an expected call of GetJobExecutionsInProgress.
+func (mr *MockStorageMockRecorder) GetJobExecutionsInProgress(jobID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobExecutionsInProgress", reflect.TypeOf((*MockStorage)(nil).GetJobExecutionsInProgress), jobID)
+}
+
 // GetJobSchedule mocks base method.
 func (m *MockStorage) GetJobSchedule(jobID uint) (*models.JobSchedule, error) {
 	m.ctrl.T.Helper()
@@ -171,20 +350,90 @@ func (mr *MockStorageMockRecorder) GetJobSchedule(jobID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobSchedule", reflect.TypeOf((*MockStorage)(nil).GetJobSchedule), jobID)
 }
 
-// GetJobsReadyForExecution mocks base method.
-func (m *MockStorage) GetJobsReadyForExecution(limit int) ([]*models.Job, []*models.JobSchedule, error) {
+// ListCredentials mocks base method.
+func (m *MockStorage) ListCredentials() ([]*models.Credential, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetJobsReadyForExecution", limit)
-	ret0, _ := ret[0].([]*models.Job)
-	ret1, _ := ret[1].([]*models.JobSchedule)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "ListCredentials")
+	ret0, _ := ret[0].([]*models.Credential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCredentials indicates an expected call of ListCredentials.
+func (mr *MockStorageMockRecorder) ListCredentials() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCredentials", reflect.TypeOf((*MockStorage)(nil).ListCredentials))
+}
+
+// MarkScheduleDispatched mocks base method.
+func (m *MockStorage) MarkScheduleDispatched(jobID uint, owner string, term int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkScheduleDispatched", jobID, owner, term)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkScheduleDispatched indicates an expected call of MarkScheduleDispatched.
+func (mr *MockStorageMockRecorder) MarkScheduleDispatched(jobID, owner, term any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkScheduleDispatched", reflect.TypeOf((*MockStorage)(nil).MarkScheduleDispatched), jobID, owner, term)
+}
+
+// MaxScheduleLeaderTerm mocks base method.
+func (m *MockStorage) MaxScheduleLeaderTerm() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxScheduleLeaderTerm")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// GetJobsReadyForExecution indicates an expected call of GetJobsReadyForExecution.
-func (mr *MockStorageMockRecorder) GetJobsReadyForExecution(limit any) *gomock.Call {
+// MaxScheduleLeaderTerm indicates an expected call of MaxScheduleLeaderTerm.
+func (mr *MockStorageMockRecorder) MaxScheduleLeaderTerm() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobsReadyForExecution", reflect.TypeOf((*MockStorage)(nil).GetJobsReadyForExecution), limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxScheduleLeaderTerm", reflect.TypeOf((*MockStorage)(nil).MaxScheduleLeaderTerm))
+}
+
+// ReleaseScheduleClaim mocks base method.
+func (m *MockStorage) ReleaseScheduleClaim(jobID uint, owner string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseScheduleClaim", jobID, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseScheduleClaim indicates an expected call of ReleaseScheduleClaim.
+func (mr *MockStorageMockRecorder) ReleaseScheduleClaim(jobID, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseScheduleClaim", reflect.TypeOf((*MockStorage)(nil).ReleaseScheduleClaim), jobID, owner)
+}
+
+// UpdateCredential mocks base method.
+func (m *MockStorage) UpdateCredential(credential *models.Credential) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCredential", credential)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCredential indicates an expected call of UpdateCredential.
+func (mr *MockStorageMockRecorder) UpdateCredential(credential any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCredential", reflect.TypeOf((*MockStorage)(nil).UpdateCredential), credential)
+}
+
+// UpdateJob mocks base method.
+func (m *MockStorage) UpdateJob(job *models.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateJob", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateJob indicates an expected call of UpdateJob.
+func (mr *MockStorageMockRecorder) UpdateJob(job any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJob", reflect.TypeOf((*MockStorage)(nil).UpdateJob), job)
 }
 
 // UpdateJobExecution mocks base method.
@@ -215,17 +464,16 @@ func (mr *MockStorageMockRecorder) UpdateJobSchedule(jobID, nextExecutionTime an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJobSchedule", reflect.TypeOf((*MockStorage)(nil).UpdateJobSchedule), jobID, nextExecutionTime)
 }
 
-// GetJobExecutionInProgress mocks base method.
-func (m *MockStorage) GetJobExecutionInProgress(jobID uint) (*models.JobExecution, error) {
+// UpdateJobWithSchedule mocks base method.
+func (m *MockStorage) UpdateJobWithSchedule(job *models.Job, schedule *models.JobSchedule) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetJobExecutionInProgress", jobID)
-	ret0, _ := ret[0].(*models.JobExecution)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "UpdateJobWithSchedule", job, schedule)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetJobExecutionInProgress indicates an expected call of GetJobExecutionInProgress.
-func (mr *MockStorageMockRecorder) GetJobExecutionInProgress(jobID any) *gomock.Call {
+// UpdateJobWithSchedule indicates an expected call of UpdateJobWithSchedule.
+func (mr *MockStorageMockRecorder) UpdateJobWithSchedule(job, schedule any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobExecutionInProgress", reflect.TypeOf((*MockStorage)(nil).GetJobExecutionInProgress), jobID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJobWithSchedule", reflect.TypeOf((*MockStorage)(nil).UpdateJobWithSchedule), job, schedule)
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/manyu/job-scheduler/internal/database"
 	"github.com/manyu/job-scheduler/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostgresStorage struct {
@@ -34,6 +35,9 @@ func (s *PostgresStorage) CreateJobWithSchedule(job *models.Job, schedule *model
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// Create job
 		if err := tx.Create(job).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) && job.IdempotencyKey != nil {
+				return ErrDuplicateIdempotencyKey
+			}
 			return fmt.Errorf("failed to create job: %w", err)
 		}
 
@@ -49,9 +53,10 @@ func (s *PostgresStorage) CreateJobWithSchedule(job *models.Job, schedule *model
 	})
 }
 
+// GetJob returns a job that has not been deleted, whether it is active or paused
 func (s *PostgresStorage) GetJob(id uint) (*models.Job, error) {
 	var job models.Job
-	result := s.db.Where("is_active = ?", true).First(&job, id)
+	result := s.db.First(&job, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, ErrJobNotFound
@@ -61,15 +66,95 @@ func (s *PostgresStorage) GetJob(id uint) (*models.Job, error) {
 	return &job, nil
 }
 
+// GetJobByIdempotencyKey returns the job created with an idempotency key, even if it was deleted since
+func (s *PostgresStorage) GetJobByIdempotencyKey(key string) (*models.Job, error) {
+	var job models.Job
+	result := s.db.Unscoped().Where("idempotency_key = ?", key).First(&job)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, result.Error
+	}
+	return &job, nil
+}
+
+// GetAllJobs returns all jobs that have not been deleted, including paused ones
 func (s *PostgresStorage) GetAllJobs() ([]*models.Job, error) {
 	var jobs []*models.Job
-	result := s.db.Where("is_active = ?", true).Find(&jobs)
+	result := s.db.Order("id ASC").Find(&jobs)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return jobs, nil
 }
 
+// UpdateJob saves all fields of an existing job
+func (s *PostgresStorage) UpdateJob(job *models.Job) error {
+	result := s.db.Save(job)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// UpdateJobWithSchedule saves a job and replaces its next execution time in a transaction.
+// A schedule that was removed after a one-off execution is restored.
+func (s *PostgresStorage) UpdateJobWithSchedule(job *models.Job, schedule *models.JobSchedule) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(job).Error; err != nil {
+			return fmt.Errorf("failed to update job: %w", err)
+		}
+
+		schedule.JobID = job.ID
+
+		// job_id is unique, so a soft-deleted schedule row has to be revived rather than recreated
+		result := tx.Unscoped().Model(&models.JobSchedule{}).
+			Where("job_id = ?", job.ID).
+			Updates(map[string]interface{}{
+				"next_execution_time": schedule.NextExecutionTime,
+				"dispatched_at":       nil,
+				"lease_owner":         "",
+				"lease_expires_at":    nil,
+				"deleted_at":          nil,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update job schedule: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			if err := tx.Create(schedule).Error; err != nil {
+				return fmt.Errorf("failed to create job schedule: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteJob deactivates and soft-deletes a job together with its schedule
+func (s *PostgresStorage) DeleteJob(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Job{}).Where("id = ?", id).Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("failed to deactivate job: %w", err)
+		}
+
+		result := tx.Delete(&models.Job{}, id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete job: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrJobNotFound
+		}
+
+		if err := tx.Where("job_id = ?", id).Delete(&models.JobSchedule{}).Error; err != nil {
+			return fmt.Errorf("failed to delete job schedule: %w", err)
+		}
+
+		return nil
+	})
+}
+
 // JobSchedule operations
 func (s *PostgresStorage) CreateJobSchedule(schedule *models.JobSchedule) error {
 	result := s.db.Create(schedule)
@@ -91,10 +176,15 @@ func (s *PostgresStorage) GetJobSchedule(jobID uint) (*models.JobSchedule, error
 	return &schedule, nil
 }
 
+// UpdateJobSchedule moves a schedule to its next occurrence, which has not been dispatched yet.
+// A claim on the schedule is kept until it is released, dispatched or expires.
 func (s *PostgresStorage) UpdateJobSchedule(jobID uint, nextExecutionTime time.Time) error {
 	result := s.db.Model(&models.JobSchedule{}).
 		Where("job_id = ?", jobID).
-		Update("next_execution_time", nextExecutionTime)
+		Updates(map[string]interface{}{
+			"next_execution_time": nextExecutionTime,
+			"dispatched_at":       nil,
+		})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -115,41 +205,145 @@ func (s *PostgresStorage) DeleteJobSchedule(jobID uint) error {
 	return nil
 }
 
-func (s *PostgresStorage) GetJobsReadyForExecution(limit int) ([]*models.Job, []*models.JobSchedule, error) {
-	var results []struct {
-		models.Job
-		models.JobSchedule
+// ClaimJobsReadyForExecution leases due, undispatched schedules of active jobs to owner until the lease
+// expires. Rows that another scheduler has locked or holds a live lease on are skipped, so concurrent
+// schedulers never claim the same occurrence. Schedules dispatched longer than dispatchExpiry ago count
+// as undispatched, so a run whose completion was lost does not stall its job. A non-nil shards limits
+// the scan to those shards. A non-zero term is the leader term of owner: schedules claimed at a higher
+// term are skipped, so a leader that stalled past its lease cannot claim what its successor claimed.
+func (s *PostgresStorage) ClaimJobsReadyForExecution(owner string, term int64, lease, dispatchExpiry time.Duration, limit int, shards *ShardFilter) ([]*models.Job, []*models.JobSchedule, error) {
+	if shards != nil && len(shards.Shards) == 0 {
+		return []*models.Job{}, []*models.JobSchedule{}, nil
 	}
 
-	// Use JOIN to get only schedules for active jobs that are ready for execution
-	result := s.db.Table("job_schedules").
-		Select("jobs.*, job_schedules.*").
-		Joins("JOIN jobs ON job_schedules.job_id = jobs.id").
-		Where("job_schedules.next_execution_time <= ? AND jobs.is_active = ? AND job_schedules.deleted_at IS NULL", time.Now(), true).
-		Order("job_schedules.next_execution_time ASC").
-		Limit(limit).
-		Scan(&results)
+	var schedules []*models.JobSchedule
+
+	now := time.Now()
+	expiresAt := now.Add(lease)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Use JOIN to get only schedules for active, non-deleted jobs that are ready for execution
+		query := tx.Select("job_schedules.*").
+			Joins("JOIN jobs ON job_schedules.job_id = jobs.id").
+			Where("job_schedules.next_execution_time <= ? AND jobs.is_active = ? AND jobs.deleted_at IS NULL", now, true).
+			Where("(job_schedules.dispatched_at IS NULL OR job_schedules.dispatched_at < ?)", now.Add(-dispatchExpiry)).
+			Where("(job_schedules.lease_expires_at IS NULL OR job_schedules.lease_expires_at < ?)", now)
+		if shards != nil {
+			query = query.Where("job_schedules.job_id % ? IN ?", shards.Count, shards.Shards)
+		}
+		if term > 0 {
+			query = query.Where("job_schedules.leader_term <= ?", term)
+		}
 
-	if result.Error != nil {
-		return nil, nil, result.Error
+		result := query.
+			Order("job_schedules.next_execution_time ASC").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "job_schedules"}, Options: "SKIP LOCKED"}).
+			Find(&schedules)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if len(schedules) == 0 {
+			return nil
+		}
+
+		scheduleIDs := make([]uint, 0, len(schedules))
+		for _, schedule := range schedules {
+			scheduleIDs = append(scheduleIDs, schedule.ID)
+		}
+
+		claim := map[string]interface{}{
+			"lease_owner":      owner,
+			"lease_expires_at": expiresAt,
+		}
+		if term > 0 {
+			claim["leader_term"] = term
+		}
+		return tx.Model(&models.JobSchedule{}).
+			Where("id IN ?", scheduleIDs).
+			Updates(claim).Error
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if len(results) == 0 {
+	if len(schedules) == 0 {
 		return []*models.Job{}, []*models.JobSchedule{}, nil
 	}
 
-	// Separate jobs and schedules
+	jobIDs := make([]uint, 0, len(schedules))
+	for _, schedule := range schedules {
+		schedule.LeaseOwner = owner
+		schedule.LeaseExpiresAt = &expiresAt
+		if term > 0 {
+			schedule.LeaderTerm = term
+		}
+		jobIDs = append(jobIDs, schedule.JobID)
+	}
+
 	var jobs []*models.Job
-	var schedules []*models.JobSchedule
+	if err := s.db.Where("id IN ?", jobIDs).Find(&jobs).Error; err != nil {
+		return nil, nil, err
+	}
 
-	for _, result := range results {
-		job := result.Job
-		schedule := result.JobSchedule
-		jobs = append(jobs, &job)
-		schedules = append(schedules, &schedule)
+	jobsByID := make(map[uint]*models.Job, len(jobs))
+	for _, job := range jobs {
+		jobsByID[job.ID] = job
 	}
 
-	return jobs, schedules, nil
+	// Pair jobs with their schedules, keeping the schedule ordering
+	readyJobs := make([]*models.Job, 0, len(schedules))
+	readySchedules := make([]*models.JobSchedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		job, ok := jobsByID[schedule.JobID]
+		if !ok {
+			continue
+		}
+		readyJobs = append(readyJobs, job)
+		readySchedules = append(readySchedules, schedule)
+	}
+
+	return readyJobs, readySchedules, nil
+}
+
+// MarkScheduleDispatched records that owner enqueued the occurrence it claimed, so it is not claimed
+// again until the schedule moves on. ErrScheduleClaimLost is returned if owner no longer holds the claim,
+// or, for a non-zero term, if a later leader claimed the schedule.
+func (s *PostgresStorage) MarkScheduleDispatched(jobID uint, owner string, term int64) error {
+	query := s.db.Model(&models.JobSchedule{}).Where("job_id = ? AND lease_owner = ?", jobID, owner)
+	if term > 0 {
+		query = query.Where("leader_term <= ?", term)
+	}
+	result := query.Updates(map[string]interface{}{
+		"dispatched_at":    time.Now(),
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduleClaimLost
+	}
+	return nil
+}
+
+// ReleaseScheduleClaim gives up owner's claim without dispatching, so the schedule can be claimed again
+func (s *PostgresStorage) ReleaseScheduleClaim(jobID uint, owner string) error {
+	return s.db.Model(&models.JobSchedule{}).
+		Where("job_id = ? AND lease_owner = ?", jobID, owner).
+		Updates(map[string]interface{}{
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		}).Error
+}
+
+// MaxScheduleLeaderTerm returns the highest leader term a schedule was claimed at, which every new
+// leader term must exceed
+func (s *PostgresStorage) MaxScheduleLeaderTerm() (int64, error) {
+	var term int64
+	err := s.db.Model(&models.JobSchedule{}).Select("COALESCE(MAX(leader_term), 0)").Scan(&term).Error
+	return term, err
 }
 
 // JobExecution operations
@@ -199,8 +393,129 @@ func (s *PostgresStorage) GetJobExecutionInProgress(jobID uint) (*models.JobExec
 	return &execution, nil
 }
 
+// GetJobExecutionsInProgress returns every scheduled or running execution of a job, oldest first
+func (s *PostgresStorage) GetJobExecutionsInProgress(jobID uint) ([]*models.JobExecution, error) {
+	var executions []*models.JobExecution
+	result := s.db.Where("job_id = ? AND status IN (?)", jobID, inProgressStatuses).
+		Order("created_at ASC").
+		Find(&executions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return executions, nil
+}
+
+// ExpireJobExecutions marks the executions of a job still in progress that started before startedBefore
+// as expired, and returns how many it expired
+func (s *PostgresStorage) ExpireJobExecutions(jobID uint, startedBefore time.Time) (int64, error) {
+	result := s.db.Model(&models.JobExecution{}).
+		Where("job_id = ? AND status IN (?) AND execution_time < ?", jobID, inProgressStatuses, startedBefore).
+		Updates(map[string]interface{}{
+			"status": models.StatusExpired,
+			"error":  "execution did not finish before its deadline",
+		})
+	return result.RowsAffected, result.Error
+}
+
+// CancelJobExecution marks an execution as cancelled with reason if it is still in progress, and
+// reports whether it was
+func (s *PostgresStorage) CancelJobExecution(id uint, reason string) (bool, error) {
+	result := s.db.Model(&models.JobExecution{}).
+		Where("id = ? AND status IN (?)", id, inProgressStatuses).
+		Updates(map[string]interface{}{
+			"status": models.StatusCancelled,
+			"error":  reason,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateCredential stores a credential, whose secret must already be encrypted
+func (s *PostgresStorage) CreateCredential(credential *models.Credential) error {
+	if err := s.db.Create(credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrDuplicateCredentialName
+		}
+		return fmt.Errorf("failed to create credential: %w", err)
+	}
+	return nil
+}
+
+// GetCredential returns a credential by ID
+func (s *PostgresStorage) GetCredential(id uint) (*models.Credential, error) {
+	var credential models.Credential
+	if err := s.db.First(&credential, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// GetCredentialByName returns the credential jobs reference by name
+func (s *PostgresStorage) GetCredentialByName(name string) (*models.Credential, error) {
+	var credential models.Credential
+	if err := s.db.Where("name = ?", name).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// ListCredentials returns all credentials ordered by name
+func (s *PostgresStorage) ListCredentials() ([]*models.Credential, error) {
+	var credentials []*models.Credential
+	if err := s.db.Order("name").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+// UpdateCredential saves a credential, whose secret must already be encrypted
+func (s *PostgresStorage) UpdateCredential(credential *models.Credential) error {
+	if err := s.db.Save(credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrDuplicateCredentialName
+		}
+		return fmt.Errorf("failed to update credential: %w", err)
+	}
+	return nil
+}
+
+// DeleteCredential removes a credential for good, so its encrypted secret is not kept
+func (s *PostgresStorage) DeleteCredential(id uint) error {
+	result := s.db.Delete(&models.Credential{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete credential: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// CountJobsUsingCredential counts the jobs that have not been deleted and reference a credential
+func (s *PostgresStorage) CountJobsUsingCredential(name string) (int64, error) {
+	var count int64
+	if err := s.db.Model(&models.Job{}).Where("credential = ?", name).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count jobs using credential: %w", err)
+	}
+	return count, nil
+}
+
+// inProgressStatuses are the statuses of executions that have not finished
+var inProgressStatuses = []models.ExecutionStatus{models.StatusScheduled, models.StatusRunning}
+
 // Error definitions
 var (
 	ErrJobNotFound         = errors.New("job not found")
 	ErrJobScheduleNotFound = errors.New("job schedule not found")
+	ErrScheduleClaimLost   = errors.New("job schedule claim lost")
+
+	ErrDuplicateIdempotencyKey = errors.New("a job was already created with this idempotency key")
+
+	ErrCredentialNotFound      = errors.New("credential not found")
+	ErrDuplicateCredentialName = errors.New("a credential with this name already exists")
 )
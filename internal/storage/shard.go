@@ -0,0 +1,22 @@
+package storage
+
+// ShardFilter restricts a scan to the jobs in some shards. Jobs are split into Count shards by job ID,
+// and a job belongs to shard jobID % Count.
+type ShardFilter struct {
+	Count  int
+	Shards []int
+}
+
+// Contains reports whether the job is in one of the filter's shards. A nil filter contains every job.
+func (f *ShardFilter) Contains(jobID uint) bool {
+	if f == nil {
+		return true
+	}
+	shard := int(jobID % uint(f.Count))
+	for _, s := range f.Shards {
+		if s == shard {
+			return true
+		}
+	}
+	return false
+}
@@ -12,18 +12,37 @@ type Storage interface {
 	CreateJob(job *models.Job) error
 	CreateJobWithSchedule(job *models.Job, schedule *models.JobSchedule) error
 	GetJob(id uint) (*models.Job, error)
+	GetJobByIdempotencyKey(key string) (*models.Job, error)
 	GetAllJobs() ([]*models.Job, error)
+	UpdateJob(job *models.Job) error
+	UpdateJobWithSchedule(job *models.Job, schedule *models.JobSchedule) error
+	DeleteJob(id uint) error
 
 	// Job schedule operations
 	CreateJobSchedule(schedule *models.JobSchedule) error
 	GetJobSchedule(jobID uint) (*models.JobSchedule, error)
 	UpdateJobSchedule(jobID uint, nextExecutionTime time.Time) error
 	DeleteJobSchedule(jobID uint) error
-	GetJobsReadyForExecution(limit int) ([]*models.Job, []*models.JobSchedule, error)
+	ClaimJobsReadyForExecution(owner string, term int64, lease, dispatchExpiry time.Duration, limit int, shards *ShardFilter) ([]*models.Job, []*models.JobSchedule, error)
+	MarkScheduleDispatched(jobID uint, owner string, term int64) error
+	ReleaseScheduleClaim(jobID uint, owner string) error
+	MaxScheduleLeaderTerm() (int64, error)
 
 	// Job execution operations
 	CreateJobExecution(execution *models.JobExecution) error
 	UpdateJobExecution(execution *models.JobExecution) error
 	GetJobExecutions(jobID uint, limit int) ([]*models.JobExecution, error)
 	GetJobExecutionInProgress(jobID uint) (*models.JobExecution, error)
+	GetJobExecutionsInProgress(jobID uint) ([]*models.JobExecution, error)
+	ExpireJobExecutions(jobID uint, startedBefore time.Time) (int64, error)
+	CancelJobExecution(id uint, reason string) (bool, error)
+
+	// Credential operations
+	CreateCredential(credential *models.Credential) error
+	GetCredential(id uint) (*models.Credential, error)
+	GetCredentialByName(name string) (*models.Credential, error)
+	ListCredentials() ([]*models.Credential, error)
+	UpdateCredential(credential *models.Credential) error
+	DeleteCredential(id uint) error
+	CountJobsUsingCredential(name string) (int64, error)
 }
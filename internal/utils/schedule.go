@@ -2,11 +2,19 @@ package utils
 
 import (
 	"fmt"
+	"strings"
 	"time"
+	_ "time/tzdata" // Embed the zone database so IANA names resolve on images without one
 
 	"github.com/robfig/cron/v3"
 )
 
+// intervalPrefix introduces a fixed-interval schedule such as "@every 90s"
+const intervalPrefix = "@every "
+
+// allHours is the cron hour bitmask that matches every hour of the day
+const allHours = 1<<24 - 1
+
 // ScheduleParser handles CRON schedule parsing and next execution time calculation
 type ScheduleParser struct {
 	cronParser cron.Parser
@@ -39,8 +47,25 @@ func (sp *ScheduleParser) CalculateNextExecution(schedule string, fromTime time.
 
 // ValidateSchedule validates if a schedule string is valid
 func (sp *ScheduleParser) ValidateSchedule(schedule string) error {
-	_, err := sp.ParseSchedule(schedule)
-	return err
+	if _, err := sp.ParseSchedule(schedule); err != nil {
+		return err
+	}
+	if interval, ok := sp.ParseInterval(schedule); ok && interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+	return nil
+}
+
+// ParseInterval returns the interval of an "@every <duration>" schedule; ok is false for cron expressions
+func (sp *ScheduleParser) ParseInterval(schedule string) (time.Duration, bool) {
+	if !strings.HasPrefix(schedule, intervalPrefix) {
+		return 0, false
+	}
+	interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(schedule, intervalPrefix)))
+	if err != nil {
+		return 0, false
+	}
+	return interval, true
 }
 
 // IsValidSchedule checks if a schedule string is valid without returning the error
@@ -69,3 +94,134 @@ func (sp *ScheduleParser) CalculateNextExecutionFromNow(schedule string) (time.T
 func (sp *ScheduleParser) CalculateNextExecutionFromTime(schedule string, fromTime time.Time) (time.Time, error) {
 	return sp.CalculateNextExecution(schedule, fromTime)
 }
+
+// ValidateTimezone checks that a time zone name exists in the IANA tz database
+func (sp *ScheduleParser) ValidateTimezone(timezone string) error {
+	_, err := loadTimezone(timezone)
+	return err
+}
+
+// CalculateNextExecutionInWindow calculates the next execution time after fromTime for a job that may not
+// run before startAt. Interval schedules with a startAt run on the grid startAt, startAt+interval, ...;
+// without one they run an interval after fromTime. Cron schedules may fire at startAt itself.
+func (sp *ScheduleParser) CalculateNextExecutionInWindow(schedule, timezone string, fromTime time.Time, startAt *time.Time) (time.Time, error) {
+	if interval, ok := sp.ParseInterval(schedule); ok {
+		if interval < time.Second {
+			return time.Time{}, fmt.Errorf("interval must be at least 1s")
+		}
+		if startAt == nil {
+			return fromTime.Add(interval).UTC(), nil
+		}
+		if fromTime.Before(*startAt) {
+			return startAt.UTC(), nil
+		}
+		elapsed := fromTime.Sub(*startAt)
+		return startAt.Add((elapsed/interval + 1) * interval).UTC(), nil
+	}
+
+	if startAt != nil && fromTime.Before(*startAt) {
+		fromTime = startAt.Add(-time.Nanosecond)
+	}
+	return sp.CalculateNextExecutionInZone(schedule, timezone, fromTime)
+}
+
+// CalculateNextExecutionInZone calculates the next execution time after fromTime, evaluating the
+// schedule in the wall-clock time of the given zone. An empty zone means UTC. The result is in UTC.
+//
+// Schedules whose hour field matches every hour follow elapsed time across DST changes: they run
+// in both passes of a repeated hour, and occurrences inside a skipped hour simply do not exist.
+// Schedules pinned to particular hours follow the wall clock instead:
+//   - an occurrence inside a skipped hour runs once, shifted forward by the length of the gap
+//     (02:30 on a day that jumps from 02:00 to 03:00 runs at 03:30)
+//   - an occurrence inside a repeated hour runs once, in the first pass
+func (sp *ScheduleParser) CalculateNextExecutionInZone(schedule, timezone string, fromTime time.Time) (time.Time, error) {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	cronSchedule, err := sp.ParseSchedule(schedule)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid schedule format: %w", err)
+	}
+
+	spec, ok := cronSchedule.(*cron.SpecSchedule)
+	if !ok || spec.Hour&allHours == allHours {
+		return cronSchedule.Next(fromTime.In(loc)).UTC(), nil
+	}
+
+	// A TZ= prefix in the expression takes precedence over the job's zone
+	if spec.Location != time.Local {
+		loc = spec.Location
+	}
+
+	return nextWallClockExecution(spec, fromTime, loc).UTC(), nil
+}
+
+// nextWallClockExecution walks the schedule in wall-clock time, treating the zone's wall clock as
+// if it had no DST changes, and maps each candidate back onto a real instant in loc
+func nextWallClockExecution(spec *cron.SpecSchedule, fromTime time.Time, loc *time.Location) time.Time {
+	wallSpec := *spec
+	wallSpec.Location = time.UTC
+
+	wall := wallClock(fromTime.In(loc))
+	for {
+		wall = wallSpec.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+
+		// Mapping back can land at or before fromTime when fromTime was in the second pass of a
+		// repeated hour, so keep walking until the instant is in the future
+		if next := wallClockToInstant(wall, loc); next.After(fromTime) {
+			return next
+		}
+	}
+}
+
+// wallClockToInstant resolves a wall-clock time (carried in a UTC time.Time) in loc. Repeated wall
+// times resolve to their first occurrence; skipped ones are shifted forward by the length of the gap.
+func wallClockToInstant(wall time.Time, loc *time.Location) time.Time {
+	// DST changes are far enough apart that the offsets a day either side cover both sides of one
+	_, offsetBefore := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, offsetAfter := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	var resolved, latest time.Time
+	for _, offset := range []int{offsetBefore, offsetAfter} {
+		candidate := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if latest.IsZero() || candidate.After(latest) {
+			latest = candidate
+		}
+
+		if wallClock(candidate).Equal(wall) && (resolved.IsZero() || candidate.Before(resolved)) {
+			resolved = candidate
+		}
+	}
+
+	if resolved.IsZero() {
+		// The wall time falls in a gap; reading it with the pre-gap offset moves it forward by the gap
+		return latest
+	}
+	return resolved
+}
+
+// wallClock returns the wall-clock reading of t carried in a UTC time.Time
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// loadTimezone resolves an IANA time zone name, rejecting the server-dependent "Local" zone
+func loadTimezone(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.UTC, nil
+	}
+	if timezone == "Local" {
+		return nil, fmt.Errorf("time zone must be an IANA name such as Europe/Berlin, not Local")
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", timezone)
+	}
+	return loc, nil
+}
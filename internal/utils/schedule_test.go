@@ -108,3 +108,240 @@ func TestCalculateNextExecution(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTimezone(t *testing.T) {
+	parser := NewScheduleParser()
+
+	for _, tz := range []string{"", "UTC", "Europe/Berlin", "America/New_York"} {
+		if err := parser.ValidateTimezone(tz); err != nil {
+			t.Errorf("ValidateTimezone(%q) error = %v", tz, err)
+		}
+	}
+
+	for _, tz := range []string{"Mars/Olympus_Mons", "Local", "CEST+2"} {
+		if err := parser.ValidateTimezone(tz); err == nil {
+			t.Errorf("ValidateTimezone(%q) expected an error", tz)
+		}
+	}
+}
+
+func TestCalculateNextExecutionInZone(t *testing.T) {
+	parser := NewScheduleParser()
+
+	tests := []struct {
+		name     string
+		schedule string
+		timezone string
+		fromTime time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Empty zone is UTC",
+			schedule: "0 0 9 * * MON-FRI",
+			fromTime: time.Date(2024, 1, 8, 6, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Berlin weekdays at 9 in winter",
+			schedule: "0 0 9 * * MON-FRI",
+			timezone: "Europe/Berlin",
+			fromTime: time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC), // Saturday
+			expected: time.Date(2024, 1, 8, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Berlin weekdays at 9 in summer",
+			schedule: "0 0 9 * * MON-FRI",
+			timezone: "Europe/Berlin",
+			fromTime: time.Date(2024, 7, 1, 6, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 7, 1, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Day of week is evaluated in the zone",
+			schedule: "0 0 9 * * MON",
+			timezone: "Pacific/Auckland",
+			fromTime: time.Date(2024, 1, 7, 19, 0, 0, 0, time.UTC), // Monday 08:00 in Auckland
+			expected: time.Date(2024, 1, 7, 20, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextTime, err := parser.CalculateNextExecutionInZone(tt.schedule, tt.timezone, tt.fromTime)
+			if err != nil {
+				t.Fatalf("CalculateNextExecutionInZone() error = %v", err)
+			}
+			if !nextTime.Equal(tt.expected) {
+				t.Errorf("CalculateNextExecutionInZone() = %v, want %v", nextTime, tt.expected)
+			}
+			if nextTime.Location() != time.UTC {
+				t.Errorf("CalculateNextExecutionInZone() returned location %v, want UTC", nextTime.Location())
+			}
+		})
+	}
+
+	if _, err := parser.CalculateNextExecutionInZone("0 0 9 * * *", "Nowhere/Special", time.Now()); err == nil {
+		t.Error("CalculateNextExecutionInZone() expected an error for an unknown zone")
+	}
+}
+
+// Europe/Berlin skips 02:00-03:00 on 2024-03-31 and repeats 02:00-03:00 on 2024-10-27
+func TestCalculateNextExecutionInZone_DST(t *testing.T) {
+	parser := NewScheduleParser()
+
+	tests := []struct {
+		name     string
+		schedule string
+		fromTime time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "Fixed hour in skipped hour runs shifted forward by the gap",
+			schedule: "0 30 2 * * *",
+			fromTime: time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), // 03:30 CEST
+				time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC),  // 02:30 CEST
+			},
+		},
+		{
+			name:     "Fixed hour in repeated hour runs once in the first pass",
+			schedule: "0 30 2 * * *",
+			fromTime: time.Date(2024, 10, 26, 12, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+				time.Date(2024, 10, 28, 1, 30, 0, 0, time.UTC), // 02:30 CET
+			},
+		},
+		{
+			name:     "Fixed hour from inside the second pass waits for the next day",
+			schedule: "0 30 2 * * *",
+			fromTime: time.Date(2024, 10, 27, 1, 10, 0, 0, time.UTC), // 02:10 CET
+			expected: []time.Time{
+				time.Date(2024, 10, 28, 1, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "Hourly schedule follows elapsed time over the gap",
+			schedule: "0 0 * * * *",
+			fromTime: time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC), // 01:30 CET
+			expected: []time.Time{
+				time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC), // 03:00 CEST
+				time.Date(2024, 3, 31, 2, 0, 0, 0, time.UTC), // 04:00 CEST
+			},
+		},
+		{
+			name:     "Hourly schedule runs in both passes of the repeated hour",
+			schedule: "0 0 * * * *",
+			fromTime: time.Date(2024, 10, 26, 23, 30, 0, 0, time.UTC), // 01:30 CEST
+			expected: []time.Time{
+				time.Date(2024, 10, 27, 0, 0, 0, 0, time.UTC), // 02:00 CEST
+				time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC), // 02:00 CET
+				time.Date(2024, 10, 27, 2, 0, 0, 0, time.UTC), // 03:00 CET
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fromTime := tt.fromTime
+			for i, expected := range tt.expected {
+				nextTime, err := parser.CalculateNextExecutionInZone(tt.schedule, "Europe/Berlin", fromTime)
+				if err != nil {
+					t.Fatalf("CalculateNextExecutionInZone() error = %v", err)
+				}
+				if !nextTime.Equal(expected) {
+					t.Fatalf("occurrence %d = %v, want %v", i, nextTime, expected)
+				}
+				fromTime = nextTime
+			}
+		})
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	parser := NewScheduleParser()
+
+	interval, ok := parser.ParseInterval("@every 90s")
+	if !ok || interval != 90*time.Second {
+		t.Errorf("ParseInterval(@every 90s) = %v, %v", interval, ok)
+	}
+
+	if _, ok := parser.ParseInterval("0 */5 * * * *"); ok {
+		t.Error("ParseInterval() should not treat a cron expression as an interval")
+	}
+
+	if err := parser.ValidateSchedule("@every 500ms"); err == nil {
+		t.Error("ValidateSchedule() should reject intervals below 1s")
+	}
+	if err := parser.ValidateSchedule("@every 1h30m"); err != nil {
+		t.Errorf("ValidateSchedule(@every 1h30m) error = %v", err)
+	}
+}
+
+func TestCalculateNextExecutionInWindow(t *testing.T) {
+	parser := NewScheduleParser()
+
+	startAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		fromTime time.Time
+		startAt  *time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Interval without start runs an interval after fromTime",
+			schedule: "@every 90s",
+			fromTime: time.Date(2025, 1, 6, 9, 0, 10, 0, time.UTC),
+			expected: time.Date(2025, 1, 6, 9, 1, 40, 0, time.UTC),
+		},
+		{
+			name:     "Interval before start runs at start",
+			schedule: "@every 90s",
+			fromTime: startAt.Add(-time.Hour),
+			startAt:  &startAt,
+			expected: startAt,
+		},
+		{
+			name:     "Interval after start stays on the start grid",
+			schedule: "@every 90s",
+			fromTime: startAt.Add(100 * time.Second),
+			startAt:  &startAt,
+			expected: startAt.Add(180 * time.Second),
+		},
+		{
+			name:     "Interval on a grid point moves to the next one",
+			schedule: "@every 90s",
+			fromTime: startAt.Add(90 * time.Second),
+			startAt:  &startAt,
+			expected: startAt.Add(180 * time.Second),
+		},
+		{
+			name:     "Cron before start may fire at start",
+			schedule: "0 0 9 * * *",
+			fromTime: startAt.Add(-48 * time.Hour),
+			startAt:  &startAt,
+			expected: startAt,
+		},
+		{
+			name:     "Cron after start is unaffected",
+			schedule: "0 0 9 * * *",
+			fromTime: startAt.Add(time.Hour),
+			startAt:  &startAt,
+			expected: startAt.Add(24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextTime, err := parser.CalculateNextExecutionInWindow(tt.schedule, "", tt.fromTime, tt.startAt)
+			if err != nil {
+				t.Fatalf("CalculateNextExecutionInWindow() error = %v", err)
+			}
+			if !nextTime.Equal(tt.expected) {
+				t.Errorf("CalculateNextExecutionInWindow() = %v, want %v", nextTime, tt.expected)
+			}
+		})
+	}
+}
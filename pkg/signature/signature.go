@@ -0,0 +1,141 @@
+// Package signature signs the calls the job scheduler makes to job APIs, and lets the receivers of
+// those calls verify that they came from the scheduler.
+//
+// A call carries the headers X-Scheduler-Timestamp, the Unix time it was signed at, and
+// X-Scheduler-Signature, one "v1=<hex>" entry per secret the scheduler signs with. Each entry is the
+// HMAC-SHA256 of the method, URL, timestamp and body, separated by newlines. While a secret is
+// rotated the scheduler signs with the old and the new one, and a receiver accepts a call matching
+// any of the secrets it knows.
+//
+// Receivers verify a call with a Verifier:
+//
+//	verifier := &signature.Verifier{Secrets: [][]byte{[]byte(os.Getenv("SCHEDULER_SIGNING_SECRET"))}}
+//	body, err := verifier.VerifyRequest(r)
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusUnauthorized)
+//		return
+//	}
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a signed call
+const (
+	HeaderSignature   = "X-Scheduler-Signature"
+	HeaderTimestamp   = "X-Scheduler-Timestamp"
+	HeaderJobID       = "X-Scheduler-Job-Id"
+	HeaderExecutionID = "X-Scheduler-Execution-Id"
+)
+
+// Version prefixes the signatures of the current scheme in HeaderSignature
+const Version = "v1"
+
+// DefaultTolerance is how far the timestamp of a call may be from the receiver's clock
+const DefaultTolerance = 5 * time.Minute
+
+// Verification errors
+var (
+	ErrMissingSignature = errors.New("signature: call is not signed")
+	ErrInvalidTimestamp = errors.New("signature: timestamp is malformed or outside the tolerance")
+	ErrInvalidSignature = errors.New("signature: no signature matches")
+)
+
+// Compute returns the hex HMAC-SHA256 of a call under secret
+func Compute(secret []byte, method, url string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%d\n", strings.ToUpper(method), url, timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign sets the timestamp and signature headers of req, whose body is body, with one signature per
+// secret. The URL signed is req.URL as sent.
+func Sign(req *http.Request, body []byte, secrets [][]byte, now time.Time) {
+	timestamp := now.Unix()
+	signatures := make([]string, len(secrets))
+	for i, secret := range secrets {
+		signatures[i] = Version + "=" + Compute(secret, req.Method, req.URL.String(), timestamp, body)
+	}
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, strings.Join(signatures, ","))
+}
+
+// Verifier checks the signatures of calls received from the scheduler
+type Verifier struct {
+	Secrets   [][]byte         // Secrets a call may be signed with; list the old and the new one while rotating
+	Tolerance time.Duration    // How old or early a call may be; DefaultTolerance when zero
+	Now       func() time.Time // Clock; time.Now when nil
+}
+
+// Verify checks that header signs a call with method, url and body under one of the secrets, at a
+// time within the tolerance
+func (v *Verifier) Verify(method, url string, header http.Header, body []byte) error {
+	signatures := header.Get(HeaderSignature)
+	if signatures == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	now, tolerance := time.Now, v.Tolerance
+	if v.Now != nil {
+		now = v.Now
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	if age := now().Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidTimestamp
+	}
+
+	for _, secret := range v.Secrets {
+		expected := []byte(Compute(secret, method, url, timestamp, body))
+		for _, entry := range strings.Split(signatures, ",") {
+			version, signature, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if ok && version == Version && hmac.Equal([]byte(signature), expected) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// VerifyRequest verifies a call received by an HTTP server and returns its body, which it reads and
+// puts back on r. The URL is rebuilt from the Host header and the request URI, with the scheme of the
+// connection or of X-Forwarded-Proto; receivers behind proxies that rewrite either should call Verify
+// with the URL the scheduler was configured with.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("signature: failed to read body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	url := scheme + "://" + r.Host + r.URL.RequestURI()
+
+	if err := v.Verify(r.Method, url, r.Header, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,90 @@
+package signature
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldSecret = []byte("old-secret-0123456789")
+	newSecret = []byte("new-secret-0123456789")
+)
+
+// signedRequest returns a call to url signed with secrets at now
+func signedRequest(t *testing.T, url string, body string, secrets [][]byte, now time.Time) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	Sign(req, []byte(body), secrets, now)
+	return req
+}
+
+func TestVerifyRequest_AcceptsSignedCalls(t *testing.T) {
+	verifier := &Verifier{Secrets: [][]byte{oldSecret}}
+	var verifyErr error
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, verifyErr = verifier.VerifyRequest(r)
+	}))
+	defer server.Close()
+
+	req := signedRequest(t, server.URL+"/hook?b=2&a=1", `{"jobId": 7}`, [][]byte{oldSecret}, time.Now())
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.NoError(t, verifyErr)
+	assert.Equal(t, `{"jobId": 7}`, string(received))
+}
+
+func TestVerify_RotatedSecrets(t *testing.T) {
+	now := time.Now()
+	req := signedRequest(t, "https://api.example.com/hook", "{}", [][]byte{newSecret, oldSecret}, now)
+	assert.Len(t, strings.Split(req.Header.Get(HeaderSignature), ","), 2)
+
+	// Receivers that know either secret accept the call while both are active
+	for _, secrets := range [][][]byte{{oldSecret}, {newSecret}, {[]byte("other-secret-0123456789"), newSecret}} {
+		verifier := &Verifier{Secrets: secrets}
+		assert.NoError(t, verifier.Verify(req.Method, req.URL.String(), req.Header, []byte("{}")))
+	}
+
+	verifier := &Verifier{Secrets: [][]byte{[]byte("other-secret-0123456789")}}
+	assert.ErrorIs(t, verifier.Verify(req.Method, req.URL.String(), req.Header, []byte("{}")), ErrInvalidSignature)
+}
+
+func TestVerify_RejectsTamperedAndStaleCalls(t *testing.T) {
+	now := time.Now()
+	verifier := &Verifier{Secrets: [][]byte{oldSecret}, Now: func() time.Time { return now }}
+	req := signedRequest(t, "https://api.example.com/hook", `{"amount": 1}`, [][]byte{oldSecret}, now)
+
+	assert.ErrorIs(t, verifier.Verify(req.Method, req.URL.String(), req.Header, []byte(`{"amount": 100}`)), ErrInvalidSignature)
+	assert.ErrorIs(t, verifier.Verify(http.MethodDelete, req.URL.String(), req.Header, []byte(`{"amount": 1}`)), ErrInvalidSignature)
+	assert.ErrorIs(t, verifier.Verify(req.Method, "https://api.example.com/other", req.Header, []byte(`{"amount": 1}`)), ErrInvalidSignature)
+	assert.ErrorIs(t, verifier.Verify(req.Method, req.URL.String(), http.Header{}, nil), ErrMissingSignature)
+
+	// Replays outside the tolerance are rejected
+	stale := signedRequest(t, "https://api.example.com/hook", "", [][]byte{oldSecret}, now.Add(-DefaultTolerance-time.Second))
+	assert.ErrorIs(t, verifier.Verify(stale.Method, stale.URL.String(), stale.Header, nil), ErrInvalidTimestamp)
+	lenient := &Verifier{Secrets: [][]byte{oldSecret}, Tolerance: time.Hour, Now: verifier.Now}
+	assert.NoError(t, lenient.Verify(stale.Method, stale.URL.String(), stale.Header, nil))
+}
+
+func TestVerifyRequest_RestoresBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://api.example.com/hook", bytes.NewBufferString("payload"))
+	Sign(req, []byte("payload"), [][]byte{oldSecret}, time.Now())
+
+	body, err := (&Verifier{Secrets: [][]byte{oldSecret}}).VerifyRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(body))
+
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(req.Body)
+	assert.Equal(t, "payload", buf.String())
+}